@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrorUnauthorized = errors.New("A valid bearer token is required.")
+	ErrorTokenInvalid = errors.New("Bearer token is malformed or its signature is invalid.")
+	ErrorTokenExpired = errors.New("Bearer token is expired or not yet valid.")
+	ErrorTokenClaims  = errors.New("Bearer token issuer, audience or subject is invalid.")
+	ErrorNoKeys       = errors.New("No token verification keys are configured.")
+	ErrorKeyInvalid   = errors.New("Verification key is not a PEM encoded RSA public key.")
+)
+
+// authenticator verifies HS256 and RS256 signed JWTs against locally configured keys.
+// Keys are indexed by key ID; keys without an ID are stored under "".
+type authenticator struct {
+	hmacKeys map[string][]byte
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+func newAuthenticator() *authenticator {
+	return &authenticator{
+		hmacKeys: make(map[string][]byte),
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+		now:      time.Now,
+	}
+}
+
+// authenticatorFromEnv builds an authenticator from the environment:
+//
+//	MomentJWTSecret     HS256 shared secret
+//	MomentJWTPublicKey  path of a PEM encoded RS256 public key
+//	MomentJWKSFile      path of a JSON Web Key Set holding RSA and oct keys
+//	MomentJWTIssuer     required "iss" claim, if set
+//	MomentJWTAudience   required "aud" claim, if set
+func authenticatorFromEnv() (*authenticator, error) {
+	au := newAuthenticator()
+	au.issuer = os.Getenv("MomentJWTIssuer")
+	au.audience = os.Getenv("MomentJWTAudience")
+
+	if s := os.Getenv("MomentJWTSecret"); s != "" {
+		au.hmacKeys[""] = []byte(s)
+	}
+	if f := os.Getenv("MomentJWTPublicKey"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err = au.addPEM("", b); err != nil {
+			return nil, err
+		}
+	}
+	if f := os.Getenv("MomentJWKSFile"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err = au.addJWKS(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(au.hmacKeys) == 0 && len(au.rsaKeys) == 0 {
+		return nil, ErrorNoKeys
+	}
+	return au, nil
+}
+
+// addPEM adds the PEM encoded RSA public key b under kid.
+func (au *authenticator) addPEM(kid string, b []byte) error {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return ErrorKeyInvalid
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	pub, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return ErrorKeyInvalid
+	}
+	au.rsaKeys[kid] = pub
+	return nil
+}
+
+// addJWKS adds every RSA and oct key of the JSON Web Key Set b.
+func (au *authenticator) addJWKS(b []byte) error {
+	type jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		K   string `json:"k"`
+	}
+	set := new(struct {
+		Keys []jwk `json:"keys"`
+	})
+	if err := json.Unmarshal(b, set); err != nil {
+		return err
+	}
+
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return err
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return err
+			}
+			au.rsaKeys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return err
+			}
+			au.hmacKeys[k.Kid] = secret
+		}
+	}
+	return nil
+}
+
+// claims are the registered JWT claims this service relies on.
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// audience decodes the "aud" claim, which may be a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// verify checks the signature and registered claims of token and returns its subject.
+func (au *authenticator) verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrorTokenInvalid
+	}
+
+	header := new(struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	})
+	if err := decodeSegment(parts[0], header); err != nil {
+		return "", ErrorTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrorTokenInvalid
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if !au.verifySignature(header.Alg, header.Kid, signed, sig) {
+		return "", ErrorTokenInvalid
+	}
+
+	c := new(claims)
+	if err := decodeSegment(parts[1], c); err != nil {
+		return "", ErrorTokenInvalid
+	}
+
+	now := au.now().Unix()
+	if c.ExpiresAt == 0 || now >= c.ExpiresAt || now < c.NotBefore {
+		return "", ErrorTokenExpired
+	}
+	if c.Subject == "" || (au.issuer != "" && c.Issuer != au.issuer) || !c.Audience.contains(au.audience) {
+		return "", ErrorTokenClaims
+	}
+	return c.Subject, nil
+}
+
+func (a audience) contains(aud string) bool {
+	if aud == "" {
+		return true
+	}
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature verifies sig over signed with the key kid, or with every key of alg if kid is not known.
+func (au *authenticator) verifySignature(alg string, kid string, signed []byte, sig []byte) bool {
+	switch alg {
+	case algHS256:
+		if k, ok := au.hmacKeys[kid]; ok {
+			return verifyHS256(k, signed, sig)
+		}
+		for _, k := range au.hmacKeys {
+			if verifyHS256(k, signed, sig) {
+				return true
+			}
+		}
+	case algRS256:
+		if k, ok := au.rsaKeys[kid]; ok {
+			return verifyRS256(k, signed, sig)
+		}
+		for _, k := range au.rsaKeys {
+			if verifyRS256(k, signed, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func verifyHS256(key []byte, signed []byte, sig []byte) bool {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(signed)
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+func verifyRS256(key *rsa.PublicKey, signed []byte, sig []byte) bool {
+	h := sha256.Sum256(signed)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], sig) == nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// middleware rejects requests without a valid bearer token with 401 and stores the
+// token's subject in the request context for authenticatedUser.
+func (au *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, bearerPrefix) {
+			unauthorized(w, r, ErrorUnauthorized)
+			return
+		}
+
+		user, err := au.verify(strings.TrimPrefix(h, bearerPrefix))
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user)))
+	})
+}
+
+// withUser returns a copy of ctx that carries the authenticated user.
+func withUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// authenticatedUser returns the user established by authenticator.middleware.
+func authenticatedUser(r *http.Request) (string, error) {
+	if u, ok := r.Context().Value(userKey).(string); ok && u != "" {
+		return u, nil
+	}
+	return "", ErrorUnauthorized
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeProblem(w, r, http.StatusUnauthorized, "", err)
+}
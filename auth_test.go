@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const tSecret = "test-secret"
+
+var tNow = time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func MockAuthenticator() *authenticator {
+	au := newAuthenticator()
+	au.hmacKeys[""] = []byte(tSecret)
+	au.now = func() time.Time { return tNow }
+	return au
+}
+
+// sign returns a JWT over c signed with alg; key is a []byte for HS256 and an *rsa.PrivateKey for RS256.
+func sign(t *testing.T, alg string, kid string, key interface{}, c map[string]interface{}) string {
+	h, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	assert.Nil(t, err)
+	p, err := json.Marshal(c)
+	assert.Nil(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	var sig []byte
+	switch alg {
+	case algHS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case algRS256:
+		d := sha256.Sum256([]byte(signed))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, d[:])
+		assert.Nil(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims(user string) map[string]interface{} {
+	return map[string]interface{}{"sub": user, "exp": tNow.Add(time.Hour).Unix()}
+}
+
+// bearer returns an Authorization header value that MockAuthenticator accepts for user.
+func bearer(t *testing.T, user string) string {
+	return bearerPrefix + sign(t, algHS256, "", []byte(tSecret), validClaims(user))
+}
+
+// asUser returns r as it would look after authenticator.middleware accepted a token for user.
+// An empty user leaves r unauthenticated.
+func asUser(r *http.Request, user string) *http.Request {
+	if user == "" {
+		return r
+	}
+	return r.WithContext(withUser(r.Context(), user))
+}
+
+func Test_verifyHS256(t *testing.T) {
+	key := []byte(tSecret)
+	type test struct {
+		token    string
+		expected error
+	}
+	tests := []test{
+		test{sign(t, algHS256, "", key, validClaims(tUser)), nil},
+		test{sign(t, algHS256, "", []byte("other"), validClaims(tUser)), ErrorTokenInvalid},
+		test{sign(t, "none", "", key, validClaims(tUser)), ErrorTokenInvalid},
+		test{sign(t, algHS256, "", key, map[string]interface{}{"sub": tUser}), ErrorTokenExpired},
+		test{sign(t, algHS256, "", key, map[string]interface{}{"sub": tUser, "exp": tNow.Unix()}), ErrorTokenExpired},
+		test{sign(t, algHS256, "", key, map[string]interface{}{"sub": tUser, "exp": tNow.Add(time.Hour).Unix(), "nbf": tNow.Add(time.Minute).Unix()}), ErrorTokenExpired},
+		test{sign(t, algHS256, "", key, map[string]interface{}{"exp": tNow.Add(time.Hour).Unix()}), ErrorTokenClaims},
+		test{"not.a.token", ErrorTokenInvalid},
+		test{"garbage", ErrorTokenInvalid},
+	}
+
+	au := MockAuthenticator()
+	for _, v := range tests {
+		user, err := au.verify(v.token)
+		assert.Exactly(t, v.expected, err, v.token)
+		if err == nil {
+			assert.Equal(t, tUser, user)
+		}
+	}
+}
+
+func Test_verifyIssuerAudience(t *testing.T) {
+	au := MockAuthenticator()
+	au.issuer = "moment-auth"
+	au.audience = "moment-service"
+	key := []byte(tSecret)
+
+	c := validClaims(tUser)
+	_, err := au.verify(sign(t, algHS256, "", key, c))
+	assert.Exactly(t, ErrorTokenClaims, err)
+
+	c["iss"] = "moment-auth"
+	c["aud"] = []string{"other", "moment-service"}
+	_, err = au.verify(sign(t, algHS256, "", key, c))
+	assert.Nil(t, err)
+
+	c["aud"] = "other"
+	_, err = au.verify(sign(t, algHS256, "", key, c))
+	assert.Exactly(t, ErrorTokenClaims, err)
+}
+
+func Test_verifyRS256(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	t.Run("PEM", func(t *testing.T) {
+		der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		assert.Nil(t, err)
+
+		au := MockAuthenticator()
+		assert.Nil(t, au.addPEM("", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+		user, err := au.verify(sign(t, algRS256, "", priv, validClaims(tUser)))
+		assert.Nil(t, err)
+		assert.Equal(t, tUser, user)
+
+		assert.Exactly(t, ErrorKeyInvalid, au.addPEM("", []byte("not pem")))
+	})
+
+	t.Run("JWKS", func(t *testing.T) {
+		jwks, err := json.Marshal(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa-1",
+					"n":   base64.RawURLEncoding.EncodeToString(priv.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(priv.E)).Bytes()),
+				},
+				{
+					"kty": "oct",
+					"kid": "oct-1",
+					"k":   base64.RawURLEncoding.EncodeToString([]byte("jwks-secret")),
+				},
+			},
+		})
+		assert.Nil(t, err)
+
+		au := newAuthenticator()
+		au.now = func() time.Time { return tNow }
+		assert.Nil(t, au.addJWKS(jwks))
+
+		_, err = au.verify(sign(t, algRS256, "rsa-1", priv, validClaims(tUser)))
+		assert.Nil(t, err)
+		_, err = au.verify(sign(t, algHS256, "oct-1", []byte("jwks-secret"), validClaims(tUser)))
+		assert.Nil(t, err)
+		_, err = au.verify(sign(t, algHS256, "oct-1", []byte(tSecret), validClaims(tUser)))
+		assert.Exactly(t, ErrorTokenInvalid, err)
+	})
+}
+
+func Test_middleware(t *testing.T) {
+	type test struct {
+		authorization  string
+		expectedStatus int
+	}
+	tests := []test{
+		test{"", http.StatusUnauthorized},
+		test{"Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		test{bearerPrefix + "garbage", http.StatusUnauthorized},
+		test{bearer(t, tUser), http.StatusOK},
+	}
+
+	h := MockAuthenticator().middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := authenticatedUser(r)
+		assert.Nil(t, err)
+		assert.Equal(t, tUser, user)
+	}))
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if v.authorization != "" {
+			req.Header.Set("Authorization", v.authorization)
+		}
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, v.expectedStatus, rec.Code, v.authorization)
+		if v.expectedStatus == http.StatusUnauthorized {
+			assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"github.com/penutty/Moment-Service/moment"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// IdempotencyHeader carries the key under which app stores the response to a mutating
+	// request, so that a retry with the same key is answered with that response.
+	IdempotencyHeader = "Idempotency-Key"
+
+	// ReplayedHeader is set to true on a response that was stored for an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// perRequestHeaders describe the request that a response was written for rather than the
+// response, so they are neither stored nor replayed.
+var perRequestHeaders = []string{CorrelationHeader, "Date"}
+
+// idempotent serves every request that carries IdempotencyHeader and does not only read once
+// per user and key. The first request is passed to next and its response stored in a.keys
+// before it is written, unless it is a 5xx, after which the request may be retried. A later
+// request with the same method, URI and body is answered with the stored response; one that
+// differs, or arrives while the first is still running, is a conflict.
+func (a *app) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if a.keys == nil || key == "" || !mutates(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		k, err := a.newIdempotencyKey(r, key)
+		if err != nil {
+			genErrorHandler(w, r, err)
+			return
+		}
+
+		ctx, cancel := withTimeout(r, a.timeouts.idempotency)
+		res, err := a.keys.ReserveKey(ctx, k)
+		cancel()
+		if err != nil {
+			genErrorHandler(w, r, err)
+			return
+		}
+		if res != nil {
+			for k, v := range storedHeader(res.Header) {
+				w.Header()[k] = v
+			}
+			w.Header().Set(ReplayedHeader, "true")
+			w.WriteHeader(res.Status)
+			w.Write(res.Body)
+			return
+		}
+
+		rec := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// The response is stored even if the client has gone, because the client may retry.
+		ctx, cancel = withTimeout(r.WithContext(context.WithoutCancel(r.Context())), a.timeouts.idempotency)
+		if rec.status >= http.StatusInternalServerError {
+			err = a.keys.ReleaseKey(ctx, k)
+		} else {
+			err = a.keys.CompleteKey(ctx, k, &moment.Response{
+				Status: rec.status,
+				Header: storedHeader(rec.header),
+				Body:   rec.body.Bytes(),
+			})
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s %s %s: %s %q: %v", correlationID(r), r.Method, instance(r), IdempotencyHeader, key, err)
+		}
+
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+// storedHeader returns a copy of h without perRequestHeaders.
+func storedHeader(h map[string][]string) http.Header {
+	c := http.Header(h).Clone()
+	for _, k := range perRequestHeaders {
+		c.Del(k)
+	}
+	return c
+}
+
+// newIdempotencyKey returns the key of the authenticated user of r, identified by the hash of
+// the method, URI and body of r. It leaves the body of r to be read again.
+func (a *app) newIdempotencyKey(r *http.Request, key string) (*moment.IdempotencyKeysRow, error) {
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(buf)
+
+	now := time.Now()
+	c := a.builder()
+	k := c.NewIdempotencyKeysRow(me, key, h.Sum(nil), &now)
+	if err = c.Err(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// mutates reports whether requests of method may change a resource.
+func mutates(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_idempotent(t *testing.T) {
+	a := MockApp()
+	a.keys = moment.NewMemoryStore()
+
+	calls := 0
+	statuses := []int{http.StatusServiceUnavailable, http.StatusCreated}
+	h := a.idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Location", "/moments/1")
+		w.WriteHeader(statuses[calls%len(statuses)])
+		w.Write([]byte("call"))
+		calls++
+	}))
+
+	type test struct {
+		method   string
+		key      string
+		body     string
+		expected int
+		replayed bool
+		calls    int
+	}
+	tests := []test{
+		test{http.MethodPost, "", "a", http.StatusServiceUnavailable, false, 1},
+		test{http.MethodPost, "", "a", http.StatusCreated, false, 2},
+		test{http.MethodPost, "k", "a", http.StatusServiceUnavailable, false, 3},
+		test{http.MethodPost, "k", "a", http.StatusCreated, false, 4},
+		test{http.MethodPost, "k", "a", http.StatusCreated, true, 4},
+		test{http.MethodPost, "k", "b", http.StatusConflict, false, 4},
+		test{http.MethodDelete, "k", "a", http.StatusConflict, false, 4},
+		test{http.MethodGet, "k", "a", http.StatusServiceUnavailable, false, 5},
+	}
+
+	for i, v := range tests {
+		req := asUser(httptest.NewRequest(v.method, MomentsEndpoint, bytes.NewBufferString(v.body)), tUser)
+		if v.key != "" {
+			req.Header.Set(IdempotencyHeader, v.key)
+		}
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, v.expected, rec.Code, "request %d", i)
+		assert.Equal(t, v.calls, calls, "request %d", i)
+		if v.replayed {
+			assert.Equal(t, "true", rec.Header().Get(ReplayedHeader), "request %d", i)
+			assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"), "request %d", i)
+			assert.Equal(t, "/moments/1", rec.Header().Get("Location"), "request %d", i)
+			assert.Equal(t, "call", rec.Body.String(), "request %d", i)
+		} else {
+			assert.Empty(t, rec.Header().Get(ReplayedHeader), "request %d", i)
+		}
+	}
+
+	req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, bytes.NewBufferString("a")), tUser2)
+	req.Header.Set(IdempotencyHeader, "k")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, 6, calls, "keys are scoped to their user")
+}
+
+// Test_routesIdempotency retries the mutating requests of Test_routesMemoryStore with an
+// Idempotency-Key and checks that each is run once.
+func Test_routesIdempotency(t *testing.T) {
+	a := MockApp()
+	s := moment.NewMemoryStore()
+	a.s, a.keys = s, s
+	a.p = new(moment.Policy)
+
+	type test struct {
+		path     string
+		user     string
+		key      string
+		body     string
+		expected int
+		replayed bool
+	}
+	create := `{"Latitude":1,"Longitude":1,"Public":true,"CreateDate":"2017-06-01T12:00:00Z","Media":[{"Message":"Hello.","Mtype":0}]}`
+	find := `{"Latitude":1,"Longitude":1,"Accuracy":5}`
+	tests := []test{
+		test{"/moments", tUser, strings.Repeat("k", 256), create, http.StatusUnprocessableEntity, false},
+		test{"/moments", tUser, "large", strings.Repeat(" ", maxBodyBytes) + create, http.StatusRequestEntityTooLarge, false},
+		test{"/moments", tUser, "create", create, http.StatusCreated, false},
+		test{"/moments", tUser, "create", create, http.StatusCreated, true},
+		test{"/moments", tUser, "create", `{"Public":true}`, http.StatusConflict, false},
+		test{"/moments/1/finds", tUser2, "find", find, http.StatusCreated, false},
+		test{"/moments/1/finds", tUser2, "find", find, http.StatusCreated, true},
+		test{"/moments/1/finds", tUser2, "find again", find, http.StatusConflict, false},
+		test{"/moments/1/finds", tUser2, "find again", find, http.StatusConflict, true},
+		test{"/moments/1/shares", tUser2, "share", `{"Recipients":[{"All":true}]}`, http.StatusCreated, false},
+		test{"/moments/1/shares", tUser2, "share", `{"Recipients":[{"All":true}]}`, http.StatusCreated, true},
+	}
+	for i, v := range tests {
+		id := fmt.Sprintf("request-%d", i)
+		req := httptest.NewRequest(http.MethodPost, v.path, bytes.NewBufferString(v.body))
+		req.Header.Set("Authorization", bearer(t, v.user))
+		req.Header.Set(IdempotencyHeader, v.key)
+		req.Header.Set(CorrelationHeader, id)
+		rec := httptest.NewRecorder()
+
+		a.routes().ServeHTTP(rec, req)
+		assert.Exactly(t, v.expected, rec.Code, v.path+" "+v.key+": "+rec.Body.String())
+		assert.Equal(t, v.replayed, rec.Header().Get(ReplayedHeader) == "true", v.path+" "+v.key)
+		assert.Equal(t, []string{id}, rec.Header().Values(CorrelationHeader), v.path+" "+v.key+": a replay keeps the ID of its own request")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/users/"+tUser+"/moments/left", nil)
+	req.Header.Set("Authorization", bearer(t, tUser))
+	rec := httptest.NewRecorder()
+
+	a.routes().ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, bytes.Count(rec.Body.Bytes(), []byte(`"message":"Hello."`)), "a retried create leaves one moment")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+)
+
+// LegacyMomentEndpoint is the single endpoint of the API that the resource tree replaced. It
+// dispatches on the type query parameter of GET and POST and the action query parameter of
+// PATCH, and reads every other argument out of the JSON body. Only legacy versions serve it,
+// so that existing clients keep working until v1 is sunset.
+const LegacyMomentEndpoint = "/moment"
+
+var (
+	ErrorLegacyType   = paramErrors{paramError{"type", "must be one of hidden, lost, sharedbyuser, sharedbylocation, found, left, public or private"}}
+	ErrorLegacyAction = paramErrors{paramError{"action", "must be one of findpublic, findprivate or share"}}
+)
+
+// legacyBody holds the arguments the legacy API read out of the body of a request.
+type legacyBody struct {
+	Latitude  float32
+	Longitude float32
+	Me        string
+	You       string
+	MomentID  int64
+}
+
+// legacyMomentHandler serves LegacyMomentEndpoint with the handlers of the resource tree:
+//
+//	GET   ?type=hidden|lost|sharedbyuser|sharedbylocation|found|left|public
+//	POST  ?type=public|private
+//	PATCH ?action=findpublic|findprivate|share
+//
+// Me names the owner of found and left, and defaults to the authenticated user. Every response
+// is marked deprecated.
+func (a *app) legacyMomentHandler(w http.ResponseWriter, r *http.Request) {
+	if !requestVersion(r).legacy {
+		http.NotFound(w, r)
+		return
+	}
+	deprecate(w)
+
+	b := new(legacyBody)
+	if err := peekBody(r, b); err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	if b.Me == "" {
+		b.Me, _ = authenticatedUser(r)
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		err = a.legacyGet(w, r, b)
+	case http.MethodPost:
+		if err = a.legacyPost(r); err == nil {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodPatch:
+		if err = a.legacyPatch(r, b); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		methodNotAllowed(w, r, http.MethodGet, http.MethodPost, http.MethodPatch)
+		return
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+	}
+}
+
+func (a *app) legacyGet(w http.ResponseWriter, r *http.Request, b *legacyBody) error {
+	switch r.URL.Query().Get("type") {
+	case "hidden":
+		return a.getHiddenMoment(w, r, b.Latitude, b.Longitude, 0)
+	case "lost":
+		return a.getLostMoment(w, r, b.Latitude, b.Longitude, 0)
+	case "sharedbyuser":
+		return a.getSharedMomentbyUser(w, r, b.You)
+	case "sharedbylocation":
+		return a.getSharedMomentbyLocation(w, r, b.Latitude, b.Longitude, 0)
+	case "found":
+		return a.getFoundMoment(w, r, b.Me)
+	case "left":
+		return a.getLeftMoment(w, r, b.Me)
+	case "public":
+		return a.getPublicMoment(w, r, b.Latitude, b.Longitude, 0)
+	}
+	return ErrorLegacyType
+}
+
+func (a *app) legacyPost(r *http.Request) error {
+	switch r.URL.Query().Get("type") {
+	case "public":
+		return a.postPublicMoment(r)
+	case "private":
+		return a.postPrivateMoment(r)
+	}
+	return ErrorLegacyType
+}
+
+func (a *app) legacyPatch(r *http.Request, b *legacyBody) error {
+	switch r.URL.Query().Get("action") {
+	case "findpublic":
+		return a.findPublicMoment(r, b.MomentID)
+	case "findprivate":
+		return a.findPrivateMoment(r, b.MomentID)
+	case "share":
+		return a.shareMoment(r, b.MomentID)
+	}
+	return ErrorLegacyAction
+}
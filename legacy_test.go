@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_legacyMomentHandler(t *testing.T) {
+	type test struct {
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+	}
+	location := `{"Latitude":1.5,"Longitude":-2.5}`
+	tests := []test{
+		test{http.MethodGet, "/moment?type=public", location, http.StatusOK},
+		test{http.MethodGet, "/moment?type=hidden", location, http.StatusOK},
+		test{http.MethodGet, "/moment?type=lost", location, http.StatusOK},
+		test{http.MethodGet, "/moment?type=sharedbylocation", location, http.StatusOK},
+		test{http.MethodGet, "/moment?type=sharedbyuser", `{"You":"` + tUser1 + `"}`, http.StatusOK},
+		test{http.MethodGet, "/moment?type=found", `{"Me":"` + tUser + `"}`, http.StatusOK},
+		test{http.MethodGet, "/moment?type=found", "", http.StatusOK},
+		test{http.MethodGet, "/moment?type=left", `{"Me":"` + tUser2 + `"}`, http.StatusBadRequest},
+		test{http.MethodGet, "/moment?type=other", "", http.StatusBadRequest},
+		test{http.MethodGet, "/moment?type=public", `{"Latitude":200}`, http.StatusUnprocessableEntity},
+		test{http.MethodPost, "/moment?type=public", `{"Latitude":1,"Longitude":1,"Public":true,"CreateDate":"2017-06-01T12:00:00Z","Media":[{"Message":"Hello.","Mtype":0}]}`, http.StatusCreated},
+		test{http.MethodPost, "/moment?type=private", `{"Latitude":1,"Longitude":1,"CreateDate":"2017-06-01T12:00:00Z","Recipients":[{"UserID":"` + tUser1 + `"}],"Media":[{"Message":"Hello.","Mtype":0}]}`, http.StatusCreated},
+		test{http.MethodPost, "/moment?type=other", "", http.StatusBadRequest},
+		test{http.MethodPatch, "/moment?action=findpublic", `{"MomentID":1}`, http.StatusNoContent},
+		test{http.MethodPatch, "/moment?action=findprivate", `{"MomentID":1}`, http.StatusNoContent},
+		test{http.MethodPatch, "/moment?action=share", `{"MomentID":1,"Recipients":[{"All":true}]}`, http.StatusNoContent},
+		test{http.MethodPatch, "/moment?action=other", "", http.StatusBadRequest},
+		test{http.MethodPatch, "/moment", "not json", http.StatusBadRequest},
+		test{http.MethodDelete, "/moment", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(v.method, v.target, strings.NewReader(v.body))
+		req.Header.Set("Authorization", bearer(t, tUser))
+		rec := httptest.NewRecorder()
+
+		MockApp().routes().ServeHTTP(rec, req)
+		assert.Exactly(t, v.expectedStatus, rec.Code, v.method+" "+v.target+": "+rec.Body.String())
+		if v.expectedStatus == http.StatusMethodNotAllowed {
+			assert.Equal(t, "GET, POST, PATCH", rec.Header().Get("Allow"))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/penutty/Moment-Service/moment"
+	"io"
+	"time"
+)
+
+var (
+	ErrorMigrateUsage = errors.New("Usage: migrate up|down|status|backfill|purge")
+	ErrorMigrateStore = errors.New("MomentStore must be mssql or sqlite to migrate.")
+)
+
+// migrate runs the migrate subcommand on the store named by MomentStore and writes what it did to w:
+//
+//	migrate up        apply every pending migration
+//	migrate down      revert the newest applied migration
+//	migrate status    list every migration and when it was applied
+//	migrate backfill  set the cell of every moment created before migration 0004
+//	migrate purge     delete every moment deleted more than moment.RestoreWindow ago
+func migrate(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return ErrorMigrateUsage
+	}
+	s, db, err := storeFromEnv(ctx)
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		defer db.Close()
+	}
+	ss, ok := s.(*moment.SQLStore)
+	if !ok {
+		return ErrorMigrateStore
+	}
+	m, err := ss.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ms, err := m.Up(ctx)
+		for _, mg := range ms {
+			fmt.Fprintf(w, "applied  %04d %s\n", mg.Version, mg.Name)
+		}
+		return err
+	case "down":
+		mg, err := m.Down(ctx)
+		if mg != nil {
+			fmt.Fprintf(w, "reverted %04d %s\n", mg.Version, mg.Name)
+		}
+		return err
+	case "status":
+		ms, err := m.Status(ctx)
+		for _, mg := range ms {
+			applied := "pending"
+			if mg.Applied != nil {
+				applied = "applied " + mg.Applied.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d %-24s %s\n", mg.Version, mg.Name, applied)
+		}
+		return err
+	case "backfill":
+		n, err := ss.BackfillCells(ctx, moment.DefaultBackfillBatch)
+		fmt.Fprintf(w, "backfilled %d moments\n", n)
+		return err
+	case "purge":
+		n, err := ss.Purge(ctx, time.Now().UTC())
+		fmt.Fprintf(w, "purged %d moments\n", n)
+		return err
+	}
+	return ErrorMigrateUsage
+}
+
+// checkSchema refuses a Moment-Db whose schema version this service does not run against.
+// A MemoryStore has no schema and always passes.
+func checkSchema(ctx context.Context, s moment.Store) error {
+	ss, ok := s.(*moment.SQLStore)
+	if !ok {
+		return nil
+	}
+	m, err := ss.Migrator()
+	if err != nil {
+		return err
+	}
+	return m.Check(ctx)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_migrate(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("MomentStore", "sqlite")
+	t.Setenv("MomentSQLitePath", filepath.Join(t.TempDir(), "moment.db"))
+
+	type test struct {
+		args     []string
+		expected []string
+		err      error
+	}
+	tests := []test{
+		test{[]string{"status"}, []string{"0001 create_moments           pending", "0002 create_finds             pending", "0003 create_shares            pending", "0004 add_cells                pending", "0005 create_idempotency_keys  pending", "0006 add_deleted_at           pending", "0007 add_expires_at           pending", "0008 add_release_date         pending", "0009 add_find_location        pending", "0010 add_capacity             pending", "0011 extend_idempotency_keys  pending"}, nil},
+		test{[]string{"up"}, []string{"applied  0001 create_moments", "applied  0002 create_finds", "applied  0003 create_shares", "applied  0004 add_cells", "applied  0005 create_idempotency_keys", "applied  0006 add_deleted_at", "applied  0007 add_expires_at", "applied  0008 add_release_date", "applied  0009 add_find_location", "applied  0010 add_capacity", "applied  0011 extend_idempotency_keys"}, nil},
+		test{[]string{"down"}, []string{"reverted 0011 extend_idempotency_keys"}, nil},
+		test{[]string{"status"}, []string{"0001 create_moments           applied ", "0002 create_finds             applied ", "0003 create_shares            applied ", "0004 add_cells                applied ", "0005 create_idempotency_keys  applied ", "0006 add_deleted_at           applied ", "0007 add_expires_at           applied ", "0008 add_release_date         applied ", "0009 add_find_location        applied ", "0010 add_capacity             applied ", "0011 extend_idempotency_keys  pending"}, nil},
+		test{[]string{"up"}, []string{"applied  0011 extend_idempotency_keys"}, nil},
+		test{[]string{"up"}, nil, nil},
+		test{[]string{"backfill"}, []string{"backfilled 0 moments"}, nil},
+		test{[]string{"purge"}, []string{"purged 0 moments"}, nil},
+		test{[]string{"sideways"}, nil, ErrorMigrateUsage},
+		test{nil, nil, ErrorMigrateUsage},
+	}
+
+	for _, v := range tests {
+		w := new(bytes.Buffer)
+		err := migrate(ctx, w, v.args)
+		name := strings.Join(v.args, " ")
+		assert.Exactly(t, v.err, err, name)
+
+		var lines []string
+		for _, l := range strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n") {
+			if l != "" {
+				lines = append(lines, l)
+			}
+		}
+		assert.Equal(t, len(v.expected), len(lines), name)
+		for i := range lines {
+			if i < len(v.expected) {
+				assert.True(t, strings.HasPrefix(lines[i], v.expected[i]), lines[i])
+			}
+		}
+	}
+
+	t.Setenv("MomentStore", "memory")
+	assert.Exactly(t, ErrorMigrateStore, migrate(ctx, new(bytes.Buffer), []string{"up"}))
+}
+
+func Test_checkSchema(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, checkSchema(ctx, moment.NewMemoryStore()))
+
+	s, err := moment.NewSQLiteStore(filepath.Join(t.TempDir(), "moment.db"))
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(checkSchema(ctx, s), moment.ErrorSchemaPending))
+
+	m, err := s.Migrator()
+	assert.Nil(t, err)
+	_, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, checkSchema(ctx, s))
+}
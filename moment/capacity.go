@@ -0,0 +1,75 @@
+package moment
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	capacity  = "[Capacity]"
+	mCapacity = momentsAlias + "." + capacity
+	claimed   = "[Claimed]"
+	mClaimed  = momentsAlias + "." + claimed
+)
+
+var (
+	ErrorCapacity   = invalid("capacity", "Only a public moment can have a capacity, and it must be more than 0.")
+	ErrorMomentFull = conflict("momentID", "The moment has been found by as many as it can be.")
+)
+
+func (m *MomentsRow) setCapacity(n *int) {
+	if m.err != nil || n == nil {
+		return
+	}
+
+	if *n < 1 || !m.public {
+		m.err = ErrorCapacity
+		return
+	}
+	m.capacity = n
+}
+
+// notFull matches the moments of a selector that can still be found by someone new.
+func notFull() sq.Or {
+	return sq.Or{sq.Eq{mCapacity: nil}, sq.Expr(mClaimed + " < " + mCapacity)}
+}
+
+// remaining returns how many more can find m, or nil if any number can.
+func (m *Moment) remaining() *int {
+	if m.capacity == nil {
+		return nil
+	}
+	n := *m.capacity - m.claimed
+	if n < 0 {
+		n = 0
+	}
+	return &n
+}
+
+// claim takes one of the places left on public moment id for a finder. It returns
+// ErrorMomentFull if none are left. The check and the claim are one statement so that concurrent finders cannot
+// take more places than the moment has.
+func (mc *MomentClient) claim(ctx context.Context, db DbRunner, id int64) error {
+	res, err := sq.
+		Update(schMoments).
+		Set(claimed, sq.Expr(claimed+" + 1")).
+		Where(sq.Eq{iD: id, public: true}).
+		Where(sq.Or{sq.Eq{capacity: nil}, sq.Expr(claimed + " < " + capacity)}).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	if cnt == 0 {
+		Error.Println(ErrorMomentFull)
+		return ErrorMomentFull
+	}
+	return nil
+}
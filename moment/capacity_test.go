@@ -0,0 +1,54 @@
+package moment
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+	"time"
+)
+
+const (
+	claimRegexp = `^UPDATE \[moment\]\.\[Moments\] SET \[Claimed\] = \[Claimed\] \+ 1 WHERE \[ID\] = \? AND \[Public\] = \? AND \(\[Capacity\] IS NULL OR \[Claimed\] < \[Capacity\]\)$`
+	// notFullRegexp matches the predicate by which LocationPublic and LocationHidden skip full moments.
+	notFullRegexp = `\(m\.\[Capacity\] IS NULL OR m\.\[Claimed\] < m\.\[Capacity\]\)`
+)
+
+func TestMomentRemaining(t *testing.T) {
+	type test struct {
+		capacity *int
+		claimed  int
+		expected *int
+	}
+	zero, two, three := 0, 2, 3
+	tests := []test{
+		test{nil, 4, nil},
+		test{&three, 1, &two},
+		test{&three, 3, &zero},
+		test{&two, 3, &zero},
+	}
+
+	for _, v := range tests {
+		m := &Moment{capacity: v.capacity, claimed: v.claimed}
+		assert.Equal(t, v.expected, m.remaining())
+	}
+}
+
+func TestFindPublicFull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mc := new(MomentClient)
+	dt := time.Now().UTC()
+
+	mock.ExpectBegin()
+	expectLive(mock, livePublicRegexp, nil, nil, 1, true)
+	mock.ExpectExec(claimRegexp).
+		WithArgs(1, true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = mc.FindPublic(context.Background(), db, mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long)))
+	assert.Exactly(t, ErrorMomentFull, err)
+	assert.Equal(t, KindConflict, KindOf(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
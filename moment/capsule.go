@@ -0,0 +1,125 @@
+package moment
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+const (
+	releaseDate  = "[ReleaseDate]"
+	mReleaseDate = momentsAlias + "." + releaseDate
+)
+
+var (
+	ErrorReleaseDate      = invalid("releaseDate", "A moment must be released after it is created and before it expires.")
+	ErrorMomentUnreleased = forbidden("momentID", "The moment cannot be found before its release date.")
+)
+
+func (m *MomentsRow) setReleaseDate(t *time.Time) {
+	if m.err != nil || t == nil {
+		return
+	}
+
+	if m.createDate != nil && !t.After(*m.createDate) {
+		m.err = ErrorReleaseDate
+		return
+	}
+	if m.expiresAt != nil && !t.Before(*m.expiresAt) {
+		m.err = ErrorReleaseDate
+		return
+	}
+	m.releaseDate = t
+}
+
+// pending reports whether m is a time capsule that has not been released by at.
+func (m *MomentsRow) pending(at time.Time) bool {
+	return m.releaseDate != nil && m.releaseDate.After(at)
+}
+
+// released matches the moments of a selector that have been released by at.
+func released(at time.Time) sq.Or {
+	return sq.Or{sq.Eq{mReleaseDate: nil}, sq.LtOrEq{mReleaseDate: at}}
+}
+
+// UserPending selects the private time capsules left for me that have not been released yet. Only
+// the author, location and release date of each is loaded so that me knows something is waiting
+// without seeing its content.
+func (mc *MomentClient) UserPending(ctx context.Context, db DbRunner, me string, pr *PageRequest) (*Page, error) {
+	if me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+
+	p, err := pr.pager(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
+		Select(
+			miD,
+			mLat,
+			mLong,
+			mUserID,
+			mReleaseDate).
+		From(schMoments+" "+momentsAlias).
+		Join(schFinds+" "+findsAlias+" ON "+fMomentID+" = "+miD).
+		Where(fUserID+" = ?", me).
+		Where(mPublic+" = "+d.Bool(false)).
+		Where(sq.Gt{mReleaseDate: now}).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)), d)
+
+	rs, err := mc.selectPendingMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
+}
+
+func (mc *MomentClient) selectPendingMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
+		return
+	}
+	defer rows.Close()
+
+	m := new(MomentsRow)
+	dest := []interface{}{
+		&m.momentID,
+		&m.latitude,
+		&m.longitude,
+		&m.userID,
+		&m.releaseDate,
+		p.dest(),
+	}
+
+	rs = make([]*Moment, 0)
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			Error.Println(err)
+			return
+		}
+		if !p.admit(m.momentID) {
+			break
+		}
+		rs = append(rs,
+			&Moment{
+				momentID:    m.momentID,
+				userID:      m.userID,
+				Location:    Location{latitude: m.latitude, longitude: m.longitude},
+				releaseDate: m.releaseDate,
+			})
+	}
+	if err = rows.Err(); err != nil {
+		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
+		return
+	}
+	return
+}
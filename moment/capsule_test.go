@@ -0,0 +1,59 @@
+package moment
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+	"time"
+)
+
+// releasedRegexp matches the predicate by which the location selectors skip unreleased time capsules.
+const releasedRegexp = `\(m\.\[ReleaseDate\] IS NULL OR m\.\[ReleaseDate\] <= \?\)`
+
+func TestUserPending(t *testing.T) {
+	t.Run("Parameter Checks", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := new(MomentClient)
+		_, err = mc.UserPending(context.Background(), db, "", nil)
+		assert.Equal(t, ErrorParameterEmpty, err)
+	})
+
+	t.Run("1", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := new(MomentClient)
+
+		mock.ExpectQuery(`^SELECT m\.\[ID\], m\.\[Latitude\], m\.\[Longitude\], m\.\[UserID\], m\.\[ReleaseDate\], m\.\[CreateDate\] AS \[SortKey\] `+
+			`FROM \[moment\]\.\[Moments\] m JOIN \[moment\]\.\[Finds\] f ON f\.\[MomentID\] = m\.\[ID\] `+
+			`WHERE f\.\[UserID\] = \? AND m\.\[Public\] = 0 AND m\.\[ReleaseDate\] > \? AND `+visibleRegexp+windowRegexp+` `+
+			`ORDER BY m\.\[CreateDate\] DESC, m\.\[ID\] DESC$`).
+			WithArgs(tUser, sqlmock.AnyArg(), sqlmock.AnyArg(), tUser, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"NoColumns"}))
+
+		_, err = mc.UserPending(context.Background(), db, tUser, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_selectPendingMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	release := time.Now().UTC().Add(time.Hour)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, userID, releaseDate, sortKey}).
+		AddRow(1, lat, long, tUser, &release, tDate).
+		AddRow(2, lat, long, tUser2, &release, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectPendingMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rs))
+	assert.Equal(t, tUser2, rs[1].userID)
+	assert.True(t, release.Equal(*rs[1].releaseDate))
+	assert.Empty(t, rs[1].media)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,259 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"math"
+	"sort"
+)
+
+const (
+	// cellPrecision is the number of geohash characters of the [Cell] of a moment, a cell of
+	// about 4.8 by 4.8 meters at the equator.
+	cellPrecision = 9
+
+	// maxCoverRanges is the most ranges of cells a location selector asks Moment-Db for, and
+	// maxCoverCells the most cells it considers. The fewer cells, the coarser they are and the
+	// more moments outside the circle the pager drops.
+	maxCoverRanges = 16
+	maxCoverCells  = 1024
+
+	// DefaultBackfillBatch is the number of IDs BackfillCells updates in one transaction.
+	DefaultBackfillBatch = 1000
+
+	cellStr = "[Cell]"
+	mCell   = momentsAlias + "." + cellStr
+
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// cellBits returns how many bits of latitude and of longitude a geohash of precision holds.
+// Geohashes interleave the bits starting with longitude, so longitude gets the odd bit.
+func cellBits(precision int) (latBits uint, longBits uint) {
+	bits := uint(5 * precision)
+	return bits / 2, bits - bits/2
+}
+
+// cellIndex returns the row and column of the cell of precision that holds lat, long.
+func cellIndex(lat float64, long float64, precision int) (row uint64, col uint64) {
+	latBits, longBits := cellBits(precision)
+	index := func(v, min, span float64, bits uint) uint64 {
+		n := uint64(1) << bits
+		i := uint64(math.Floor((v - min) / span * float64(n)))
+		if i >= n {
+			i = n - 1
+		}
+		return i
+	}
+	return index(lat, minLat, maxLat-minLat, latBits), index(long, minLong, maxLong-minLong, longBits)
+}
+
+// cellHash interleaves row and col into the geohash of precision as an integer.
+func cellHash(row uint64, col uint64, precision int) (h uint64) {
+	latBits, longBits := cellBits(precision)
+	for i := uint(0); i < uint(5*precision); i++ {
+		var bit uint64
+		if i%2 == 0 {
+			bit = col >> (longBits - 1 - i/2) & 1
+		} else {
+			bit = row >> (latBits - 1 - i/2) & 1
+		}
+		h = h<<1 | bit
+	}
+	return
+}
+
+// cellString returns the base32 geohash of precision characters of h.
+func cellString(h uint64, precision int) string {
+	b := make([]byte, precision)
+	for i := precision - 1; i >= 0; i-- {
+		b[i] = geohashAlphabet[h&31]
+		h >>= 5
+	}
+	return string(b)
+}
+
+// cellOf returns the geohash of precision characters of the cell that holds l.
+func cellOf(l Location, precision int) string {
+	row, col := cellIndex(float64(l.latitude), float64(l.longitude), precision)
+	return cellString(cellHash(row, col, precision), precision)
+}
+
+// cellRange is the cells from from up to but not including to. An empty to has no end.
+// Every cell that starts with the geohash of a coarser cell sorts within that cell's range.
+type cellRange struct {
+	from string
+	to   string
+}
+
+// cover returns the ranges of the finest cells that cover the boxes of the circle of radius
+// meters around l in at most maxCoverRanges ranges. It returns nil if no precision covers the
+// boxes with that few.
+func cover(l Location, radius float64) []cellRange {
+	bs := boxes(l, radius)
+	for p := cellPrecision; p > 0; p-- {
+		var hs []uint64
+		for _, b := range bs {
+			r0, c0 := cellIndex(b.south, b.west, p)
+			r1, c1 := cellIndex(b.north, b.east, p)
+			if len(hs)+int((r1-r0+1)*(c1-c0+1)) > maxCoverCells {
+				hs = nil
+				break
+			}
+			for r := r0; r <= r1; r++ {
+				for c := c0; c <= c1; c++ {
+					hs = append(hs, cellHash(r, c, p))
+				}
+			}
+		}
+		if hs == nil {
+			continue
+		}
+		if rs := cellRanges(hs, p); len(rs) <= maxCoverRanges {
+			return rs
+		}
+	}
+	return nil
+}
+
+// cellRanges merges the geohashes hs of precision into ranges of consecutive cells.
+func cellRanges(hs []uint64, precision int) (rs []cellRange) {
+	sort.Slice(hs, func(i, j int) bool { return hs[i] < hs[j] })
+	last := uint64(1)<<uint(5*precision) - 1
+	for i := 0; i < len(hs); {
+		j := i
+		for j+1 < len(hs) && hs[j+1] <= hs[j]+1 {
+			j++
+		}
+		r := cellRange{from: cellString(hs[i], precision)}
+		if hs[j] < last {
+			r.to = cellString(hs[j]+1, precision)
+		}
+		rs = append(rs, r)
+		i = j + 1
+	}
+	return
+}
+
+// cellPredicate restricts a query to the moments whose [Cell] lies within one of rs.
+func cellPredicate(rs []cellRange) sq.Sqlizer {
+	or := make(sq.Or, 0, len(rs))
+	for _, r := range rs {
+		if r.to == "" {
+			or = append(or, sq.GtOrEq{mCell: r.from})
+			continue
+		}
+		or = append(or, sq.And{sq.GtOrEq{mCell: r.from}, sq.Lt{mCell: r.to}})
+	}
+	return or
+}
+
+// boxPredicate restricts a query to the moments within one of bs.
+func boxPredicate(bs []bounds) sq.Sqlizer {
+	or := make(sq.Or, 0, len(bs))
+	for _, b := range bs {
+		or = append(or, sq.And{
+			sq.GtOrEq{mLat: b.south}, sq.LtOrEq{mLat: b.north},
+			sq.GtOrEq{mLong: b.west}, sq.LtOrEq{mLong: b.east},
+		})
+	}
+	return or
+}
+
+// BackfillCells sets the [Cell] of every moment created before Moment-Db stored cells, batch
+// IDs per transaction, and returns how many moments it updated. A batch of 0 is
+// DefaultBackfillBatch. Location selectors do not find a moment until it has a cell.
+func (mc *MomentClient) BackfillCells(ctx context.Context, db DbRunnerTrans, batch int) (n int64, err error) {
+	if batch <= 0 {
+		batch = DefaultBackfillBatch
+	}
+	d := mc.dialect()
+
+	var maxID sql.NullInt64
+	err = sq.
+		Select("MAX(" + iD + ")").
+		From(schMoments).
+		PlaceholderFormat(format{d}).
+		RunWith(db).
+		QueryRowContext(ctx).
+		Scan(&maxID)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+
+	for from := int64(0); from < maxID.Int64; from += int64(batch) {
+		cnt, err := mc.backfillCells(ctx, db, from, from+int64(batch))
+		n += cnt
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// backfillCells sets the [Cell] of the moments without one whose ID is after from and at most
+// to in one transaction.
+func (mc *MomentClient) backfillCells(ctx context.Context, db DbRunnerTrans, from int64, to int64) (n int64, err error) {
+	d := mc.dialect()
+	rows, err := sq.
+		Select(iD, latStr, longStr).
+		From(schMoments).
+		Where(sq.Eq{cellStr: nil}).
+		Where(sq.Gt{iD: from}).
+		Where(sq.LtOrEq{iD: to}).
+		PlaceholderFormat(format{d}).
+		RunWith(db).
+		QueryContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	var ms []*MomentsRow
+	for rows.Next() {
+		m := new(MomentsRow)
+		if err = rows.Scan(&m.momentID, &m.latitude, &m.longitude); err != nil {
+			rows.Close()
+			Error.Println(err)
+			return 0, dbError(err)
+		}
+		m.cell = cellOf(m.Location, cellPrecision)
+		ms = append(ms, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	if len(ms) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	for _, m := range ms {
+		_, err = sq.
+			Update(schMoments).
+			Set(cellStr, m.cell).
+			Where(sq.Eq{iD: m.momentID}).
+			PlaceholderFormat(format{d}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			Error.Println(err)
+			if txerr := tx.Rollback(); txerr != nil {
+				Error.Println(txerr)
+			}
+			return 0, dbError(ctxError(ctx, err))
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	return int64(len(ms)), nil
+}
@@ -0,0 +1,223 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// cellsRegexp matches the cell predicate of a location selector written for SQL Server.
+const cellsRegexp = `\(\(m\.\[Cell\] >= \? AND m\.\[Cell\] < \?\)( OR \(m\.\[Cell\] >= \? AND m\.\[Cell\] < \?\))*\)`
+
+// tCells returns the cell arguments of a location selector around lat, long with DefaultRadius.
+func tCells() (args []sqldriver.Value) {
+	for _, r := range cover(Location{latitude: lat, longitude: long}, DefaultRadius) {
+		args = append(args, r.from, r.to)
+	}
+	return
+}
+
+func Test_cellOf(t *testing.T) {
+	type test struct {
+		l         Location
+		precision int
+		expected  string
+	}
+	tests := []test{
+		test{Location{latitude: 0, longitude: 0}, 9, "s00000000"},
+		test{Location{latitude: 57.64911, longitude: 10.40744}, 9, "u4pruydqq"},
+		test{Location{latitude: -33.8688, longitude: 151.2093}, 6, "r3gx2f"},
+		test{Location{latitude: 90, longitude: 180}, 4, "zzzz"},
+		test{Location{latitude: -90, longitude: -180}, 4, "0000"},
+	}
+
+	for _, v := range tests {
+		assert.Equal(t, v.expected, cellOf(v.l, v.precision), v.l.String())
+	}
+
+	mc := new(MomentClient)
+	m := mc.NewMomentsRow(mc.NewLocation(57.64911, 10.40744), tUser, true, false, &tDate, nil, nil, nil)
+	assert.Nil(t, mc.Err())
+	assert.Equal(t, "u4pruydqq", m.cell)
+}
+
+func Test_cellRanges(t *testing.T) {
+	rs := cellRanges([]uint64{5, 3, 4, 9, 4, 31}, 1)
+	assert.Equal(t, []cellRange{
+		cellRange{"3", "6"},
+		cellRange{"9", "b"},
+		cellRange{"z", ""},
+	}, rs)
+
+	sql, args, err := cellPredicate(rs).ToSql()
+	assert.Nil(t, err)
+	assert.Equal(t, "((m.[Cell] >= ? AND m.[Cell] < ?) OR (m.[Cell] >= ? AND m.[Cell] < ?) OR m.[Cell] >= ?)", sql)
+	assert.Equal(t, []interface{}{"3", "6", "9", "b", "z"}, args)
+}
+
+// Test_cover checks that the cells of every moment within the radius of a selector anywhere on
+// the globe lie within the selector's ranges, and that the ranges stay within maxCoverRanges.
+func Test_cover(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		l := tGlobe(rnd)
+		radius := rnd.Float64() * MaxRadius
+		rs := cover(l, radius)
+		assert.NotNil(t, rs, l.String())
+
+		assert.True(t, len(rs) <= maxCoverRanges, l.String())
+		for _, r := range rs {
+			assert.True(t, r.to == "" || r.from < r.to, l.String())
+		}
+
+		for j := 0; j < 20; j++ {
+			m := tDestination(l, rnd.Float64()*radius, rnd.Float64()*360)
+			if haversine(l, m) > radius {
+				continue
+			}
+			c := cellOf(m, cellPrecision)
+			in := false
+			for _, r := range rs {
+				if c >= r.from && (r.to == "" || c < r.to) {
+					in = true
+				}
+			}
+			assert.True(t, in, "%v is within %v meters of %v", m, radius, l)
+		}
+	}
+}
+
+func TestBackfillCells(t *testing.T) {
+	ctx := context.Background()
+	ss := tStores(t)
+	s := ss["sqlite"].(*SQLStore)
+
+	_, err := s.db.ExecContext(ctx, `UPDATE "Moments" SET "Cell" = NULL WHERE "ID" <> 4`)
+	assert.Nil(t, err)
+
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+	pg, err := s.LocationPublic(ctx, l, 0, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, pg.Moments, "moments without a cell are not found")
+
+	n, err := s.mc.BackfillCells(ctx, s.db, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), n)
+
+	pg, err = s.LocationPublic(ctx, l, 0, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 5}, tIDs(pg))
+
+	n, err = s.BackfillCells(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+// benchMoments is the size of the synthetic dataset of BenchmarkProximity.
+const benchMoments = 1000000
+
+// tBenchStore returns a migrated SQLiteStore holding benchMoments hidden moments spread
+// uniformly over two by two degrees, about 50,000 square kilometers, and the center of that area.
+func tBenchStore(b *testing.B) (*SQLStore, Location) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(b.TempDir(), "moment.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	m, err := s.Migrator()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err = m.Up(ctx); err != nil {
+		b.Fatal(err)
+	}
+
+	center := Location{latitude: 47.6, longitude: -122.3}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO "Moments" ("UserID", "Latitude", "Longitude", "Cell", "Public", "Hidden", "CreateDate") VALUES (?, ?, ?, ?, 1, 1, ?)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < benchMoments; i++ {
+		l := Location{
+			latitude:  center.latitude - 1 + float32(rnd.Float64()*2),
+			longitude: center.longitude - 1 + float32(rnd.Float64()*2),
+		}
+		if _, err = stmt.ExecContext(ctx, tUser, l.latitude, l.longitude, cellOf(l, cellPrecision), tDate); err != nil {
+			b.Fatal(err)
+		}
+	}
+	stmt.Close()
+	if err = tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	if _, err = s.db.ExecContext(ctx, "ANALYZE"); err != nil {
+		b.Fatal(err)
+	}
+	return s, center
+}
+
+// BenchmarkProximity compares a location selector, which looks moments up by cell, with a
+// bounding box on [Latitude] and [Longitude] refined by the same exact distance.
+// It builds its dataset of benchMoments moments first, which takes a while.
+func BenchmarkProximity(b *testing.B) {
+	if testing.Short() {
+		b.Skip("builds a dataset of a million moments")
+	}
+	ctx := context.Background()
+	s, center := tBenchStore(b)
+
+	for _, radius := range []float64{100, DefaultRadius, 10000} {
+		name := fmt.Sprintf("%.0fm", radius)
+
+		b.Run("cells/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := s.LocationHidden(ctx, &center, radius, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run("box/"+name, func(b *testing.B) {
+			bx := boxes(center, radius)[0]
+			for i := 0; i < b.N; i++ {
+				rows, err := sq.
+					Select(iD, latStr, longStr, createDate).
+					From(schMoments).
+					Where(latStr+" BETWEEN ? AND ?", bx.south, bx.north).
+					Where(longStr+" BETWEEN ? AND ?", bx.west, bx.east).
+					Where(public + " = 1").
+					Where(hidden + " = 1").
+					PlaceholderFormat(format{SQLite}).
+					RunWith(s.db).
+					QueryContext(ctx)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var ms []*Moment
+				for rows.Next() {
+					m := new(Moment)
+					var d sql.NullTime
+					if err = rows.Scan(&m.momentID, &m.latitude, &m.longitude, &d); err != nil {
+						b.Fatal(err)
+					}
+					if haversine(center, m.Location) <= radius {
+						ms = append(ms, m)
+					}
+				}
+				rows.Close()
+			}
+		})
+	}
+}
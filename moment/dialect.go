@@ -0,0 +1,108 @@
+package moment
+
+import (
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"regexp"
+	"strings"
+)
+
+// Dialect adapts the statements of this package to a database. Statements are written as for
+// SQL Server: identifiers quoted [Like].[This], ? placeholders and boolean literals from Bool.
+type Dialect interface {
+	// Quote rewrites the bracket-quoted identifiers of statement in the quoting of the dialect.
+	Quote(statement string) string
+	// Placeholder is the placeholder format of the dialect.
+	Placeholder() sq.PlaceholderFormat
+	// Bool returns the literal of b that a BIT or BOOLEAN column compares equal to.
+	Bool(b bool) string
+	// InsertID returns an insert of columns into table whose one result row is the generated
+	// value of column id.
+	InsertID(table string, id string, columns ...string) sq.InsertBuilder
+	// Limit restricts query to its first n rows.
+	Limit(query sq.SelectBuilder, n uint64) sq.SelectBuilder
+}
+
+var (
+	// MSSQL is the Dialect of SQL Server, where Moment-Db runs.
+	MSSQL Dialect = mssqlDialect{}
+	// PostgreSQL is the Dialect of a Moment-Db migrated to PostgreSQL.
+	PostgreSQL Dialect = postgresDialect{}
+	// SQLite is the Dialect of the SQLite backend, which has no [moment] schema.
+	SQLite Dialect = sqliteDialect{}
+)
+
+var bracketed = regexp.MustCompile(`\[(\w+)\]`)
+
+type mssqlDialect struct{}
+
+func (mssqlDialect) Quote(s string) string { return s }
+
+func (mssqlDialect) Placeholder() sq.PlaceholderFormat { return sq.Question }
+
+func (mssqlDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// InsertID places the OUTPUT clause between the columns and VALUES, where SQL Server expects
+// it, because the drivers of SQL Server do not reliably support LastInsertId.
+func (mssqlDialect) InsertID(table string, id string, columns ...string) sq.InsertBuilder {
+	return sq.Insert(table + " (" + strings.Join(columns, ",") + ") OUTPUT INSERTED." + id)
+}
+
+// Limit uses TOP, as SQL Server has no LIMIT clause.
+func (mssqlDialect) Limit(query sq.SelectBuilder, n uint64) sq.SelectBuilder {
+	return query.Options(fmt.Sprintf("TOP %d", n))
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Quote(s string) string { return bracketed.ReplaceAllString(s, `"$1"`) }
+
+func (postgresDialect) Placeholder() sq.PlaceholderFormat { return sq.Dollar }
+
+func (postgresDialect) Bool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func (postgresDialect) InsertID(table string, id string, columns ...string) sq.InsertBuilder {
+	return sq.Insert(table).Columns(columns...).Suffix("RETURNING " + id)
+}
+
+func (postgresDialect) Limit(query sq.SelectBuilder, n uint64) sq.SelectBuilder {
+	return query.Limit(n)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Quote(s string) string {
+	return bracketed.ReplaceAllString(strings.ReplaceAll(s, momentSchema+".", ""), `"$1"`)
+}
+
+func (sqliteDialect) Placeholder() sq.PlaceholderFormat { return sq.Question }
+
+func (sqliteDialect) Bool(b bool) string { return MSSQL.Bool(b) }
+
+func (sqliteDialect) InsertID(table string, id string, columns ...string) sq.InsertBuilder {
+	return PostgreSQL.InsertID(table, id, columns...)
+}
+
+func (sqliteDialect) Limit(query sq.SelectBuilder, n uint64) sq.SelectBuilder {
+	return PostgreSQL.Limit(query, n)
+}
+
+// format is the squirrel PlaceholderFormat of the statements of d. squirrel passes it the
+// whole statement, so it quotes identifiers as well as replacing placeholders.
+type format struct {
+	d Dialect
+}
+
+func (f format) ReplacePlaceholders(s string) (string, error) {
+	return f.d.Placeholder().ReplacePlaceholders(f.d.Quote(s))
+}
@@ -0,0 +1,147 @@
+package moment
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialect(t *testing.T) {
+	type test struct {
+		name     string
+		d        Dialect
+		quoted   string
+		literals [2]string
+		insertID string
+	}
+	tests := []test{
+		test{"MSSQL", MSSQL,
+			`SELECT m.[ID] FROM [moment].[Moments] m WHERE m.[Public] = ?`,
+			[2]string{"1", "0"},
+			`INSERT INTO [moment].[Shares] ([MomentID],[UserID]) OUTPUT INSERTED.[ID] VALUES (?,?)`},
+		test{"PostgreSQL", PostgreSQL,
+			`SELECT m."ID" FROM "moment"."Moments" m WHERE m."Public" = ?`,
+			[2]string{"TRUE", "FALSE"},
+			`INSERT INTO "moment"."Shares" ("MomentID","UserID") VALUES ($1,$2) RETURNING "ID"`},
+		test{"SQLite", SQLite,
+			`SELECT m."ID" FROM "Moments" m WHERE m."Public" = ?`,
+			[2]string{"1", "0"},
+			`INSERT INTO "Shares" ("MomentID","UserID") VALUES (?,?) RETURNING "ID"`},
+	}
+
+	for _, v := range tests {
+		assert.Equal(t, v.quoted, v.d.Quote(`SELECT m.[ID] FROM [moment].[Moments] m WHERE m.[Public] = ?`), v.name)
+		assert.Equal(t, v.literals, [2]string{v.d.Bool(true), v.d.Bool(false)}, v.name)
+
+		s, args, err := v.d.
+			InsertID(schShares, iD, momentID, userID).
+			Values(1, tUser).
+			PlaceholderFormat(format{v.d}).
+			ToSql()
+		assert.Nil(t, err, v.name)
+		assert.Equal(t, v.insertID, s, v.name)
+		assert.Equal(t, []interface{}{1, tUser}, args, v.name)
+	}
+}
+
+func TestPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+	dt := time.Now().UTC()
+
+	t.Run("CreatePublic", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := NewMomentClient(PostgreSQL)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "moment"."Moments" ("UserID","Latitude","Longitude","Cell","Public","Hidden","CreateDate","ExpiresAt","ReleaseDate","Capacity") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "ID"`)).
+			WithArgs(tUser, lat, long, "s00000000", true, false, &dt, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(7))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "moment"."Media" ("MomentID","Message","Type","Dir") VALUES ($1,$2,$3,$4)`)).
+			WithArgs(7, "Helloworld.", DNE, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, true, false, &dt, nil, nil, nil)
+		md := mc.NewMediaRow(0, "Helloworld.", DNE, "")
+		assert.Nil(t, mc.Err())
+
+		assert.Nil(t, mc.CreatePublic(ctx, db, m, []*MediaRow{md}))
+		assert.Equal(t, int64(7), m.momentID)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FindPublic", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := NewMomentClient(PostgreSQL)
+
+		mock.ExpectBegin()
+		expectLive(mock, regexp.QuoteMeta(`SELECT "Latitude", "Longitude", "ExpiresAt", "ReleaseDate" FROM "moment"."Moments" WHERE "DeletedAt" IS NULL AND "ID" = $1 AND "Public" = $2`), nil, nil, 1, true)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "moment"."Moments" SET "Claimed" = "Claimed" + 1 WHERE "ID" = $1 AND "Public" = $2 AND ("Capacity" IS NULL OR "Claimed" < "Capacity")`)).
+			WithArgs(1, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "moment"."Finds" ("MomentID","UserID","Found","FindDate","FindLatitude","FindLongitude","FindAccuracy") VALUES ($1,$2,$3,$4,$5,$6,$7)`)).
+			WithArgs(1, tUser, true, &dt, lat, long, 5.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err = mc.FindPublic(ctx, db, mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long)))
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FindPrivate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := NewMomentClient(PostgreSQL)
+
+		expectLive(mock, regexp.QuoteMeta(`SELECT "Latitude", "Longitude", "ExpiresAt", "ReleaseDate" FROM "moment"."Moments" WHERE "DeletedAt" IS NULL AND "ID" = $1`), nil, nil, 1)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "moment"."Finds" SET "Found" = $1, "FindDate" = $2, "FindLatitude" = $3, "FindLongitude" = $4, "FindAccuracy" = $5 WHERE "MomentID" = $6 AND "UserID" = $7`)).
+			WithArgs(true, &dt, lat, long, 5.0, 1, tUser).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, mc.FindPrivate(ctx, db, mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long))))
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("LocationHidden", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := NewMomentClient(PostgreSQL)
+
+		hav := strings.ReplaceAll(regexp.QuoteMeta(PostgreSQL.Quote(havSQL)), `\?`, `\$\d+`)
+		mock.ExpectQuery(`^SELECT m\."ID", m\."Latitude", m\."Longitude", m\."Capacity", m\."Claimed", ` + hav + ` AS "SortKey" ` +
+			`FROM "moment"\."Moments" m ` +
+			`WHERE m\."Public" = TRUE AND m\."Hidden" = TRUE AND m\."DeletedAt" IS NULL ` +
+			`AND \(m\."ExpiresAt" IS NULL OR m\."ExpiresAt" > \$4\) ` +
+			`AND \(m\."ReleaseDate" IS NULL OR m\."ReleaseDate" <= \$5\) ` +
+			`AND \(m\."Capacity" IS NULL OR m\."Claimed" < m\."Capacity"\) ` +
+			`AND \(\(m\."Cell" >= \$6 AND m\."Cell" < \$7\)( OR \(m\."Cell" >= \$\d+ AND m\."Cell" < \$\d+\))*\) ` +
+			`AND ` + hav + ` <= \$\d+ AND m\."ID" IN \(SELECT m\."ID" FROM .* GROUP BY m\."ID", m\."Latitude", m\."Longitude" ` +
+			`ORDER BY ` + hav + ` ASC, m\."ID" ASC LIMIT 51\) ` +
+			`ORDER BY "SortKey" ASC, m\."ID" ASC$`).
+			WithArgs(tNearestArgs(sqlmock.AnyArg(), sqlmock.AnyArg())...).
+			WillReturnRows(sqlmock.NewRows([]string{"NoColumns"}))
+
+		_, err = mc.LocationHidden(ctx, db, mc.NewLocation(lat, long), 0, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("IsAuthor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+
+		expectExists(mock, regexp.QuoteMeta(`SELECT 1 FROM "moment"."Moments" WHERE "ID" = $1 AND "UserID" = $2`), true, nil, 1, tUser)
+
+		ok, err := NewSQLStore(db, PostgreSQL).IsAuthor(ctx, tUser, 1)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
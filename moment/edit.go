@@ -0,0 +1,279 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+const (
+	// RestoreWindow is how long the author of a deleted moment may restore it. Purge deletes the
+	// moment and the rows that reference it once RestoreWindow has passed.
+	RestoreWindow = 30 * 24 * time.Hour
+
+	deletedAt  = "[DeletedAt]"
+	mDeletedAt = momentsAlias + "." + deletedAt
+)
+
+var (
+	ErrorMediaCount       = invalid("media", "An edit must have one message per medium of the moment.")
+	ErrorMomentNotDeleted = notFound("momentID", "No unexpired moment deleted within the restore window exists for this momentID.")
+)
+
+// MomentEdit is an edit of the hidden flag and the media messages of a row in the
+// [Moment-Db].[moment].[Moments] table.
+type MomentEdit struct {
+	mID
+	hidden   bool
+	messages []string
+	err      error
+}
+
+// String returns the string representation of a MomentEdit instance.
+func (e MomentEdit) String() string {
+	return fmt.Sprintf("momentID: %v, hidden: %v, messages: %q", e.momentID, e.hidden, e.messages)
+}
+
+// NewMomentEdit is a constructor for the MomentEdit struct. messages replace, in order of
+// creation, the messages of every medium of moment mID.
+func (mc *MomentClient) NewMomentEdit(mID int64, h bool, messages []string) (e *MomentEdit) {
+	if mc.err != nil {
+		return
+	}
+
+	e = new(MomentEdit)
+
+	e.setMomentID(mID)
+	for _, m := range messages {
+		e.addMessage(m)
+	}
+	if e.err == nil && len(e.messages) == 0 {
+		e.err = ErrorMediaCount
+	}
+	if e.err != nil {
+		Error.Println(e.err)
+		mc.err = e.err
+		return
+	}
+
+	e.hidden = h
+	return
+}
+
+func (e *MomentEdit) setMomentID(mID int64) {
+	if e.err != nil {
+		return
+	}
+	e.err = e.mID.setMomentID(mID)
+}
+
+func (e *MomentEdit) addMessage(m string) {
+	if e.err != nil {
+		return
+	}
+	if len(m) > maxMessage {
+		e.err = ErrorMessageLong
+		return
+	}
+	e.messages = append(e.messages, m)
+}
+
+// Edit sets the [Hidden] of the moment of e in [Moment-Db].[moment].[Moments] and the [Message]
+// of each of its rows in [Moment-Db].[moment].[Media] in one transaction. A private moment cannot
+// be hidden.
+func (mc *MomentClient) Edit(ctx context.Context, db DbRunnerTrans, e *MomentEdit) (err error) {
+	if e == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	d := mc.dialect()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	defer func() {
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				Error.Println(txerr)
+			}
+			Error.Println(err)
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+		}
+	}()
+
+	var p bool
+	err = sq.
+		Select(public).
+		From(schMoments).
+		Where(sq.Eq{iD: e.momentID, deletedAt: nil}).
+		PlaceholderFormat(format{d}).
+		RunWith(tx).
+		QueryRowContext(ctx).
+		Scan(&p)
+	if err == sql.ErrNoRows {
+		return ErrorMomentDNE
+	}
+	if err != nil {
+		return dbError(ctxError(ctx, err))
+	}
+	if e.hidden && !p {
+		return ErrorPrivateHiddenMoment
+	}
+
+	ids, err := mc.mediaIDs(ctx, tx, e.momentID)
+	if err != nil {
+		return
+	}
+	if len(ids) != len(e.messages) {
+		return ErrorMediaCount
+	}
+
+	_, err = sq.
+		Update(schMoments).
+		Set(hidden, e.hidden).
+		Where(sq.Eq{iD: e.momentID}).
+		PlaceholderFormat(format{d}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return dbError(ctxError(ctx, err))
+	}
+	for i, id := range ids {
+		_, err = sq.
+			Update(schMedia).
+			Set(message, e.messages[i]).
+			Where(sq.Eq{iD: id}).
+			PlaceholderFormat(format{d}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return dbError(ctxError(ctx, err))
+		}
+	}
+	return
+}
+
+// mediaIDs returns the IDs of the media of moment id in order of creation.
+func (mc *MomentClient) mediaIDs(ctx context.Context, db DbRunner, id int64) (ids []int64, err error) {
+	rows, err := sq.
+		Select(iD).
+		From(schMedia).
+		Where(sq.Eq{momentID: id}).
+		OrderBy(iD).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		QueryContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mdID int64
+		if err = rows.Scan(&mdID); err != nil {
+			Error.Println(err)
+			return nil, dbError(err)
+		}
+		ids = append(ids, mdID)
+	}
+	if err = rows.Err(); err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+	return
+}
+
+// Delete sets the [DeletedAt] of moment id in [Moment-Db].[moment].[Moments] to at. The moment
+// keeps its media, finds and shares until Purge, so that Restore can bring it back.
+func (mc *MomentClient) Delete(ctx context.Context, db DbRunner, id int64, at time.Time) error {
+	return mc.setDeletedAt(ctx, db, sq.Eq{iD: id, deletedAt: nil}, at, ErrorMomentDNE)
+}
+
+// Restore clears the [DeletedAt] of moment id in [Moment-Db].[moment].[Moments] if it was set
+// within RestoreWindow before at and the moment has not expired by at. Reap deletes an expired
+// moment, and Restore must not bring it back.
+func (mc *MomentClient) Restore(ctx context.Context, db DbRunner, id int64, at time.Time) error {
+	where := sq.And{
+		sq.Eq{iD: id},
+		sq.GtOrEq{deletedAt: at.Add(-RestoreWindow)},
+		sq.Or{sq.Eq{expiresAt: nil}, sq.Gt{expiresAt: at}},
+	}
+	return mc.setDeletedAt(ctx, db, where, nil, ErrorMomentNotDeleted)
+}
+
+// setDeletedAt sets the [DeletedAt] of the moment that matches where to v, or returns dne if
+// no moment matches.
+func (mc *MomentClient) setDeletedAt(ctx context.Context, db DbRunner, where sq.Sqlizer, v interface{}, dne error) error {
+	res, err := sq.
+		Update(schMoments).
+		Set(deletedAt, v).
+		Where(where).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	if cnt == 0 {
+		Error.Println(dne)
+		return dne
+	}
+	return nil
+}
+
+// Purge deletes every moment deleted more than RestoreWindow before at from
+// [Moment-Db].[moment].[Moments], together with its rows in [Recipients], [Shares], [Finds] and
+// [Media], in one transaction. It returns how many moments it deleted.
+func (mc *MomentClient) Purge(ctx context.Context, db DbRunnerTrans, at time.Time) (n int64, err error) {
+	before := at.Add(-RestoreWindow)
+	purged := "SELECT " + iD + " FROM " + schMoments + " WHERE " + deletedAt + " < ?"
+	deletes := []sq.DeleteBuilder{
+		sq.Delete(schRecipients).Where(sharesID+" IN (SELECT "+iD+" FROM "+schShares+" WHERE "+momentID+" IN ("+purged+"))", before),
+		sq.Delete(schShares).Where(momentID+" IN ("+purged+")", before),
+		sq.Delete(schFinds).Where(momentID+" IN ("+purged+")", before),
+		sq.Delete(schMedia).Where(momentID+" IN ("+purged+")", before),
+		sq.Delete(schMoments).Where(sq.Lt{deletedAt: before}),
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	var res sql.Result
+	for _, del := range deletes {
+		res, err = del.PlaceholderFormat(format{mc.dialect()}).RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			Error.Println(err)
+			if txerr := tx.Rollback(); txerr != nil {
+				Error.Println(txerr)
+			}
+			return 0, dbError(ctxError(ctx, err))
+		}
+	}
+	if n, err = res.RowsAffected(); err != nil {
+		Error.Println(err)
+		tx.Rollback()
+		return 0, dbError(ctxError(ctx, err))
+	}
+	if err = tx.Commit(); err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	return n, nil
+}
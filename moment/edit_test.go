@@ -0,0 +1,183 @@
+package moment
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMomentEdit(t *testing.T) {
+	type test struct {
+		momentID int64
+		hidden   bool
+		messages []string
+		expected error
+	}
+	tests := []test{
+		test{1, true, []string{"edited", ""}, nil},
+		test{-1, false, []string{"edited"}, ErrorMomentID},
+		test{1, false, nil, ErrorMediaCount},
+		test{1, false, []string{strings.Repeat("m", maxMessage+1)}, ErrorMessageLong},
+	}
+
+	for _, v := range tests {
+		mc := new(MomentClient)
+		e := mc.NewMomentEdit(v.momentID, v.hidden, v.messages)
+		assert.Exactly(t, v.expected, mc.Err())
+		if v.expected == nil {
+			assert.Equal(t, `momentID: 1, hidden: true, messages: ["edited" ""]`, e.String())
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	t.Run("Parameter Checks", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := new(MomentClient)
+		assert.Equal(t, ErrorParameterEmpty, mc.Edit(context.Background(), db, nil))
+	})
+
+	type test struct {
+		name     string
+		public   []bool
+		media    int
+		expected error
+	}
+	tests := []test{
+		test{"1", []bool{true}, 2, nil},
+		test{"deleted", nil, 0, ErrorMomentDNE},
+		test{"private", []bool{false}, 0, ErrorPrivateHiddenMoment},
+		test{"media count", []bool{true}, 1, ErrorMediaCount},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			mc := new(MomentClient)
+			e := mc.NewMomentEdit(1, true, []string{"edited", "edited"})
+
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"Public"})
+			for _, p := range v.public {
+				rows.AddRow(p)
+			}
+			mock.ExpectQuery(`^SELECT \[Public\] FROM \[moment\]\.\[Moments\] WHERE \[DeletedAt\] IS NULL AND \[ID\] = \?$`).
+				WithArgs(1).
+				WillReturnRows(rows)
+			if len(v.public) > 0 && v.public[0] {
+				rows := sqlmock.NewRows([]string{"ID"})
+				for i := 0; i < v.media; i++ {
+					rows.AddRow(10 + i)
+				}
+				mock.ExpectQuery(`^SELECT \[ID\] FROM \[moment\]\.\[Media\] WHERE \[MomentID\] = \? ORDER BY \[ID\]$`).
+					WithArgs(1).
+					WillReturnRows(rows)
+			}
+			if v.expected == nil {
+				mock.ExpectExec(`^UPDATE \[moment\]\.\[Moments\] SET \[Hidden\] = \? WHERE \[ID\] = \?$`).
+					WithArgs(true, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				for i := 0; i < v.media; i++ {
+					mock.ExpectExec(`^UPDATE \[moment\]\.\[Media\] SET \[Message\] = \? WHERE \[ID\] = \?$`).
+						WithArgs("edited", 10+i).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = mc.Edit(context.Background(), db, e)
+			assert.Exactly(t, v.expected, err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteRestore(t *testing.T) {
+	at := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	type test struct {
+		name     string
+		restore  bool
+		affected int64
+		expected error
+	}
+	tests := []test{
+		test{"Delete", false, 1, nil},
+		test{"Delete of a deleted moment", false, 0, ErrorMomentDNE},
+		test{"Restore", true, 1, nil},
+		test{"Restore after RestoreWindow", true, 0, ErrorMomentNotDeleted},
+	}
+	for _, v := range tests {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mc := new(MomentClient)
+
+		if v.restore {
+			mock.ExpectExec(`^UPDATE \[moment\]\.\[Moments\] SET \[DeletedAt\] = \? WHERE \(\[ID\] = \? AND \[DeletedAt\] >= \? AND \(\[ExpiresAt\] IS NULL OR \[ExpiresAt\] > \?\)\)$`).
+				WithArgs(nil, 1, at.Add(-RestoreWindow), at).
+				WillReturnResult(sqlmock.NewResult(0, v.affected))
+			err = mc.Restore(context.Background(), db, 1, at)
+		} else {
+			mock.ExpectExec(`^UPDATE \[moment\]\.\[Moments\] SET \[DeletedAt\] = \? WHERE \[DeletedAt\] IS NULL AND \[ID\] = \?$`).
+				WithArgs(at, 1).
+				WillReturnResult(sqlmock.NewResult(0, v.affected))
+			err = mc.Delete(context.Background(), db, 1, at)
+		}
+		assert.Exactly(t, v.expected, err, v.name)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.name)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	at := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := at.Add(-RestoreWindow)
+	purged := `SELECT \[ID\] FROM \[moment\]\.\[Moments\] WHERE \[DeletedAt\] < \?`
+
+	expect := func(mock sqlmock.Sqlmock, fail bool) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Recipients\] WHERE \[SharesID\] IN \(SELECT \[ID\] FROM \[moment\]\.\[Shares\] WHERE \[MomentID\] IN \(` + purged + `\)\)$`).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Shares\] WHERE \[MomentID\] IN \(` + purged + `\)$`).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		if fail {
+			mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Finds\]`).WillReturnError(netError{})
+			mock.ExpectRollback()
+			return
+		}
+		mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Finds\] WHERE \[MomentID\] IN \(` + purged + `\)$`).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Media\] WHERE \[MomentID\] IN \(` + purged + `\)$`).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`^DELETE FROM \[moment\]\.\[Moments\] WHERE \[DeletedAt\] < \?$`).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	expect(mock, false)
+	n, err := new(MomentClient).Purge(context.Background(), db, at)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	db, mock, err = sqlmock.New()
+	assert.Nil(t, err)
+	expect(mock, true)
+	n, err = new(MomentClient).Purge(context.Background(), db, at)
+	assert.True(t, errors.Is(err, ErrorUnavailable))
+	assert.Zero(t, n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,188 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Kind classifies the errors returned by this package so that callers can react to the class
+// of a failure without matching on individual sentinels.
+type Kind int
+
+const (
+	// KindInternal is the Kind of errors that this package cannot attribute to the caller or to Moment-Db.
+	KindInternal Kind = iota
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindForbidden
+	KindUnavailable
+)
+
+var kindNames = map[Kind]string{
+	KindInternal:    "internal",
+	KindValidation:  "validation",
+	KindNotFound:    "not-found",
+	KindConflict:    "conflict",
+	KindForbidden:   "forbidden",
+	KindUnavailable: "unavailable",
+}
+
+// String returns the name of k.
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// ErrorValidation, ErrorNotFound, ErrorConflict, ErrorForbidden and ErrorUnavailable match,
+// through errors.Is, every DomainError of the corresponding Kind.
+var (
+	ErrorValidation  = errors.New("validation failed")
+	ErrorNotFound    = errors.New("not found")
+	ErrorConflict    = errors.New("conflict")
+	ErrorForbidden   = errors.New("forbidden")
+	ErrorUnavailable = errors.New("Moment-Db is unavailable")
+)
+
+var kindErrors = map[Kind]error{
+	KindValidation:  ErrorValidation,
+	KindNotFound:    ErrorNotFound,
+	KindConflict:    ErrorConflict,
+	KindForbidden:   ErrorForbidden,
+	KindUnavailable: ErrorUnavailable,
+}
+
+// DomainError is a classified error. Field names the offending input, if there is one,
+// and Err is the underlying sentinel or driver error.
+type DomainError struct {
+	Kind  Kind
+	Field string
+	Err   error
+}
+
+// Error returns the message of the wrapped error.
+func (e *DomainError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the class sentinel of e's Kind.
+func (e *DomainError) Is(target error) bool {
+	k, ok := kindErrors[e.Kind]
+	return ok && k == target
+}
+
+// KindOf returns the Kind of the first DomainError in err's chain, or KindInternal.
+func KindOf(err error) Kind {
+	var e *DomainError
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// FieldOf returns the Field of the first DomainError in err's chain, or "".
+func FieldOf(err error) string {
+	var e *DomainError
+	if errors.As(err, &e) {
+		return e.Field
+	}
+	return ""
+}
+
+// ChunkError is the error of an insert that Moment-Db ran in Chunks statements. Chunk is the
+// 1-based statement that failed and Rows the number of rows the statements before it inserted,
+// which the enclosing transaction rolls back. Err is the classified error of the statement.
+type ChunkError struct {
+	Chunk  int
+	Chunks int
+	Rows   int64
+	Err    error
+}
+
+// Error returns the progress of the insert and the message of the wrapped error.
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d of %d failed after %d rows: %v", e.Chunk, e.Chunks, e.Rows, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// invalid is a constructor for validation sentinels.
+func invalid(field string, msg string) error {
+	return &DomainError{Kind: KindValidation, Field: field, Err: errors.New(msg)}
+}
+
+// notFound is a constructor for not-found sentinels.
+func notFound(field string, msg string) error {
+	return &DomainError{Kind: KindNotFound, Field: field, Err: errors.New(msg)}
+}
+
+// conflict is a constructor for conflict sentinels.
+func conflict(field string, msg string) error {
+	return &DomainError{Kind: KindConflict, Field: field, Err: errors.New(msg)}
+}
+
+// forbidden is a constructor for forbidden sentinels.
+func forbidden(field string, msg string) error {
+	return &DomainError{Kind: KindForbidden, Field: field, Err: errors.New(msg)}
+}
+
+// ctxError returns the error of ctx in place of err once ctx is done, because a driver
+// reports a statement it abandoned on cancellation with an error of its own.
+func ctxError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// dbError classifies an error returned by database/sql or the Moment-Db driver.
+// Errors that are already classified are returned unchanged.
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *DomainError
+	if errors.As(err, &e) {
+		return err
+	}
+
+	var ne net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &DomainError{Kind: KindNotFound, Err: err}
+	case errors.Is(err, sqldriver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, context.Canceled),
+		errors.As(err, &ne):
+		return &DomainError{Kind: KindUnavailable, Err: err}
+	}
+
+	// The drivers report constraint violations only through the message text of SQL Server,
+	// PostgreSQL or SQLite.
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "Violation of PRIMARY KEY"),
+		strings.Contains(msg, "Violation of UNIQUE KEY"),
+		strings.Contains(msg, "Cannot insert duplicate key"),
+		strings.Contains(msg, "duplicate key value violates unique constraint"),
+		strings.Contains(msg, "UNIQUE constraint failed"):
+		return &DomainError{Kind: KindConflict, Err: err}
+	case strings.Contains(msg, "FOREIGN KEY constraint"),
+		strings.Contains(msg, "violates foreign key constraint"):
+		return &DomainError{Kind: KindNotFound, Field: "momentID", Err: err}
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+	"time"
+)
+
+func TestDomainError(t *testing.T) {
+	type test struct {
+		err   error
+		class error
+		kind  Kind
+		field string
+	}
+	tests := []test{
+		test{ErrorLatitude, ErrorValidation, KindValidation, "latitude"},
+		test{ErrorUserIDShort, ErrorValidation, KindValidation, "userID"},
+		test{ErrorMediaDNE, ErrorValidation, KindValidation, "dir"},
+		test{ErrorParameterEmpty, ErrorValidation, KindValidation, ""},
+		test{ErrorFindsRowDNE, ErrorNotFound, KindNotFound, "momentID"},
+	}
+
+	for _, v := range tests {
+		wrapped := fmt.Errorf("handler: %w", v.err)
+		assert.True(t, errors.Is(wrapped, v.err))
+		assert.True(t, errors.Is(wrapped, v.class))
+		assert.Equal(t, v.kind, KindOf(wrapped))
+		assert.Equal(t, v.field, FieldOf(wrapped))
+	}
+
+	assert.False(t, errors.Is(ErrorLatitude, ErrorNotFound))
+	assert.Equal(t, KindInternal, KindOf(ErrorTypeNotImplemented))
+}
+
+type netError struct{}
+
+func (netError) Error() string   { return "dial tcp: connection refused" }
+func (netError) Timeout() bool   { return false }
+func (netError) Temporary() bool { return true }
+
+func Test_dbError(t *testing.T) {
+	type test struct {
+		err      error
+		expected Kind
+	}
+	tests := []test{
+		test{sql.ErrNoRows, KindNotFound},
+		test{sqldriver.ErrBadConn, KindUnavailable},
+		test{context.DeadlineExceeded, KindUnavailable},
+		test{netError{}, KindUnavailable},
+		test{errors.New("Violation of PRIMARY KEY constraint 'PK_Finds'."), KindConflict},
+		test{errors.New("The INSERT statement conflicted with the FOREIGN KEY constraint \"FK_Finds_Moments\"."), KindNotFound},
+		test{errors.New("pq: duplicate key value violates unique constraint \"Finds_pkey\""), KindConflict},
+		test{errors.New("pq: insert or update on table \"Finds\" violates foreign key constraint \"Finds_MomentID_fkey\""), KindNotFound},
+		test{errors.New("constraint failed: UNIQUE constraint failed: Finds.MomentID, Finds.UserID (1555)"), KindConflict},
+		test{errors.New("constraint failed: FOREIGN KEY constraint failed (787)"), KindNotFound},
+		test{errors.New("Incorrect syntax near 'FROM'."), KindInternal},
+		test{ErrorLatitude, KindValidation},
+	}
+
+	for _, v := range tests {
+		assert.Equal(t, v.expected, KindOf(dbError(v.err)), v.err.Error())
+	}
+	assert.Nil(t, dbError(nil))
+}
+
+func TestFindPrivateNoRow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	expectLive(mock, liveRegexp, nil, nil, 1)
+	mock.ExpectExec(`^UPDATE`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mc := new(MomentClient)
+	dt := time.Now().UTC()
+	err = mc.FindPrivate(context.Background(), db, mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long)))
+	assert.True(t, errors.Is(err, ErrorNotFound))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	mock.ExpectQuery(`^SELECT`).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{iD}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	mc := new(MomentClient)
+	_, err = mc.LocationPublic(ctx, db, mc.NewLocation(lat, long), 0, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, errors.Is(err, ErrorUnavailable))
+}
+
+func TestBeginCanceled(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mc := new(MomentClient)
+	dt := time.Now().UTC()
+	m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, true, false, &dt, nil, nil, nil)
+	md := mc.NewMediaRow(0, "message", DNE, "")
+	err = mc.CreatePublic(ctx, db, m, []*MediaRow{md})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, KindUnavailable, KindOf(err))
+}
@@ -0,0 +1,114 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+const (
+	expiresAt  = "[ExpiresAt]"
+	mExpiresAt = momentsAlias + "." + expiresAt
+)
+
+var (
+	ErrorExpiresAt     = invalid("expiresAt", "A moment must expire after it is created.")
+	ErrorMomentExpired = notFound("momentID", "The moment has expired.")
+)
+
+// Reaper removes the moments that are past their lifetime. A Store that keeps moments runs it
+// on a schedule.
+type Reaper interface {
+	// Reap deletes every moment that expired by at, as of its expiry, and returns how many it
+	// deleted.
+	Reap(ctx context.Context, at time.Time) (int64, error)
+	// Purge removes every moment deleted more than RestoreWindow before at and returns how
+	// many it removed.
+	Purge(ctx context.Context, at time.Time) (int64, error)
+	// SweepKeys deletes every idempotency key that expired by at and returns how many it
+	// deleted.
+	SweepKeys(ctx context.Context, at time.Time) (int64, error)
+}
+
+func (m *MomentsRow) setExpiresAt(t *time.Time) {
+	if m.err != nil || t == nil {
+		return
+	}
+
+	if m.createDate != nil && !t.After(*m.createDate) {
+		m.err = ErrorExpiresAt
+		return
+	}
+	m.expiresAt = t
+}
+
+// expired reports whether m has expired by at.
+func (m *MomentsRow) expired(at time.Time) bool {
+	return m.expiresAt != nil && !m.expiresAt.After(at)
+}
+
+// notExpired matches the moments of a selector that have not expired by at.
+func notExpired(at time.Time) sq.Or {
+	return sq.Or{sq.Eq{mExpiresAt: nil}, sq.Gt{mExpiresAt: at}}
+}
+
+// live returns the Location of moment id, ErrorMomentDNE unless it exists, is not deleted and
+// matches every predicate of where, ErrorMomentExpired if it has expired and
+// ErrorMomentUnreleased if it is a time capsule that has not been released.
+func (mc *MomentClient) live(ctx context.Context, db DbRunner, id int64, where ...sq.Eq) (l Location, err error) {
+	var e, r sql.NullTime
+	query := sq.
+		Select(latStr, longStr, expiresAt, releaseDate).
+		From(schMoments).
+		Where(sq.Eq{iD: id, deletedAt: nil})
+	for _, w := range where {
+		query = query.Where(w)
+	}
+	err = query.
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		QueryRowContext(ctx).
+		Scan(&l.latitude, &l.longitude, &e, &r)
+	if err == sql.ErrNoRows {
+		Error.Println(ErrorMomentDNE)
+		return l, ErrorMomentDNE
+	}
+	if err != nil {
+		Error.Println(err)
+		return l, dbError(ctxError(ctx, err))
+	}
+	now := time.Now()
+	if e.Valid && !e.Time.After(now) {
+		Error.Println(ErrorMomentExpired)
+		return l, ErrorMomentExpired
+	}
+	if r.Valid && r.Time.After(now) {
+		Error.Println(ErrorMomentUnreleased)
+		return l, ErrorMomentUnreleased
+	}
+	return l, nil
+}
+
+// Reap sets the [DeletedAt] of every moment in [Moment-Db].[moment].[Moments] whose [ExpiresAt]
+// is at or before at to its [ExpiresAt], so that Purge removes it RestoreWindow after it expired.
+func (mc *MomentClient) Reap(ctx context.Context, db DbRunner, at time.Time) (int64, error) {
+	res, err := sq.
+		Update(schMoments).
+		Set(deletedAt, sq.Expr(expiresAt)).
+		Where(sq.Eq{deletedAt: nil}).
+		Where(sq.LtOrEq{expiresAt: at}).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package moment
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+	"time"
+)
+
+const (
+	// liveRegexp matches the query by which a modification checks that its moment is neither
+	// deleted, expired nor unreleased.
+	liveRegexp = `^SELECT \[Latitude\], \[Longitude\], \[ExpiresAt\], \[ReleaseDate\] FROM \[moment\]\.\[Moments\] WHERE \[DeletedAt\] IS NULL AND \[ID\] = \?$`
+	// livePublicRegexp matches the query by which FindPublic checks that its moment is also public.
+	livePublicRegexp = `^SELECT \[Latitude\], \[Longitude\], \[ExpiresAt\], \[ReleaseDate\] FROM \[moment\]\.\[Moments\] WHERE \[DeletedAt\] IS NULL AND \[ID\] = \? AND \[Public\] = \?$`
+
+	// visibleRegexp matches the predicates by which every selector skips deleted and expired moments.
+	visibleRegexp = `m\.\[DeletedAt\] IS NULL AND \(m\.\[ExpiresAt\] IS NULL OR m\.\[ExpiresAt\] > \?\)`
+)
+
+// expectLive queues a query for regexp with args that returns a moment at lat, long expiring at e
+// and released at r, or never expiring and already released if they are nil.
+func expectLive(mock sqlmock.Sqlmock, regexp string, e *time.Time, r *time.Time, args ...sqldriver.Value) {
+	row := []sqldriver.Value{lat, long, nil, nil}
+	if e != nil {
+		row[2] = *e
+	}
+	if r != nil {
+		row[3] = *r
+	}
+	rows := sqlmock.NewRows([]string{"Latitude", "Longitude", "ExpiresAt", "ReleaseDate"}).AddRow(row...)
+	mock.ExpectQuery(regexp).WithArgs(args...).WillReturnRows(rows)
+}
+
+func TestLive(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	type test struct {
+		name     string
+		e        *time.Time
+		r        *time.Time
+		expected error
+	}
+	tests := []test{
+		test{"never expires", nil, nil, nil},
+		test{"expires later", &future, nil, nil},
+		test{"expired", &past, nil, ErrorMomentExpired},
+		test{"released", nil, &past, nil},
+		test{"unreleased", nil, &future, ErrorMomentUnreleased},
+	}
+	for _, v := range tests {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+
+		expectLive(mock, liveRegexp, v.e, v.r, 1)
+		_, err = new(MomentClient).live(context.Background(), db, 1)
+		assert.Exactly(t, v.expected, err, v.name)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.name)
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectQuery(liveRegexp).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"Latitude", "Longitude", "ExpiresAt", "ReleaseDate"}))
+	_, err = new(MomentClient).live(context.Background(), db, 1)
+	assert.Exactly(t, ErrorMomentDNE, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReap(t *testing.T) {
+	at := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	mock.ExpectExec(`^UPDATE \[moment\]\.\[Moments\] SET \[DeletedAt\] = \[ExpiresAt\] WHERE \[DeletedAt\] IS NULL AND \[ExpiresAt\] <= \?$`).
+		WithArgs(at).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := new(MomentClient).Reap(context.Background(), db, at)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package moment
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// earthRadius is the mean radius of the Earth in meters.
+	earthRadius = 6371008.8
+
+	// DefaultRadius and MaxRadius are the default and largest radius in meters of a location selector.
+	DefaultRadius = 1000
+	MaxRadius     = 100000
+)
+
+var ErrorRadius = invalid("radius", fmt.Sprintf("Radius must be between 0 and %d meters.", MaxRadius))
+
+// heading is how far, in meters, and in which direction, in degrees clockwise from north, a
+// moment lies from the Location of a location selector.
+type heading struct {
+	distance float64
+	bearing  float64
+}
+
+func radians(deg float32) float64 {
+	return float64(deg) * math.Pi / 180
+}
+
+// haversine returns the great-circle distance in meters between a and b.
+func haversine(a Location, b Location) float64 {
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(hav(a, b))))
+}
+
+// hav returns the haversine of the central angle between a and b. It grows with the distance
+// between a and b, so location selectors filter and order by it in SQL, see havSQL.
+func hav(a Location, b Location) float64 {
+	lat1, lat2 := radians(a.latitude), radians(b.latitude)
+	dlat, dlong := lat2-lat1, radians(b.longitude-a.longitude)
+
+	return math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlong/2)*math.Sin(dlong/2)
+}
+
+// havOf returns the hav of two locations meters apart.
+func havOf(meters float64) float64 {
+	s := math.Sin(meters / earthRadius / 2)
+	return s * s
+}
+
+// bearing returns the initial bearing in degrees in [0, 360) of the great circle from a to b.
+func bearing(a Location, b Location) float64 {
+	lat1, lat2 := radians(a.latitude), radians(b.latitude)
+	dlong := radians(b.longitude - a.longitude)
+
+	y := math.Sin(dlong) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dlong)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// bounds is a box of latitudes from south to north and longitudes from west to east in degrees.
+type bounds struct {
+	south float64
+	north float64
+	west  float64
+	east  float64
+}
+
+// boxes returns the bounds that together hold every point within radius meters of l. A circle
+// that crosses the antimeridian needs a box on each side of it, and one that reaches a pole
+// spans every longitude.
+func boxes(l Location, radius float64) []bounds {
+	dlat := radius / earthRadius
+	lat, long := radians(l.latitude), float64(l.longitude)
+
+	b := bounds{
+		south: math.Max(float64(l.latitude)-dlat*180/math.Pi, minLat),
+		north: math.Min(float64(l.latitude)+dlat*180/math.Pi, maxLat),
+		west:  minLong,
+		east:  maxLong,
+	}
+	if b.south == minLat || b.north == maxLat {
+		return []bounds{b}
+	}
+
+	dlong := math.Asin(math.Min(1, math.Sin(dlat)/math.Cos(lat))) * 180 / math.Pi
+	b.west, b.east = long-dlong, long+dlong
+	switch {
+	case b.west < minLong:
+		w := b
+		w.west, w.east = b.west+360, maxLong
+		b.west = minLong
+		return []bounds{w, b}
+	case b.east > maxLong:
+		e := b
+		e.west, e.east = minLong, b.east-360
+		b.east = maxLong
+		return []bounds{b, e}
+	}
+	return []bounds{b}
+}
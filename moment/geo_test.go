@@ -0,0 +1,139 @@
+package moment
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func Test_haversine(t *testing.T) {
+	type test struct {
+		name     string
+		a        Location
+		b        Location
+		expected float64
+	}
+	tests := []test{
+		test{"same point", Location{latitude: 10, longitude: 20}, Location{latitude: 10, longitude: 20}, 0},
+		test{"degree of latitude", Location{latitude: 0, longitude: 0}, Location{latitude: 1, longitude: 0}, 111195},
+		test{"degree of longitude at 60N", Location{latitude: 60, longitude: 0}, Location{latitude: 60, longitude: 1}, 55597},
+		test{"pole to pole", Location{latitude: 90, longitude: 0}, Location{latitude: -90, longitude: 0}, math.Pi * earthRadius},
+		test{"London to Paris", Location{latitude: 51.5074, longitude: -0.1278}, Location{latitude: 48.8566, longitude: 2.3522}, 343556},
+	}
+
+	for _, v := range tests {
+		assert.InDelta(t, v.expected, haversine(v.a, v.b), 1, v.name)
+		assert.InDelta(t, v.expected, haversine(v.b, v.a), 1, v.name)
+	}
+}
+
+func Test_bearing(t *testing.T) {
+	type test struct {
+		name     string
+		b        Location
+		expected float64
+	}
+	tests := []test{
+		test{"north", Location{latitude: 1, longitude: 0}, 0},
+		test{"east", Location{latitude: 0, longitude: 1}, 90},
+		test{"south", Location{latitude: -1, longitude: 0}, 180},
+		test{"west", Location{latitude: 0, longitude: -1}, 270},
+	}
+
+	for _, v := range tests {
+		assert.InDelta(t, v.expected, bearing(Location{}, v.b), 1e-6, v.name)
+	}
+}
+
+// tDestination returns the point distance meters from l along the great circle with initial
+// bearing degrees clockwise from north.
+func tDestination(l Location, distance float64, bearing float64) Location {
+	lat, long := radians(l.latitude), radians(l.longitude)
+	d, b := distance/earthRadius, bearing*math.Pi/180
+
+	lat2 := math.Asin(math.Sin(lat)*math.Cos(d) + math.Cos(lat)*math.Sin(d)*math.Cos(b))
+	long2 := long + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat), math.Cos(d)-math.Sin(lat)*math.Sin(lat2))
+	return Location{
+		latitude:  float32(lat2 * 180 / math.Pi),
+		longitude: float32(math.Mod(long2*180/math.Pi+540, 360) - 180),
+	}
+}
+
+// tGlobe returns a random Location, a third of the time within a degree of the antimeridian
+// and a third of the time within a degree of a pole.
+func tGlobe(rnd *rand.Rand) Location {
+	l := Location{latitude: float32(rnd.Float64()*180 - 90), longitude: float32(rnd.Float64()*360 - 180)}
+	switch rnd.Intn(3) {
+	case 0:
+		l.longitude = float32(180 - rnd.Float64())
+		if rnd.Intn(2) == 0 {
+			l.longitude = -l.longitude
+		}
+	case 1:
+		l.latitude = float32(90 - rnd.Float64())
+		if rnd.Intn(2) == 0 {
+			l.latitude = -l.latitude
+		}
+	}
+	return l
+}
+
+func Test_boxes(t *testing.T) {
+	type test struct {
+		name     string
+		l        Location
+		radius   float64
+		expected int
+	}
+	tests := []test{
+		test{"equator", Location{latitude: 0, longitude: 0}, 1000, 1},
+		test{"north", Location{latitude: 60, longitude: 10}, 5000, 1},
+		test{"south", Location{latitude: -45.5, longitude: -120}, MaxRadius, 1},
+		test{"east of the antimeridian", Location{latitude: -17.7, longitude: -179.99}, 5000, 2},
+		test{"west of the antimeridian", Location{latitude: 65.5, longitude: 179.95}, 10000, 2},
+		test{"north pole", Location{latitude: 89.995, longitude: 30}, 1000, 1},
+		test{"south pole", Location{latitude: -89.5, longitude: 179.9}, MaxRadius, 1},
+	}
+
+	for _, v := range tests {
+		bs := boxes(v.l, v.radius)
+		assert.Equal(t, v.expected, len(bs), v.name)
+		for _, b := range bs {
+			assert.True(t, minLat <= b.south && b.south < b.north && b.north <= maxLat, v.name)
+			assert.True(t, minLong <= b.west && b.west < b.east && b.east <= maxLong, v.name)
+		}
+	}
+
+	bs := boxes(Location{latitude: 89.995, longitude: 30}, 1000)
+	assert.Equal(t, []float64{90, -180, 180}, []float64{bs[0].north, bs[0].west, bs[0].east}, "a circle around a pole spans every longitude")
+
+	bs = boxes(Location{latitude: 0, longitude: 179.995}, 1000)
+	assert.InDelta(t, 179.986, bs[0].west, 1e-3)
+	assert.Equal(t, float64(maxLong), bs[0].east)
+	assert.Equal(t, float64(minLong), bs[1].west)
+	assert.InDelta(t, -179.996, bs[1].east, 1e-3)
+}
+
+// Test_boxesProperty checks that every point within radius meters of a Location anywhere on the
+// globe lies within one of its boxes.
+func Test_boxesProperty(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		l := tGlobe(rnd)
+		radius := rnd.Float64() * MaxRadius
+		bs := boxes(l, radius)
+		for j := 0; j < 20; j++ {
+			m := tDestination(l, rnd.Float64()*radius, rnd.Float64()*360)
+			if haversine(l, m) > radius {
+				continue
+			}
+			in := false
+			for _, b := range bs {
+				lat, long := float64(m.latitude), float64(m.longitude)
+				in = in || (b.south <= lat && lat <= b.north && b.west <= long && long <= b.east)
+			}
+			assert.True(t, in, "%v is within %v meters of %v", m, radius, l)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package moment
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+const (
+	// maxKeyChars is the length of the longest Idempotency-Key Moment-Db stores.
+	maxKeyChars = 255
+
+	// KeyLease is how long a request holds the key it reserved. A key whose request neither
+	// completed nor released it within KeyLease, because its process stopped, may be reserved again.
+	KeyLease = 5 * time.Minute
+
+	// KeyTTL is how long a key is kept, and the response to its request replayed, after it was
+	// reserved. The reaper deletes the keys that have expired.
+	KeyTTL = 24 * time.Hour
+
+	idempotencyKeys    = "[IdempotencyKeys]"
+	schIdempotencyKeys = momentSchema + "." + idempotencyKeys
+
+	idempotencyKey = "[IdempotencyKey]"
+	requestHash    = "[RequestHash]"
+	status         = "[Status]"
+	contentType    = "[ContentType]"
+	headers        = "[Headers]"
+	body           = "[Body]"
+)
+
+var (
+	ErrorKeyInvalid    = invalid("Idempotency-Key", fmt.Sprintf("Idempotency-Key must be between 1 and %d characters.", maxKeyChars))
+	ErrorKeyReused     = conflict("Idempotency-Key", "Idempotency-Key was already used for a different request.")
+	ErrorKeyInProgress = conflict("Idempotency-Key", "A request with this Idempotency-Key is still in progress.")
+	ErrorKeyDNE        = notFound("Idempotency-Key", "No request reserved this Idempotency-Key.")
+)
+
+// IdempotencyStore keeps the response of every request sent with an Idempotency-Key, so that a
+// retry of the request is answered with the original response instead of being run again.
+// Keys are scoped to the user that sent them.
+type IdempotencyStore interface {
+	// ReserveKey reserves k for the request it hashes and returns nil, or returns the response
+	// of the request that reserved k before. It returns ErrorKeyReused if that request had a
+	// different hash and ErrorKeyInProgress if it has no response yet.
+	ReserveKey(ctx context.Context, k *IdempotencyKeysRow) (*Response, error)
+	// CompleteKey stores the response of the request that reserved k.
+	CompleteKey(ctx context.Context, k *IdempotencyKeysRow, res *Response) error
+	// ReleaseKey drops the reservation of k, so that the request may be run again.
+	ReleaseKey(ctx context.Context, k *IdempotencyKeysRow) error
+}
+
+// Response is the response to a request that IdempotencyStore replays. Header is its whole
+// header set.
+type Response struct {
+	Status int
+	Header map[string][]string
+	Body   []byte
+}
+
+// IdempotencyKeysRow is a row in the [Moment-Db].[moment].[IdempotencyKeys] table. A row
+// without a response is reserved by a request that is still running.
+type IdempotencyKeysRow struct {
+	uID
+	key        string
+	hash       []byte
+	createDate *time.Time
+	expiresAt  *time.Time
+	response   *Response
+	err        error
+}
+
+// String returns the string representation of IdempotencyKeysRow.
+func (k IdempotencyKeysRow) String() string {
+	return fmt.Sprintf("userID: %v, key: %v, hash: %x, createDate: %v",
+		k.userID,
+		k.key,
+		k.hash,
+		k.createDate)
+}
+
+// NewIdempotencyKeysRow is a constructor for the IdempotencyKeysRow struct. hash identifies
+// the request sent with key and c is when it was received. The key expires KeyTTL after c.
+func (mc *MomentClient) NewIdempotencyKeysRow(uID string, key string, hash []byte, c *time.Time) (k *IdempotencyKeysRow) {
+	if mc.err != nil {
+		return
+	}
+
+	k = new(IdempotencyKeysRow)
+
+	k.setUserID(uID)
+	k.setKey(key)
+	if k.err == nil && (len(hash) == 0 || c == nil) {
+		k.err = ErrorParameterEmpty
+	}
+	if k.err != nil {
+		Error.Println(k.err)
+		mc.err = k.err
+		return
+	}
+
+	k.hash = hash
+	utc := c.UTC()
+	e := utc.Add(KeyTTL)
+	k.createDate, k.expiresAt = &utc, &e
+	return
+}
+
+func (k *IdempotencyKeysRow) setUserID(id string) {
+	if k.err != nil {
+		return
+	}
+	k.err = k.uID.setUserID(id)
+}
+
+func (k *IdempotencyKeysRow) setKey(key string) {
+	if k.err != nil {
+		return
+	}
+	if l := len(key); l == 0 || l > maxKeyChars {
+		k.err = ErrorKeyInvalid
+		return
+	}
+	k.key = key
+}
+
+// replay returns the response stored for k, or the error ReserveKey returns when the stored
+// row was reserved by another request.
+func (k *IdempotencyKeysRow) replay(stored *IdempotencyKeysRow) (*Response, error) {
+	if !bytes.Equal(k.hash, stored.hash) {
+		return nil, ErrorKeyReused
+	}
+	if stored.response == nil {
+		return nil, ErrorKeyInProgress
+	}
+	return stored.response, nil
+}
+
+// stale reports whether stored has expired, or has been reserved for longer than KeyLease, by
+// the time of k, so that k may take its place.
+func (k *IdempotencyKeysRow) stale(stored *IdempotencyKeysRow) bool {
+	if !stored.expiresAt.After(*k.createDate) {
+		return true
+	}
+	return stored.response == nil && stored.createDate.Before(k.createDate.Add(-KeyLease))
+}
+
+// keyExpired matches the keys that have expired by at. A key reserved before [ExpiresAt] was
+// added expires KeyTTL after its [CreateDate].
+func keyExpired(at time.Time) sq.Or {
+	return sq.Or{
+		sq.LtOrEq{expiresAt: at},
+		sq.And{sq.Eq{expiresAt: nil}, sq.LtOrEq{createDate: at.Add(-KeyTTL)}},
+	}
+}
+
+// ReserveKey inserts k into [Moment-Db].[moment].[IdempotencyKeys] without a response. If k
+// exists it returns the response stored for it; see IdempotencyStore.
+func (mc *MomentClient) ReserveKey(ctx context.Context, db DbRunner, k *IdempotencyKeysRow) (*Response, error) {
+	if k == nil {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := insert(ctx, mc.dialect(), db, k)
+		if KindOf(err) != KindConflict {
+			return nil, err
+		}
+
+		stored, err := mc.selectKey(ctx, db, k)
+		switch {
+		case err == sql.ErrNoRows:
+			continue
+		case err != nil:
+			return nil, err
+		case attempt > 0 || !k.stale(stored):
+			return k.replay(stored)
+		}
+
+		_, err = sq.
+			Delete(schIdempotencyKeys).
+			Where(sq.Eq{userID: k.userID, idempotencyKey: k.key}).
+			Where(sq.Or{
+				sq.And{sq.Eq{status: nil}, sq.Lt{createDate: k.createDate.Add(-KeyLease)}},
+				keyExpired(*k.createDate),
+			}).
+			PlaceholderFormat(format{mc.dialect()}).
+			RunWith(db).
+			ExecContext(ctx)
+		if err != nil {
+			Error.Println(err)
+			return nil, dbError(ctxError(ctx, err))
+		}
+	}
+	return nil, ErrorKeyInProgress
+}
+
+// selectKey returns the row of [Moment-Db].[moment].[IdempotencyKeys] with the user and key of
+// k, or sql.ErrNoRows.
+func (mc *MomentClient) selectKey(ctx context.Context, db DbRunner, k *IdempotencyKeysRow) (*IdempotencyKeysRow, error) {
+	stored := new(IdempotencyKeysRow)
+	var e sql.NullTime
+	var st sql.NullInt64
+	var ct, h sql.NullString
+	var b []byte
+	err := sq.
+		Select(requestHash, createDate, expiresAt, status, contentType, headers, body).
+		From(schIdempotencyKeys).
+		Where(sq.Eq{userID: k.userID, idempotencyKey: k.key}).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		QueryRowContext(ctx).
+		Scan(&stored.hash, &stored.createDate, &e, &st, &ct, &h, &b)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+	expires := stored.createDate.Add(KeyTTL)
+	if e.Valid {
+		expires = e.Time
+	}
+	stored.expiresAt = &expires
+	if !st.Valid {
+		return stored, nil
+	}
+
+	// A response stored before [Headers] was added only kept its [ContentType].
+	stored.response = &Response{Status: int(st.Int64), Body: b}
+	switch {
+	case h.Valid:
+		if err = json.Unmarshal([]byte(h.String), &stored.response.Header); err != nil {
+			Error.Println(err)
+			return nil, err
+		}
+	case ct.String != "":
+		stored.response.Header = map[string][]string{"Content-Type": {ct.String}}
+	}
+	return stored, nil
+}
+
+// CompleteKey stores res as the response of k in [Moment-Db].[moment].[IdempotencyKeys].
+func (mc *MomentClient) CompleteKey(ctx context.Context, db DbRunner, k *IdempotencyKeysRow, res *Response) error {
+	if k == nil || res == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+
+	h, _ := json.Marshal(res.Header)
+	r, err := sq.
+		Update(schIdempotencyKeys).
+		Set(status, res.Status).
+		Set(headers, string(h)).
+		Set(body, res.Body).
+		Where(sq.Eq{userID: k.userID, idempotencyKey: k.key, status: nil}).
+		Where(requestHash+" = ?", k.hash).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	cnt, err := r.RowsAffected()
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	if cnt == 0 {
+		return ErrorKeyDNE
+	}
+	return nil
+}
+
+// ReleaseKey deletes k from [Moment-Db].[moment].[IdempotencyKeys] if it has no response.
+func (mc *MomentClient) ReleaseKey(ctx context.Context, db DbRunner, k *IdempotencyKeysRow) error {
+	if k == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+
+	_, err := sq.
+		Delete(schIdempotencyKeys).
+		Where(sq.Eq{userID: k.userID, idempotencyKey: k.key, status: nil}).
+		Where(requestHash+" = ?", k.hash).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	return nil
+}
+
+// SweepKeys deletes every key of [Moment-Db].[moment].[IdempotencyKeys] that expired by at and
+// returns how many it deleted.
+func (mc *MomentClient) SweepKeys(ctx context.Context, db DbRunner, at time.Time) (int64, error) {
+	res, err := sq.
+		Delete(schIdempotencyKeys).
+		Where(keyExpired(at)).
+		PlaceholderFormat(format{mc.dialect()}).
+		RunWith(db).
+		ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		Error.Println(err)
+		return 0, dbError(ctxError(ctx, err))
+	}
+	return n, nil
+}
@@ -0,0 +1,37 @@
+package moment
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIdempotencyKeysRow(t *testing.T) {
+	type test struct {
+		user     string
+		key      string
+		hash     []byte
+		date     *time.Time
+		expected error
+	}
+	hash := []byte{1, 2, 3}
+	tests := []test{
+		test{tUser, "key", hash, &tDate, nil},
+		test{tUser, strings.Repeat("k", maxKeyChars), hash, &tDate, nil},
+		test{tUser, "", hash, &tDate, ErrorKeyInvalid},
+		test{tUser, strings.Repeat("k", maxKeyChars+1), hash, &tDate, ErrorKeyInvalid},
+		test{tUser, "key", nil, &tDate, ErrorParameterEmpty},
+		test{tUser, "key", hash, nil, ErrorParameterEmpty},
+		test{tEmptyUser, "key", hash, &tDate, ErrorUserIDShort},
+	}
+
+	for _, v := range tests {
+		mc := new(MomentClient)
+		k := mc.NewIdempotencyKeysRow(v.user, v.key, v.hash, v.date)
+		assert.Exactly(t, v.expected, mc.Err(), v.key)
+		if v.expected == nil {
+			assert.Equal(t, time.UTC, k.createDate.Location(), v.key)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package moment
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type locationJSON struct {
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+}
+
+type mediaJSON struct {
+	MomentID int64  `json:"momentID"`
+	Message  string `json:"message"`
+	Type     uint8  `json:"type"`
+	Dir      string `json:"dir"`
+}
+
+// MarshalJSON encodes md using the wire format of the Moment "media" array.
+func (md MediaRow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mediaJSON{md.momentID, md.message, md.mType, md.dir})
+}
+
+// UnmarshalJSON decodes md from the wire format of the Moment "media" array.
+func (md *MediaRow) UnmarshalJSON(b []byte) error {
+	j := new(mediaJSON)
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	md.momentID = j.MomentID
+	md.message = j.Message
+	md.mType = j.Type
+	md.dir = j.Dir
+	return nil
+}
+
+type findsJSON struct {
+	MomentID int64      `json:"momentID"`
+	UserID   string     `json:"userID"`
+	Found    bool       `json:"found"`
+	FindDate *time.Time `json:"findDate,omitempty"`
+}
+
+// MarshalJSON encodes f using the wire format of the Moment "finds" array.
+func (f FindsRow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(findsJSON{f.momentID, f.userID, f.found, f.findDate})
+}
+
+// UnmarshalJSON decodes f from the wire format of the Moment "finds" array.
+func (f *FindsRow) UnmarshalJSON(b []byte) error {
+	j := new(findsJSON)
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	f.momentID = j.MomentID
+	f.userID = j.UserID
+	f.found = j.Found
+	f.findDate = j.FindDate
+	return nil
+}
+
+type sharesJSON struct {
+	ID       int64  `json:"id"`
+	MomentID int64  `json:"momentID"`
+	UserID   string `json:"userID"`
+}
+
+// MarshalJSON encodes s using the wire format of the Moment "shares" array.
+func (s SharesRow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sharesJSON{s.sharesID, s.momentID, s.userID})
+}
+
+// UnmarshalJSON decodes s from the wire format of the Moment "shares" array.
+func (s *SharesRow) UnmarshalJSON(b []byte) error {
+	j := new(sharesJSON)
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	s.sharesID = j.ID
+	s.momentID = j.MomentID
+	s.userID = j.UserID
+	return nil
+}
+
+type momentJSON struct {
+	ID         int64        `json:"id"`
+	UserID     string       `json:"userID"`
+	Location   locationJSON `json:"location"`
+	Public     bool         `json:"public"`
+	Hidden     bool         `json:"hidden"`
+	CreateDate *time.Time   `json:"createDate,omitempty"`
+	Distance   *float64     `json:"distance,omitempty"`
+	Bearing    *float64     `json:"bearing,omitempty"`
+	// ReleaseDate and ReleasesIn are only present on time capsules.
+	ReleaseDate *time.Time   `json:"releaseDate,omitempty"`
+	ReleasesIn  *int64       `json:"releasesIn,omitempty"`
+	Media       []*MediaRow  `json:"media"`
+	Finds       []*FindsRow  `json:"finds"`
+	Shares      []*SharesRow `json:"shares"`
+
+	// Capacity and Remaining are only present on moments with a capacity.
+	Capacity  *int `json:"capacity,omitempty"`
+	Remaining *int `json:"remaining,omitempty"`
+}
+
+// MarshalJSON encodes m using the Moment wire format:
+//
+//	{
+//		"id":         1,
+//		"userID":     "user00",
+//		"location":   {"latitude": 1.5, "longitude": -2.5},
+//		"public":     true,
+//		"hidden":     false,
+//		"createDate": "2017-06-01T12:00:00Z",
+//		"distance":   412.7,
+//		"bearing":    87.3,
+//		"releaseDate": "2018-06-01T12:00:00Z",
+//		"releasesIn": 31536000,
+//		"media":      [{"momentID": 1, "message": "Hello.", "type": 0, "dir": ""}],
+//		"finds":      [{"momentID": 1, "userID": "user01", "found": true, "findDate": "2017-06-02T12:00:00Z"}],
+//		"shares":     [{"id": 1, "momentID": 1, "userID": "user00"}],
+//		"capacity":   10,
+//		"remaining":  3
+//	}
+//
+// Dates are RFC 3339 strings and are omitted when unknown. distance, in meters, and bearing, in
+// degrees clockwise from north, are only present on the moments of location selectors.
+// releaseDate is only present on the pending time capsules of UserLeft and UserPending, and
+// releasesIn is the whole number of seconds left until it when it is still ahead. capacity and
+// remaining, how many more can find the moment, are only present on the moments of location
+// selectors that only their first capacity finders can find. media,
+// finds and shares are always present and are empty arrays when the selector that produced the
+// Moment did not load them.
+func (m Moment) MarshalJSON() ([]byte, error) {
+	j := momentJSON{
+		ID:         m.momentID,
+		UserID:     m.userID,
+		Location:   locationJSON{m.latitude, m.longitude},
+		Public:     m.public,
+		Hidden:     m.hidden,
+		CreateDate: m.createDate,
+		Media:      m.media,
+		Finds:      m.finds,
+		Shares:     m.shares,
+	}
+	if m.heading != nil {
+		j.Distance, j.Bearing = &m.heading.distance, &m.heading.bearing
+	}
+	if m.releaseDate != nil {
+		j.ReleaseDate = m.releaseDate
+		if d := time.Until(*m.releaseDate); d > 0 {
+			s := int64(d / time.Second)
+			j.ReleasesIn = &s
+		}
+	}
+	if m.capacity != nil {
+		j.Capacity, j.Remaining = m.capacity, m.remaining()
+	}
+	if j.Media == nil {
+		j.Media = []*MediaRow{}
+	}
+	if j.Finds == nil {
+		j.Finds = []*FindsRow{}
+	}
+	if j.Shares == nil {
+		j.Shares = []*SharesRow{}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes m from the Moment wire format.
+func (m *Moment) UnmarshalJSON(b []byte) error {
+	j := new(momentJSON)
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+
+	*m = Moment{
+		momentID:    j.ID,
+		userID:      j.UserID,
+		public:      j.Public,
+		hidden:      j.Hidden,
+		Location:    Location{latitude: j.Location.Latitude, longitude: j.Location.Longitude},
+		createDate:  j.CreateDate,
+		releaseDate: j.ReleaseDate,
+	}
+	if j.Distance != nil && j.Bearing != nil {
+		m.heading = &heading{*j.Distance, *j.Bearing}
+	}
+	if j.Capacity != nil {
+		m.capacity = j.Capacity
+		if j.Remaining != nil {
+			m.claimed = *j.Capacity - *j.Remaining
+		}
+	}
+	if len(j.Media) > 0 {
+		m.media = j.Media
+	}
+	if len(j.Finds) > 0 {
+		m.finds = j.Finds
+	}
+	if len(j.Shares) > 0 {
+		m.shares = j.Shares
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package moment
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"sort"
+	"testing"
+	"time"
+)
+
+var tDate = time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// roundTrip encodes rs to JSON, decodes the result and asserts that nothing was lost on the way.
+func roundTrip(t *testing.T, rs []*Moment) {
+	sort.Slice(rs, func(i, j int) bool { return rs[i].momentID < rs[j].momentID })
+
+	b, err := json.Marshal(rs)
+	assert.Nil(t, err)
+
+	var actual []*Moment
+	assert.Nil(t, json.Unmarshal(b, &actual))
+	assert.Equal(t, rs, actual)
+}
+
+func TestMomentMarshalJSON(t *testing.T) {
+	m := Moment{
+		momentID:   1,
+		userID:     tUser,
+		public:     true,
+		Location:   Location{latitude: 1.5, longitude: -2.5},
+		createDate: &tDate,
+		media:      []*MediaRow{&MediaRow{mID: mID{1}, message: "Hello.", mType: DNE}},
+		finds:      []*FindsRow{&FindsRow{mID: mID{1}, uID: uID{tUser2}, found: true, findDate: &tDate}},
+		shares:     []*SharesRow{&SharesRow{sID: sID{1}, mID: mID{1}, uID: uID{tUser}}},
+	}
+
+	expected := `{"id":1,"userID":"user00","location":{"latitude":1.5,"longitude":-2.5},"public":true,"hidden":false,` +
+		`"createDate":"2017-06-01T12:00:00Z",` +
+		`"media":[{"momentID":1,"message":"Hello.","type":0,"dir":""}],` +
+		`"finds":[{"momentID":1,"userID":"user02","found":true,"findDate":"2017-06-01T12:00:00Z"}],` +
+		`"shares":[{"id":1,"momentID":1,"userID":"user00"}]}`
+
+	actual, err := json.Marshal(m)
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, string(actual))
+
+	roundTrip(t, []*Moment{&m})
+}
+
+func TestMomentMarshalJSONHeading(t *testing.T) {
+	m := Moment{momentID: 1, heading: &heading{distance: 412.5, bearing: 0}}
+
+	actual, err := json.Marshal(m)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":1,"userID":"","location":{"latitude":0,"longitude":0},"public":false,"hidden":false,`+
+		`"distance":412.5,"bearing":0,"media":[],"finds":[],"shares":[]}`, string(actual))
+
+	roundTrip(t, []*Moment{&m})
+}
+
+func TestMomentMarshalJSONRelease(t *testing.T) {
+	release := time.Now().UTC().Add(time.Hour)
+	m := Moment{momentID: 1, releaseDate: &release}
+
+	actual, err := json.Marshal(m)
+	assert.Nil(t, err)
+
+	var j momentJSON
+	assert.Nil(t, json.Unmarshal(actual, &j))
+	assert.True(t, release.Equal(*j.ReleaseDate))
+	assert.InDelta(t, 3600, *j.ReleasesIn, 1)
+
+	past := tDate
+	m.releaseDate = &past
+	actual, err = json.Marshal(m)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":1,"userID":"","location":{"latitude":0,"longitude":0},"public":false,"hidden":false,`+
+		`"releaseDate":"2017-06-01T12:00:00Z","media":[],"finds":[],"shares":[]}`, string(actual))
+}
+
+func TestMomentMarshalJSONEmpty(t *testing.T) {
+	actual, err := json.Marshal(Moment{})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":0,"userID":"","location":{"latitude":0,"longitude":0},"public":false,"hidden":false,"media":[],"finds":[],"shares":[]}`, string(actual))
+}
+
+func TestRoundTrip_selectMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden, sortKey}).
+		AddRow(1, lat, long, "Hello there.", DNE, "", tDate, tUser, false, false, tDate).
+		AddRow(1, lat, long, "Enjoy this photo.", Image, "D:/ImageDir/image.png", tDate, tUser, false, false, tDate).
+		AddRow(2, lat, long, "Where am I? :p", DNE, "", tDate, tUser, true, true, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	roundTrip(t, rs)
+}
+
+func TestRoundTrip_selectPublicMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, capacity, claimed, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", tDate, tUser, nil, 0, tDate).
+		AddRow(2, lat, long, "message 2", DNE, "", tDate, tUser, 5, 2, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectPublicMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	roundTrip(t, rs)
+}
+
+func TestRoundTrip_selectLostMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, capacity, claimed, sortKey}).
+		AddRow(1, lat, long, nil, 0, tDate).
+		AddRow(2, 10.5, -20.25, 1, 0, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectLostMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	roundTrip(t, rs)
+}
+
+func TestRoundTrip_selectLeftMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	release := time.Now().UTC().Truncate(time.Second).Add(time.Hour)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, public, hidden, userID, findDate, releaseDate, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", tDate, false, false, tUser2, tDate, nil, tDate).
+		AddRow(2, lat, long, "message 2", DNE, "", tDate, false, false, tUser2, tDate, nil, tDate).
+		AddRow(2, lat, long, "message 3", Image, "D:/Image/image.png", tDate, false, false, tUser3, tDate, nil, tDate).
+		AddRow(3, lat, long, "message 4", DNE, "", tDate, true, false, nil, nil, release, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectLeftMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	roundTrip(t, rs)
+}
+
+func TestRoundTrip_selectFoundMoments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden, findDate, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", tDate, tUser, false, false, tDate, tDate).
+		AddRow(2, lat, long, "message 2", DNE, "", tDate, tUser, true, true, tDate, tDate)
+	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	rs, err := mc.selectFoundMoments(context.Background(), db, fakeSelect, tPager(t))
+	assert.Nil(t, err)
+	roundTrip(t, rs)
+}
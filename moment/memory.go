@@ -0,0 +1,779 @@
+package moment
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrorMomentDNE           = notFound("momentID", "No moment exists for this momentID.")
+	ErrorFindsRowExists      = conflict("momentID", "A Finds row already exists for this momentID and userID.")
+	ErrorRecipientsRowExists = conflict("recipientID", "A Recipients row already exists for this sharesID and recipientID.")
+)
+
+// MemoryStore is the Store that keeps moments in process, for local development and tests.
+// It enforces the constraints of Moment-Db, and each of its selectors matches, orders and
+// loads moments as the SQL selector of the same name does.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	lastMomentID int64
+	lastSharesID int64
+
+	moments map[int64]*memMoment
+	finds   map[findKey]*FindsRow
+	keys    map[keyKey]*IdempotencyKeysRow
+
+	// radius is how far in meters from a moment a find is accepted, or 0 for DefaultFindRadius.
+	radius float64
+}
+
+// memMoment is a row of [Moments] together with the rows that reference it.
+type memMoment struct {
+	MomentsRow
+	// deletedAt is the [DeletedAt] of the moment.
+	deletedAt *time.Time
+	media     []*MediaRow
+	finds     []*FindsRow
+	shares    []*memShare
+	// claimed is the [Claimed] of the moment.
+	claimed int
+}
+
+// memShare is a row of [Shares] together with its [Recipients].
+type memShare struct {
+	SharesRow
+	recipients []*RecipientsRow
+}
+
+// findKey is the primary key of [Finds].
+type findKey struct {
+	momentID int64
+	userID   string
+}
+
+// keyKey is the primary key of [IdempotencyKeys].
+type keyKey struct {
+	userID string
+	key    string
+}
+
+// NewMemoryStore is a constructor for the MemoryStore struct.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		moments: make(map[int64]*memMoment),
+		finds:   make(map[findKey]*FindsRow),
+		keys:    make(map[keyKey]*IdempotencyKeysRow),
+	}
+}
+
+// ctxDone returns the error a statement run under ctx fails with once ctx is done.
+func ctxDone(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		Error.Println(err)
+		return dbError(err)
+	}
+	return nil
+}
+
+// FindPublic adds f to the finds of its moment with Found=true, or returns ErrorMomentFull if
+// the moment has been found by as many as it can be. A private moment is reported as ErrorMomentDNE.
+func (s *MemoryStore) FindPublic(ctx context.Context, f *FindsRow) (int64, error) {
+	if err := f.isFound(); err != nil {
+		Error.Println(err)
+		return 0, err
+	}
+	if err := ctxDone(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.live(f.momentID, true)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.near(r.Location, s.findRadius()); err != nil {
+		return 0, err
+	}
+	if r.full() {
+		Error.Println(ErrorMomentFull)
+		return 0, ErrorMomentFull
+	}
+	if err := s.addFinds(r, []*FindsRow{f}); err != nil {
+		return 0, err
+	}
+	r.claimed++
+	return 1, nil
+}
+
+// FindPrivate sets Found=true on the find of f's user of f's moment.
+func (s *MemoryStore) FindPrivate(ctx context.Context, f *FindsRow) error {
+	if err := f.isFound(); err != nil {
+		Error.Println(err)
+		return err
+	}
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.live(f.momentID, false)
+	if err != nil {
+		return err
+	}
+	if err := f.near(r.Location, s.findRadius()); err != nil {
+		return err
+	}
+	fr, ok := s.finds[findKey{f.momentID, f.userID}]
+	if !ok {
+		return ErrorFindsRowDNE
+	}
+	fr.found = f.found
+	fr.findDate = f.findDate
+	fr.attempt = f.attempt
+	return nil
+}
+
+// SetFindRadius sets how far in meters from a moment FindPublic and FindPrivate accept a find.
+func (s *MemoryStore) SetFindRadius(meters float64) error {
+	if err := checkFindRadius(meters); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.radius = meters
+	return nil
+}
+
+// findRadius must be called with s.mu held.
+func (s *MemoryStore) findRadius() float64 {
+	if s.radius == 0 {
+		return DefaultFindRadius
+	}
+	return s.radius
+}
+
+// Share adds sr and its recipients rs to the shares of sr's moment.
+func (s *MemoryStore) Share(ctx context.Context, sr *SharesRow, rs []*RecipientsRow) error {
+	if len(rs) == 0 || sr == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.live(sr.momentID, false)
+	if err != nil {
+		return err
+	}
+
+	id := s.lastSharesID + 1
+	sh := &memShare{SharesRow: *sr}
+	sh.sharesID = id
+	seen := make(map[string]bool)
+	for _, rr := range rs {
+		rr.setSharesID(id)
+		if rr.err != nil {
+			Error.Println(rr.err)
+			return rr.err
+		}
+		if seen[rr.recipientID] {
+			Error.Println(ErrorRecipientsRowExists)
+			return ErrorRecipientsRowExists
+		}
+		seen[rr.recipientID] = true
+		c := *rr
+		sh.recipients = append(sh.recipients, &c)
+	}
+
+	r.shares = append(r.shares, sh)
+	s.lastSharesID = id
+	sr.sharesID = id
+	return nil
+}
+
+// live returns moment id unless it does not exist, is deleted, has expired or has not been
+// released. If public is set, a private moment is reported as ErrorMomentDNE before it is
+// checked for expiry or release, as SQLStore does.
+func (s *MemoryStore) live(id int64, public bool) (*memMoment, error) {
+	r, err := s.undeleted(id)
+	if err != nil {
+		return nil, err
+	}
+	if public && !r.public {
+		Error.Println(ErrorMomentDNE)
+		return nil, ErrorMomentDNE
+	}
+	now := time.Now()
+	if r.expired(now) {
+		Error.Println(ErrorMomentExpired)
+		return nil, ErrorMomentExpired
+	}
+	if r.pending(now) {
+		Error.Println(ErrorMomentUnreleased)
+		return nil, ErrorMomentUnreleased
+	}
+	return r, nil
+}
+
+// undeleted returns moment id unless it does not exist or is deleted.
+func (s *MemoryStore) undeleted(id int64) (*memMoment, error) {
+	r, ok := s.moments[id]
+	if !ok || r.deletedAt != nil {
+		Error.Println(ErrorMomentDNE)
+		return nil, ErrorMomentDNE
+	}
+	return r, nil
+}
+
+// CreatePublic adds m and its media ms.
+func (s *MemoryStore) CreatePublic(ctx context.Context, m *MomentsRow, ms []*MediaRow) error {
+	if len(ms) == 0 || m == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	return s.create(ctx, m, ms, nil)
+}
+
+// CreatePrivate adds m, its media ms and the finds fs of its recipients.
+func (s *MemoryStore) CreatePrivate(ctx context.Context, m *MomentsRow, ms []*MediaRow, fs []*FindsRow) error {
+	if m == nil || len(ms) == 0 || len(fs) == 0 {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	return s.create(ctx, m, ms, fs)
+}
+
+// create adds m with ms and fs, or nothing at all, and sets the ID of m on success.
+func (s *MemoryStore) create(ctx context.Context, m *MomentsRow, ms []*MediaRow, fs []*FindsRow) error {
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.lastMomentID + 1
+	r := &memMoment{MomentsRow: *m}
+	r.momentID = id
+	for _, md := range ms {
+		md.setMomentID(id)
+		if md.err != nil {
+			Error.Println(md.err)
+			return md.err
+		}
+		c := *md
+		r.media = append(r.media, &c)
+	}
+	for _, f := range fs {
+		f.setMomentID(id)
+		if f.err != nil {
+			Error.Println(f.err)
+			return f.err
+		}
+	}
+
+	s.moments[id] = r
+	if err := s.addFinds(r, fs); err != nil {
+		delete(s.moments, id)
+		return err
+	}
+	s.lastMomentID = id
+	m.momentID = id
+	return nil
+}
+
+// addFinds adds fs to the finds of r unless one of them repeats the key of an existing find.
+func (s *MemoryStore) addFinds(r *memMoment, fs []*FindsRow) error {
+	keys := make(map[findKey]bool)
+	for _, f := range fs {
+		k := findKey{r.momentID, f.userID}
+		if _, ok := s.finds[k]; ok || keys[k] {
+			Error.Println(ErrorFindsRowExists)
+			return ErrorFindsRowExists
+		}
+		keys[k] = true
+	}
+	for _, f := range fs {
+		c := *f
+		c.momentID = r.momentID
+		r.finds = append(r.finds, &c)
+		s.finds[findKey{r.momentID, f.userID}] = &c
+	}
+	return nil
+}
+
+// Edit sets the hidden flag of the moment of e and the messages of its media.
+func (s *MemoryStore) Edit(ctx context.Context, e *MomentEdit) error {
+	if e == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.undeleted(e.momentID)
+	if err != nil {
+		return err
+	}
+	if e.hidden && !r.public {
+		Error.Println(ErrorPrivateHiddenMoment)
+		return ErrorPrivateHiddenMoment
+	}
+	if len(r.media) != len(e.messages) {
+		Error.Println(ErrorMediaCount)
+		return ErrorMediaCount
+	}
+	r.hidden = e.hidden
+	for i, md := range r.media {
+		md.message = e.messages[i]
+	}
+	return nil
+}
+
+// Delete marks moment id deleted at at.
+func (s *MemoryStore) Delete(ctx context.Context, id int64, at time.Time) error {
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.undeleted(id)
+	if err != nil {
+		return err
+	}
+	r.deletedAt = &at
+	return nil
+}
+
+// Restore clears the deletion of moment id if it was deleted within RestoreWindow before at
+// and has not expired by at.
+func (s *MemoryStore) Restore(ctx context.Context, id int64, at time.Time) error {
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.moments[id]
+	if !ok || r.deletedAt == nil || r.deletedAt.Before(at.Add(-RestoreWindow)) || r.expired(at) {
+		Error.Println(ErrorMomentNotDeleted)
+		return ErrorMomentNotDeleted
+	}
+	r.deletedAt = nil
+	return nil
+}
+
+// Reap deletes the moments that expired by at, as of their expiry, and returns how many it
+// deleted.
+func (s *MemoryStore) Reap(ctx context.Context, at time.Time) (int64, error) {
+	if err := ctxDone(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, r := range s.moments {
+		if r.deletedAt == nil && r.expired(at) {
+			e := *r.expiresAt
+			r.deletedAt = &e
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Purge removes the moments deleted more than RestoreWindow before at, together with their
+// finds, and returns how many it removed.
+func (s *MemoryStore) Purge(ctx context.Context, at time.Time) (int64, error) {
+	if err := ctxDone(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, r := range s.moments {
+		if r.deletedAt == nil || !r.deletedAt.Before(at.Add(-RestoreWindow)) {
+			continue
+		}
+		for _, f := range r.finds {
+			delete(s.finds, findKey{id, f.userID})
+		}
+		delete(s.moments, id)
+		n++
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) LocationShared(ctx context.Context, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
+	if l == nil || me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, l, radius, pr, nil,
+		func(r *memMoment) bool { return !r.pending(now) && r.sharedWith(me, "") },
+		(*memMoment).loadShared)
+}
+
+func (s *MemoryStore) LocationPublic(ctx context.Context, l *Location, radius float64, pr *PageRequest) (*Page, error) {
+	if l == nil {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, l, radius, pr, nil,
+		func(r *memMoment) bool { return !r.pending(now) && !r.full() && r.public && !r.hidden },
+		(*memMoment).loadPublic)
+}
+
+func (s *MemoryStore) LocationHidden(ctx context.Context, l *Location, radius float64, pr *PageRequest) (*Page, error) {
+	if l == nil {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, l, radius, pr, make([]*Moment, 0),
+		func(r *memMoment) bool { return !r.pending(now) && !r.full() && r.public && r.hidden },
+		(*memMoment).loadLost)
+}
+
+func (s *MemoryStore) LocationLost(ctx context.Context, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
+	if l == nil || me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, l, radius, pr, make([]*Moment, 0),
+		func(r *memMoment) bool {
+			return !r.pending(now) && !r.public && !r.hidden && s.finds[findKey{r.momentID, me}] != nil
+		},
+		(*memMoment).loadLost)
+}
+
+func (s *MemoryStore) UserShared(ctx context.Context, you string, me string, pr *PageRequest) (*Page, error) {
+	if me == "" || you == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	return s.selectPage(ctx, nil, 0, pr, nil,
+		func(r *memMoment) bool { return r.sharedWith(me, you) },
+		(*memMoment).loadShared)
+}
+
+func (s *MemoryStore) UserLeft(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	if me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, nil, 0, pr, nil,
+		func(r *memMoment) bool { return r.userID == me && (len(r.finds) > 0 || r.pending(now)) },
+		func(r *memMoment) *Moment { return r.loadLeft(now) })
+}
+
+func (s *MemoryStore) UserFound(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	if me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	return s.selectPage(ctx, nil, 0, pr, nil,
+		func(r *memMoment) bool {
+			f := s.finds[findKey{r.momentID, me}]
+			return f != nil && f.found
+		},
+		func(r *memMoment) *Moment {
+			m := r.loadShared()
+			m.finds = []*FindsRow{&FindsRow{findDate: s.finds[findKey{r.momentID, me}].findDate}}
+			return m
+		})
+}
+
+func (s *MemoryStore) UserPending(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	if me == "" {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	now := time.Now()
+	return s.selectPage(ctx, nil, 0, pr, make([]*Moment, 0),
+		func(r *memMoment) bool {
+			return !r.public && r.pending(now) && s.finds[findKey{r.momentID, me}] != nil
+		},
+		(*memMoment).loadPending)
+}
+
+// selectPage appends to rs the moments that match, loaded by load, and cuts them into the
+// Page pr of a selector within radius meters of origin.
+func (s *MemoryStore) selectPage(ctx context.Context, origin *Location, radius float64, pr *PageRequest, rs []*Moment,
+	match func(*memMoment) bool, load func(*memMoment) *Moment) (*Page, error) {
+	p, err := pr.pager(origin, radius)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctxDone(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type keyed struct {
+		r *memMoment
+		k *cursor
+	}
+	var ks []keyed
+	now := time.Now()
+	for _, r := range s.moments {
+		if r.deletedAt != nil || r.expired(now) || !p.within(r.Location) || !match(r) {
+			continue
+		}
+		k := p.key(r.momentID, r.createDate, r.Location)
+		if p.after != nil && !p.after.before(k) {
+			continue
+		}
+		ks = append(ks, keyed{r, k})
+	}
+	sort.Slice(ks, func(i, j int) bool { return ks[i].k.before(ks[j].k) })
+
+	for _, v := range ks {
+		p.keyDate, p.keyHav = v.k.CreateDate, v.k.Hav
+		if !p.admit(v.r.momentID) {
+			break
+		}
+		rs = append(rs, load(v.r))
+	}
+	return p.page(rs), nil
+}
+
+// sharedWith reports whether r was shared with me, or with all, by sharer or, if sharer is "", by anyone.
+func (r *memMoment) sharedWith(me string, sharer string) bool {
+	for _, sh := range r.shares {
+		if sharer != "" && sh.userID != sharer {
+			continue
+		}
+		for _, rr := range sh.recipients {
+			if rr.all || rr.recipientID == me {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *memMoment) mediaRows() (ms []*MediaRow) {
+	for _, md := range r.media {
+		ms = append(ms, &MediaRow{message: md.message, mType: md.mType, dir: md.dir})
+	}
+	return
+}
+
+// loadShared, loadPublic, loadLost, loadLeft and loadPending load the fields of r that the SQL
+// selectors scanning into selectMoments, selectPublicMoments, selectLostMoments,
+// selectLeftMoments and selectPendingMoments load.
+func (r *memMoment) loadShared() *Moment {
+	return &Moment{
+		momentID:   r.momentID,
+		userID:     r.userID,
+		public:     r.public,
+		hidden:     r.hidden,
+		Location:   Location{latitude: r.latitude, longitude: r.longitude},
+		createDate: r.createDate,
+		media:      r.mediaRows(),
+	}
+}
+
+func (r *memMoment) loadPublic() *Moment {
+	return &Moment{
+		momentID: r.momentID,
+		userID:   r.userID,
+		Location: Location{latitude: r.latitude, longitude: r.longitude},
+		media:    r.mediaRows(),
+		capacity: r.capacity,
+		claimed:  r.claimed,
+	}
+}
+
+func (r *memMoment) loadLost() *Moment {
+	return &Moment{
+		momentID: r.momentID,
+		Location: Location{latitude: r.latitude, longitude: r.longitude},
+		capacity: r.capacity,
+		claimed:  r.claimed,
+	}
+}
+
+// full reports whether r has been found by as many as its capacity.
+func (r *memMoment) full() bool {
+	return r.capacity != nil && r.claimed >= *r.capacity
+}
+
+func (r *memMoment) loadLeft(now time.Time) *Moment {
+	m := r.loadShared()
+	// selectLeftMoments does not load the author, who is the caller.
+	m.userID = ""
+	if r.pending(now) {
+		m.releaseDate = r.releaseDate
+	}
+	for _, f := range r.finds {
+		m.finds = append(m.finds, &FindsRow{uID: uID{userID: f.userID}, findDate: f.findDate})
+	}
+	return m
+}
+
+func (r *memMoment) loadPending() *Moment {
+	return &Moment{
+		momentID:    r.momentID,
+		userID:      r.userID,
+		Location:    Location{latitude: r.latitude, longitude: r.longitude},
+		releaseDate: r.releaseDate,
+	}
+}
+
+func (s *MemoryStore) IsAuthor(ctx context.Context, user string, id int64) (bool, error) {
+	if err := ctxDone(ctx); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.moments[id]
+	return ok && r.userID == user, nil
+}
+
+func (s *MemoryStore) HasFound(ctx context.Context, user string, id int64) (bool, error) {
+	if err := ctxDone(ctx); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.finds[findKey{id, user}]
+	return ok && f.found, nil
+}
+
+func (s *MemoryStore) IsRecipient(ctx context.Context, user string, id int64) (bool, error) {
+	if err := ctxDone(ctx); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.finds[findKey{id, user}]
+	return ok, nil
+}
+
+func (s *MemoryStore) IsPublic(ctx context.Context, id int64) (bool, error) {
+	if err := ctxDone(ctx); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.moments[id]
+	return ok && r.public, nil
+}
+
+// ReserveKey adds k to the keys of s without a response, unless a request reserved it before.
+func (s *MemoryStore) ReserveKey(ctx context.Context, k *IdempotencyKeysRow) (*Response, error) {
+	if k == nil {
+		Error.Println(ErrorParameterEmpty)
+		return nil, ErrorParameterEmpty
+	}
+	if err := ctxDone(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kk := keyKey{k.userID, k.key}
+	if stored, ok := s.keys[kk]; ok && !k.stale(stored) {
+		return k.replay(stored)
+	}
+	c := *k
+	c.response = nil
+	s.keys[kk] = &c
+	return nil, nil
+}
+
+// CompleteKey sets the response of k.
+func (s *MemoryStore) CompleteKey(ctx context.Context, k *IdempotencyKeysRow, res *Response) error {
+	if k == nil || res == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.keys[keyKey{k.userID, k.key}]
+	if !ok || stored.response != nil || !bytes.Equal(stored.hash, k.hash) {
+		return ErrorKeyDNE
+	}
+	stored.response = res
+	return nil
+}
+
+// SweepKeys removes the keys of s that expired by at and returns how many it removed.
+func (s *MemoryStore) SweepKeys(ctx context.Context, at time.Time) (int64, error) {
+	if err := ctxDone(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for kk, k := range s.keys {
+		if !k.expiresAt.After(at) {
+			delete(s.keys, kk)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ReleaseKey removes k from the keys of s if it has no response.
+func (s *MemoryStore) ReleaseKey(ctx context.Context, k *IdempotencyKeysRow) error {
+	if k == nil {
+		Error.Println(ErrorParameterEmpty)
+		return ErrorParameterEmpty
+	}
+	if err := ctxDone(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kk := keyKey{k.userID, k.key}
+	if stored, ok := s.keys[kk]; ok && stored.response == nil && bytes.Equal(stored.hash, k.hash) {
+		delete(s.keys, kk)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package moment
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFS holds, for each Dialect, the migrations of Moment-Db in files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, and history.sql, which creates the
+// history table if it does not exist.
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+// migrationDirs names the directory of migrationFS that holds the migrations of each Dialect.
+var migrationDirs = map[Dialect]string{
+	MSSQL:      "migrations/mssql",
+	PostgreSQL: "migrations/postgres",
+	SQLite:     "migrations/sqlite",
+}
+
+const (
+	schemaMigrations = "[SchemaMigrations]"
+
+	schSchemaMigrations = momentSchema + "." + schemaMigrations
+
+	version       = "[Version]"
+	migrationName = "[Name]"
+	appliedDate   = "[AppliedDate]"
+)
+
+var (
+	ErrorDialectUnknown   = errors.New("Dialect has no migrations.")
+	ErrorMigrationInvalid = errors.New("Migration file name must be <version>_<name>.up.sql or <version>_<name>.down.sql.")
+	ErrorSchemaUnknown    = errors.New("Moment-Db has a schema version this service does not know. Upgrade the service.")
+	ErrorSchemaPending    = errors.New("Moment-Db has migrations pending. Run migrate up.")
+)
+
+// Migration is one versioned change to the schema of Moment-Db.
+type Migration struct {
+	Version int
+	Name    string
+	// Applied is when the migration was applied, or nil while it is pending.
+	Applied *time.Time
+
+	up   string
+	down string
+}
+
+// Migrator applies and reverts the migrations of a Dialect and records them in the
+// [SchemaMigrations] table. Every method creates that table if it does not exist.
+type Migrator struct {
+	db         DbRunnerTrans
+	d          Dialect
+	history    string
+	migrations []*Migration
+}
+
+// NewMigrator is a constructor for the Migrator struct. It reads the migrations of d.
+func NewMigrator(db DbRunnerTrans, d Dialect) (*Migrator, error) {
+	dir, ok := migrationDirs[d]
+	if !ok {
+		Error.Println(ErrorDialectUnknown)
+		return nil, ErrorDialectUnknown
+	}
+
+	history, err := migrationFS.ReadFile(path.Join(dir, "history.sql"))
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+	m := &Migrator{db: db, d: d, history: string(history)}
+
+	files, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, f := range files {
+		if f.Name() == "history.sql" {
+			continue
+		}
+		v, name, up, err := parseMigration(f.Name())
+		if err != nil {
+			Error.Println(err, f.Name())
+			return nil, err
+		}
+		b, err := migrationFS.ReadFile(path.Join(dir, f.Name()))
+		if err != nil {
+			Error.Println(err)
+			return nil, err
+		}
+
+		mg, ok := byVersion[v]
+		if !ok {
+			mg = &Migration{Version: v, Name: name}
+			byVersion[v] = mg
+			m.migrations = append(m.migrations, mg)
+		}
+		if up {
+			mg.up = string(b)
+		} else {
+			mg.down = string(b)
+		}
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	for i, mg := range m.migrations {
+		if mg.Version != i+1 || mg.up == "" || mg.down == "" {
+			Error.Println(ErrorMigrationInvalid, mg.Version)
+			return nil, ErrorMigrationInvalid
+		}
+	}
+	return m, nil
+}
+
+// parseMigration splits the file name of a migration into its version, its name and whether
+// it is the up migration.
+func parseMigration(file string) (v int, name string, up bool, err error) {
+	switch {
+	case strings.HasSuffix(file, ".up.sql"):
+		file, up = strings.TrimSuffix(file, ".up.sql"), true
+	case strings.HasSuffix(file, ".down.sql"):
+		file = strings.TrimSuffix(file, ".down.sql")
+	default:
+		return 0, "", false, ErrorMigrationInvalid
+	}
+
+	s, name, ok := strings.Cut(file, "_")
+	if v, err = strconv.Atoi(s); !ok || err != nil || v < 1 || name == "" {
+		return 0, "", false, ErrorMigrationInvalid
+	}
+	return
+}
+
+// Latest returns the version of the newest migration of the Migrator.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Status returns every migration of the Migrator with the time it was applied, if it was.
+func (m *Migrator) Status(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := make([]*Migration, len(m.migrations))
+	for i, mg := range m.migrations {
+		c := *mg
+		if d, ok := applied[mg.Version]; ok {
+			c.Applied = d
+		}
+		ms[i] = &c
+	}
+	return ms, nil
+}
+
+// Check returns ErrorSchemaUnknown if Moment-Db has a migration applied that the Migrator does
+// not know, and ErrorSchemaPending if a migration of the Migrator is not applied.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for v := range applied {
+		if v < 1 || v > m.Latest() {
+			Error.Println(ErrorSchemaUnknown, v)
+			return fmt.Errorf("version %d: %w", v, ErrorSchemaUnknown)
+		}
+	}
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; !ok {
+			Error.Println(ErrorSchemaPending, mg.Version)
+			return fmt.Errorf("version %d: %w", mg.Version, ErrorSchemaPending)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns
+// the migrations it applied.
+func (m *Migrator) Up(ctx context.Context) (ms []*Migration, err error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		dt := time.Now().UTC()
+		err = m.run(ctx, mg.up, sq.
+			Insert(schSchemaMigrations).
+			Columns(version, migrationName, appliedDate).
+			Values(mg.Version, mg.Name, dt).
+			PlaceholderFormat(format{m.d}))
+		if err != nil {
+			return ms, err
+		}
+		c := *mg
+		c.Applied = &dt
+		ms = append(ms, &c)
+	}
+	return ms, nil
+}
+
+// Down reverts the newest applied migration and returns it, or returns nil if no migration
+// is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		err = m.run(ctx, mg.down, sq.
+			Delete(schSchemaMigrations).
+			Where(sq.Eq{version: mg.Version}).
+			PlaceholderFormat(format{m.d}))
+		if err != nil {
+			return nil, err
+		}
+		c := *mg
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// run executes script and then record in one transaction.
+func (m *Migrator) run(ctx context.Context, script string, record sq.Sqlizer) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	defer func() {
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				Error.Println(txerr)
+			}
+			Error.Println(err)
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			err = dbError(ctxError(ctx, err))
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return dbError(ctxError(ctx, err))
+	}
+
+	if _, err = sq.ExecContextWith(ctx, tx, record); err != nil {
+		return dbError(ctxError(ctx, err))
+	}
+	return nil
+}
+
+// applied creates the history table if it does not exist and returns the time each
+// applied migration was applied, by version.
+func (m *Migrator) applied(ctx context.Context) (map[int]*time.Time, error) {
+	if _, err := m.db.ExecContext(ctx, m.history); err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+
+	rows, err := sq.
+		Select(version, appliedDate).
+		From(schSchemaMigrations).
+		OrderBy(version).
+		PlaceholderFormat(format{m.d}).
+		RunWith(m.db).
+		QueryContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*time.Time)
+	for rows.Next() {
+		var v int
+		d := new(time.Time)
+		if err = rows.Scan(&v, d); err != nil {
+			Error.Println(err)
+			return nil, dbError(err)
+		}
+		applied[v] = d
+	}
+	if err = rows.Err(); err != nil {
+		Error.Println(err)
+		return nil, dbError(ctxError(ctx, err))
+	}
+	return applied, nil
+}
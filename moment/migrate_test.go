@@ -0,0 +1,189 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewMigrator(t *testing.T) {
+	var names []string
+	for _, d := range []Dialect{MSSQL, PostgreSQL, SQLite} {
+		m, err := NewMigrator(nil, d)
+		assert.Nil(t, err)
+
+		var ns []string
+		for _, mg := range m.migrations {
+			ns = append(ns, mg.Name)
+		}
+		if names == nil {
+			names = ns
+		}
+		assert.Equal(t, names, ns, "every Dialect has the same migrations")
+	}
+	assert.Equal(t, []string{"create_moments", "create_finds", "create_shares", "add_cells", "create_idempotency_keys", "add_deleted_at", "add_expires_at", "add_release_date", "add_find_location", "add_capacity", "extend_idempotency_keys"}, names)
+
+	_, err := NewMigrator(nil, nil)
+	assert.Exactly(t, ErrorDialectUnknown, err)
+}
+
+func Test_parseMigration(t *testing.T) {
+	type test struct {
+		file     string
+		version  int
+		name     string
+		up       bool
+		expected error
+	}
+	tests := []test{
+		test{"0001_create_moments.up.sql", 1, "create_moments", true, nil},
+		test{"0012_add_index.down.sql", 12, "add_index", false, nil},
+		test{"0001_create_moments.sql", 0, "", false, ErrorMigrationInvalid},
+		test{"create_moments.up.sql", 0, "", false, ErrorMigrationInvalid},
+		test{"0001.up.sql", 0, "", false, ErrorMigrationInvalid},
+		test{"0000_zero.up.sql", 0, "", false, ErrorMigrationInvalid},
+	}
+
+	for _, v := range tests {
+		version, name, up, err := parseMigration(v.file)
+		assert.Exactly(t, v.expected, err, v.file)
+		assert.Equal(t, v.version, version, v.file)
+		assert.Equal(t, v.name, name, v.file)
+		assert.Equal(t, v.up, up, v.file)
+	}
+}
+
+func TestMigratorCheck(t *testing.T) {
+	type test struct {
+		name     string
+		applied  []int
+		expected error
+	}
+	tests := []test{
+		test{"current", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, nil},
+		test{"empty", nil, ErrorSchemaPending},
+		test{"behind", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ErrorSchemaPending},
+		test{"ahead", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, ErrorSchemaUnknown},
+	}
+
+	for _, v := range tests {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+
+		rows := sqlmock.NewRows([]string{"Version", "AppliedDate"})
+		for _, a := range v.applied {
+			rows.AddRow(a, time.Now())
+		}
+		mock.ExpectExec(`CREATE TABLE \[moment\]\.\[SchemaMigrations\]`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT \[Version\], \[AppliedDate\] FROM \[moment\]\.\[SchemaMigrations\] ORDER BY \[Version\]$`).WillReturnRows(rows)
+
+		m, err := NewMigrator(db, MSSQL)
+		assert.Nil(t, err)
+		err = m.Check(context.Background())
+		if v.expected == nil {
+			assert.Nil(t, err, v.name)
+		} else {
+			assert.True(t, errors.Is(err, v.expected), v.name)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.name)
+	}
+}
+
+func TestMigratorSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open(SQLiteDriver, "file:"+filepath.Join(t.TempDir(), "moment.db")+"?_pragma=foreign_keys(1)")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db, SQLite)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(m.Check(ctx), ErrorSchemaPending))
+
+	ms, err := m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Latest(), len(ms))
+	assert.Nil(t, m.Check(ctx))
+
+	ms, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, ms)
+
+	mg, err := m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 11, mg.Version)
+	_, err = db.Exec(`SELECT ExpiresAt, Headers FROM IdempotencyKeys`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, mg.Version)
+	_, err = db.Exec(`SELECT Capacity FROM Moments`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, mg.Version)
+	_, err = db.Exec(`SELECT FindLatitude FROM Finds`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, mg.Version)
+	_, err = db.Exec(`SELECT ReleaseDate FROM Moments`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, mg.Version)
+	_, err = db.Exec(`SELECT ExpiresAt FROM Moments`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, mg.Version)
+	_, err = db.Exec(`SELECT DeletedAt FROM Moments`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, mg.Version)
+	_, err = db.Exec(`SELECT 1 FROM "IdempotencyKeys"`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, mg.Version)
+	_, err = db.Exec(`SELECT Cell FROM Moments`)
+	assert.NotNil(t, err)
+
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, mg.Version)
+	_, err = db.Exec(`SELECT 1 FROM "Shares"`)
+	assert.NotNil(t, err)
+
+	ms, err = m.Status(ctx)
+	assert.Nil(t, err)
+	assert.NotNil(t, ms[1].Applied)
+	assert.Nil(t, ms[2].Applied)
+	assert.True(t, errors.Is(m.Check(ctx), ErrorSchemaPending))
+
+	for i := 0; i < 2; i++ {
+		_, err = m.Down(ctx)
+		assert.Nil(t, err)
+	}
+	mg, err = m.Down(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, mg)
+
+	_, err = m.Up(ctx)
+	assert.Nil(t, err)
+	_, err = db.Exec(`INSERT INTO "SchemaMigrations" VALUES (?, 'from_the_future', ?)`, m.Latest()+1, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(m.Check(ctx), ErrorSchemaUnknown))
+}
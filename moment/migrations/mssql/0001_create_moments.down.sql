@@ -0,0 +1,2 @@
+DROP TABLE [moment].[Media];
+DROP TABLE [moment].[Moments];
@@ -0,0 +1,20 @@
+CREATE TABLE [moment].[Moments] (
+	[ID]         BIGINT       IDENTITY(1,1) NOT NULL CONSTRAINT [PK_Moments] PRIMARY KEY,
+	[UserID]     NVARCHAR(64) NOT NULL,
+	[Latitude]   REAL         NOT NULL,
+	[Longitude]  REAL         NOT NULL,
+	[Public]     BIT          NOT NULL,
+	[Hidden]     BIT          NOT NULL,
+	[CreateDate] DATETIME2    NOT NULL
+);
+CREATE INDEX [IX_Moments_Location] ON [moment].[Moments] ([Latitude], [Longitude]);
+CREATE INDEX [IX_Moments_UserID] ON [moment].[Moments] ([UserID]);
+
+CREATE TABLE [moment].[Media] (
+	[ID]       BIGINT        IDENTITY(1,1) NOT NULL CONSTRAINT [PK_Media] PRIMARY KEY,
+	[MomentID] BIGINT        NOT NULL CONSTRAINT [FK_Media_Moments] REFERENCES [moment].[Moments] ([ID]),
+	[Message]  NVARCHAR(256) NOT NULL,
+	[Type]     TINYINT       NOT NULL,
+	[Dir]      NVARCHAR(260) NOT NULL
+);
+CREATE INDEX [IX_Media_MomentID] ON [moment].[Media] ([MomentID]);
@@ -0,0 +1 @@
+DROP TABLE [moment].[Finds];
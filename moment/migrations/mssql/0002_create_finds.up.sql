@@ -0,0 +1,8 @@
+CREATE TABLE [moment].[Finds] (
+	[MomentID] BIGINT       NOT NULL CONSTRAINT [FK_Finds_Moments] REFERENCES [moment].[Moments] ([ID]),
+	[UserID]   NVARCHAR(64) NOT NULL,
+	[Found]    BIT          NOT NULL,
+	[FindDate] DATETIME2    NULL,
+	CONSTRAINT [PK_Finds] PRIMARY KEY ([MomentID], [UserID])
+);
+CREATE INDEX [IX_Finds_UserID] ON [moment].[Finds] ([UserID]);
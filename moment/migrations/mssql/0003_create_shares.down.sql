@@ -0,0 +1,2 @@
+DROP TABLE [moment].[Recipients];
+DROP TABLE [moment].[Shares];
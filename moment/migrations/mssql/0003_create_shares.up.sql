@@ -0,0 +1,14 @@
+CREATE TABLE [moment].[Shares] (
+	[ID]       BIGINT       IDENTITY(1,1) NOT NULL CONSTRAINT [PK_Shares] PRIMARY KEY,
+	[MomentID] BIGINT       NOT NULL CONSTRAINT [FK_Shares_Moments] REFERENCES [moment].[Moments] ([ID]),
+	[UserID]   NVARCHAR(64) NOT NULL
+);
+CREATE INDEX [IX_Shares_MomentID] ON [moment].[Shares] ([MomentID]);
+CREATE INDEX [IX_Shares_UserID] ON [moment].[Shares] ([UserID]);
+
+CREATE TABLE [moment].[Recipients] (
+	[SharesID]    BIGINT       NOT NULL CONSTRAINT [FK_Recipients_Shares] REFERENCES [moment].[Shares] ([ID]),
+	[All]         BIT          NOT NULL,
+	[RecipientID] NVARCHAR(64) NOT NULL,
+	CONSTRAINT [UQ_Recipients] UNIQUE ([SharesID], [RecipientID])
+);
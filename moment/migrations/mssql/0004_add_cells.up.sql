@@ -0,0 +1,4 @@
+-- [Cell] is the geohash of [Latitude] and [Longitude]. Location selectors compare it by range,
+-- so it must sort by code point. Run migrate backfill to set it on existing moments.
+ALTER TABLE [moment].[Moments] ADD [Cell] VARCHAR(12) COLLATE Latin1_General_BIN2 NULL;
+CREATE INDEX [IX_Moments_Cell] ON [moment].[Moments] ([Cell]);
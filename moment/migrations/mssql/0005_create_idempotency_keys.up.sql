@@ -0,0 +1,11 @@
+-- A row without a [Status] is reserved by a request that is still running.
+CREATE TABLE [moment].[IdempotencyKeys] (
+	[UserID]         NVARCHAR(64)   NOT NULL,
+	[IdempotencyKey] NVARCHAR(255)  NOT NULL,
+	[RequestHash]    VARBINARY(32)  NOT NULL,
+	[CreateDate]     DATETIME2      NOT NULL,
+	[Status]         SMALLINT       NULL,
+	[ContentType]    NVARCHAR(255)  NULL,
+	[Body]           VARBINARY(MAX) NULL,
+	CONSTRAINT [PK_IdempotencyKeys] PRIMARY KEY ([UserID], [IdempotencyKey])
+);
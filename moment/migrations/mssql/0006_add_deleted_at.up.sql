@@ -0,0 +1,4 @@
+-- A moment with a [DeletedAt] is deleted. Selectors skip it and its author may restore it until
+-- it is purged, together with its [Media], [Finds], [Shares] and [Recipients], RestoreWindow later.
+ALTER TABLE [moment].[Moments] ADD [DeletedAt] DATETIME2 NULL;
+CREATE INDEX [IX_Moments_DeletedAt] ON [moment].[Moments] ([DeletedAt]);
@@ -0,0 +1,2 @@
+DROP INDEX [IX_Moments_ExpiresAt] ON [moment].[Moments];
+ALTER TABLE [moment].[Moments] DROP COLUMN [ExpiresAt];
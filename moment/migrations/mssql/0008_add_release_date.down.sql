@@ -0,0 +1,2 @@
+DROP INDEX [IX_Moments_ReleaseDate] ON [moment].[Moments];
+ALTER TABLE [moment].[Moments] DROP COLUMN [ReleaseDate];
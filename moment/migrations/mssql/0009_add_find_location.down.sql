@@ -0,0 +1 @@
+ALTER TABLE [moment].[Finds] DROP COLUMN [FindAccuracy], [FindLongitude], [FindLatitude];
@@ -0,0 +1,2 @@
+ALTER TABLE [moment].[Moments] DROP CONSTRAINT [DF_Moments_Claimed];
+ALTER TABLE [moment].[Moments] DROP COLUMN [Claimed], [Capacity];
@@ -0,0 +1,2 @@
+DROP INDEX [IX_IdempotencyKeys_ExpiresAt] ON [moment].[IdempotencyKeys];
+ALTER TABLE [moment].[IdempotencyKeys] DROP COLUMN [Headers], [ExpiresAt];
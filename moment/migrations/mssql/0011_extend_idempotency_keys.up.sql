@@ -0,0 +1,8 @@
+-- A key expires at its [ExpiresAt], after which the reaper deletes it. A key reserved before has
+-- no [ExpiresAt] and expires KeyTTL after its [CreateDate]. [Headers] is the JSON object of the
+-- whole header set of the response and replaces [ContentType], which is only read for the
+-- responses stored before.
+ALTER TABLE [moment].[IdempotencyKeys] ADD
+	[ExpiresAt] DATETIME2     NULL,
+	[Headers]   NVARCHAR(MAX) NULL;
+CREATE INDEX [IX_IdempotencyKeys_ExpiresAt] ON [moment].[IdempotencyKeys] ([ExpiresAt]);
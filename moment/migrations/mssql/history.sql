@@ -0,0 +1,10 @@
+-- The [moment] schema and the history of the migrations applied to it.
+IF SCHEMA_ID('moment') IS NULL
+	EXEC('CREATE SCHEMA [moment]');
+
+IF OBJECT_ID('[moment].[SchemaMigrations]') IS NULL
+	CREATE TABLE [moment].[SchemaMigrations] (
+		[Version]     INT           NOT NULL CONSTRAINT [PK_SchemaMigrations] PRIMARY KEY,
+		[Name]        NVARCHAR(128) NOT NULL,
+		[AppliedDate] DATETIME2     NOT NULL
+	);
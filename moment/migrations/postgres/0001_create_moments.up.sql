@@ -0,0 +1,20 @@
+CREATE TABLE "moment"."Moments" (
+	"ID"         BIGINT      GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	"UserID"     VARCHAR(64) NOT NULL,
+	"Latitude"   REAL        NOT NULL,
+	"Longitude"  REAL        NOT NULL,
+	"Public"     BOOLEAN     NOT NULL,
+	"Hidden"     BOOLEAN     NOT NULL,
+	"CreateDate" TIMESTAMP   NOT NULL
+);
+CREATE INDEX "IX_Moments_Location" ON "moment"."Moments" ("Latitude", "Longitude");
+CREATE INDEX "IX_Moments_UserID" ON "moment"."Moments" ("UserID");
+
+CREATE TABLE "moment"."Media" (
+	"ID"       BIGINT       GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	"MomentID" BIGINT       NOT NULL REFERENCES "moment"."Moments" ("ID"),
+	"Message"  VARCHAR(256) NOT NULL,
+	"Type"     SMALLINT     NOT NULL,
+	"Dir"      VARCHAR(260) NOT NULL
+);
+CREATE INDEX "IX_Media_MomentID" ON "moment"."Media" ("MomentID");
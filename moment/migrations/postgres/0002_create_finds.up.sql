@@ -0,0 +1,8 @@
+CREATE TABLE "moment"."Finds" (
+	"MomentID" BIGINT      NOT NULL REFERENCES "moment"."Moments" ("ID"),
+	"UserID"   VARCHAR(64) NOT NULL,
+	"Found"    BOOLEAN     NOT NULL,
+	"FindDate" TIMESTAMP,
+	PRIMARY KEY ("MomentID", "UserID")
+);
+CREATE INDEX "IX_Finds_UserID" ON "moment"."Finds" ("UserID");
@@ -0,0 +1,14 @@
+CREATE TABLE "moment"."Shares" (
+	"ID"       BIGINT      GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	"MomentID" BIGINT      NOT NULL REFERENCES "moment"."Moments" ("ID"),
+	"UserID"   VARCHAR(64) NOT NULL
+);
+CREATE INDEX "IX_Shares_MomentID" ON "moment"."Shares" ("MomentID");
+CREATE INDEX "IX_Shares_UserID" ON "moment"."Shares" ("UserID");
+
+CREATE TABLE "moment"."Recipients" (
+	"SharesID"    BIGINT      NOT NULL REFERENCES "moment"."Shares" ("ID"),
+	"All"         BOOLEAN     NOT NULL,
+	"RecipientID" VARCHAR(64) NOT NULL,
+	UNIQUE ("SharesID", "RecipientID")
+);
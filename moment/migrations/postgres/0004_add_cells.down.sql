@@ -0,0 +1,2 @@
+DROP INDEX "moment"."IX_Moments_Cell";
+ALTER TABLE "moment"."Moments" DROP COLUMN "Cell";
@@ -0,0 +1,4 @@
+-- "Cell" is the geohash of "Latitude" and "Longitude". Location selectors compare it by range,
+-- so it must sort by code point. Run migrate backfill to set it on existing moments.
+ALTER TABLE "moment"."Moments" ADD COLUMN "Cell" VARCHAR(12) COLLATE "C";
+CREATE INDEX "IX_Moments_Cell" ON "moment"."Moments" ("Cell");
@@ -0,0 +1,11 @@
+-- A row without a "Status" is reserved by a request that is still running.
+CREATE TABLE "moment"."IdempotencyKeys" (
+	"UserID"         VARCHAR(64)  NOT NULL,
+	"IdempotencyKey" VARCHAR(255) NOT NULL,
+	"RequestHash"    BYTEA        NOT NULL,
+	"CreateDate"     TIMESTAMP    NOT NULL,
+	"Status"         SMALLINT,
+	"ContentType"    VARCHAR(255),
+	"Body"           BYTEA,
+	PRIMARY KEY ("UserID", "IdempotencyKey")
+);
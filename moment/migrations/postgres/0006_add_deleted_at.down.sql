@@ -0,0 +1,2 @@
+DROP INDEX "moment"."IX_Moments_DeletedAt";
+ALTER TABLE "moment"."Moments" DROP COLUMN "DeletedAt";
@@ -0,0 +1,4 @@
+-- A moment with a "ReleaseDate" in the future is a time capsule. Location selectors skip it and
+-- it cannot be found until then; its author and recipients only see that it is waiting.
+ALTER TABLE "moment"."Moments" ADD COLUMN "ReleaseDate" TIMESTAMP;
+CREATE INDEX "IX_Moments_ReleaseDate" ON "moment"."Moments" ("ReleaseDate");
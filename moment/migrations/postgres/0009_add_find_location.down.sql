@@ -0,0 +1,4 @@
+ALTER TABLE "moment"."Finds"
+	DROP COLUMN "FindAccuracy",
+	DROP COLUMN "FindLongitude",
+	DROP COLUMN "FindLatitude";
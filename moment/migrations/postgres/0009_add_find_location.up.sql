@@ -0,0 +1,7 @@
+-- Where a find was attempted from and the accuracy in meters of that GPS fix. A find is only
+-- accepted close to its moment; the columns are kept for auditing and are NULL on the "Finds"
+-- of the recipients of private moments until they find them.
+ALTER TABLE "moment"."Finds"
+	ADD COLUMN "FindLatitude"  REAL,
+	ADD COLUMN "FindLongitude" REAL,
+	ADD COLUMN "FindAccuracy"  REAL;
@@ -0,0 +1,3 @@
+ALTER TABLE "moment"."Moments"
+	DROP COLUMN "Claimed",
+	DROP COLUMN "Capacity";
@@ -0,0 +1,4 @@
+DROP INDEX "moment"."IX_IdempotencyKeys_ExpiresAt";
+ALTER TABLE "moment"."IdempotencyKeys"
+	DROP COLUMN "Headers",
+	DROP COLUMN "ExpiresAt";
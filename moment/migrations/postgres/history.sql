@@ -0,0 +1,8 @@
+-- The moment schema and the history of the migrations applied to it.
+CREATE SCHEMA IF NOT EXISTS "moment";
+
+CREATE TABLE IF NOT EXISTS "moment"."SchemaMigrations" (
+	"Version"     INTEGER      NOT NULL PRIMARY KEY,
+	"Name"        VARCHAR(128) NOT NULL,
+	"AppliedDate" TIMESTAMP    NOT NULL
+);
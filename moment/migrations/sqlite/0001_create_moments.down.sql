@@ -0,0 +1,2 @@
+DROP TABLE "Media";
+DROP TABLE "Moments";
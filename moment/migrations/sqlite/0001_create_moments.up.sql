@@ -0,0 +1,20 @@
+CREATE TABLE "Moments" (
+	"ID"         INTEGER  PRIMARY KEY AUTOINCREMENT,
+	"UserID"     TEXT     NOT NULL,
+	"Latitude"   REAL     NOT NULL,
+	"Longitude"  REAL     NOT NULL,
+	"Public"     BOOLEAN  NOT NULL,
+	"Hidden"     BOOLEAN  NOT NULL,
+	"CreateDate" DATETIME NOT NULL
+);
+CREATE INDEX "IX_Moments_Location" ON "Moments" ("Latitude", "Longitude");
+CREATE INDEX "IX_Moments_UserID" ON "Moments" ("UserID");
+
+CREATE TABLE "Media" (
+	"ID"       INTEGER PRIMARY KEY AUTOINCREMENT,
+	"MomentID" INTEGER NOT NULL REFERENCES "Moments" ("ID"),
+	"Message"  TEXT    NOT NULL,
+	"Type"     INTEGER NOT NULL,
+	"Dir"      TEXT    NOT NULL
+);
+CREATE INDEX "IX_Media_MomentID" ON "Media" ("MomentID");
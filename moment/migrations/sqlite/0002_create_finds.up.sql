@@ -0,0 +1,8 @@
+CREATE TABLE "Finds" (
+	"MomentID" INTEGER  NOT NULL REFERENCES "Moments" ("ID"),
+	"UserID"   TEXT     NOT NULL,
+	"Found"    BOOLEAN  NOT NULL,
+	"FindDate" DATETIME,
+	PRIMARY KEY ("MomentID", "UserID")
+);
+CREATE INDEX "IX_Finds_UserID" ON "Finds" ("UserID");
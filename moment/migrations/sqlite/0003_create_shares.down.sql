@@ -0,0 +1,2 @@
+DROP TABLE "Recipients";
+DROP TABLE "Shares";
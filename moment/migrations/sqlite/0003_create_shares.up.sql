@@ -0,0 +1,14 @@
+CREATE TABLE "Shares" (
+	"ID"       INTEGER PRIMARY KEY AUTOINCREMENT,
+	"MomentID" INTEGER NOT NULL REFERENCES "Moments" ("ID"),
+	"UserID"   TEXT    NOT NULL
+);
+CREATE INDEX "IX_Shares_MomentID" ON "Shares" ("MomentID");
+CREATE INDEX "IX_Shares_UserID" ON "Shares" ("UserID");
+
+CREATE TABLE "Recipients" (
+	"SharesID"    INTEGER NOT NULL REFERENCES "Shares" ("ID"),
+	"All"         BOOLEAN NOT NULL,
+	"RecipientID" TEXT    NOT NULL,
+	UNIQUE ("SharesID", "RecipientID")
+);
@@ -0,0 +1,2 @@
+DROP INDEX "IX_Moments_Cell";
+ALTER TABLE "Moments" DROP COLUMN "Cell";
@@ -0,0 +1,4 @@
+-- "Cell" is the geohash of "Latitude" and "Longitude". Location selectors compare it by range.
+-- Run migrate backfill to set it on existing moments.
+ALTER TABLE "Moments" ADD COLUMN "Cell" TEXT;
+CREATE INDEX "IX_Moments_Cell" ON "Moments" ("Cell");
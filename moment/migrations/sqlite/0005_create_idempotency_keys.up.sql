@@ -0,0 +1,11 @@
+-- A row without a "Status" is reserved by a request that is still running.
+CREATE TABLE "IdempotencyKeys" (
+	"UserID"         TEXT     NOT NULL,
+	"IdempotencyKey" TEXT     NOT NULL,
+	"RequestHash"    BLOB     NOT NULL,
+	"CreateDate"     DATETIME NOT NULL,
+	"Status"         INTEGER,
+	"ContentType"    TEXT,
+	"Body"           BLOB,
+	PRIMARY KEY ("UserID", "IdempotencyKey")
+);
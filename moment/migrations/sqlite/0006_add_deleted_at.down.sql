@@ -0,0 +1,2 @@
+DROP INDEX "IX_Moments_DeletedAt";
+ALTER TABLE "Moments" DROP COLUMN "DeletedAt";
@@ -0,0 +1,2 @@
+DROP INDEX "IX_Moments_ExpiresAt";
+ALTER TABLE "Moments" DROP COLUMN "ExpiresAt";
@@ -0,0 +1,4 @@
+-- A moment with an "ExpiresAt" in the past has expired. Selectors skip it, it cannot be found,
+-- and the reaper deletes it as of its "ExpiresAt".
+ALTER TABLE "Moments" ADD COLUMN "ExpiresAt" DATETIME;
+CREATE INDEX "IX_Moments_ExpiresAt" ON "Moments" ("ExpiresAt");
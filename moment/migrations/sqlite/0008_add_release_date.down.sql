@@ -0,0 +1,2 @@
+DROP INDEX "IX_Moments_ReleaseDate";
+ALTER TABLE "Moments" DROP COLUMN "ReleaseDate";
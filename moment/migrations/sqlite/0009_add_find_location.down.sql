@@ -0,0 +1,3 @@
+ALTER TABLE "Finds" DROP COLUMN "FindAccuracy";
+ALTER TABLE "Finds" DROP COLUMN "FindLongitude";
+ALTER TABLE "Finds" DROP COLUMN "FindLatitude";
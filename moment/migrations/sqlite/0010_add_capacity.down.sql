@@ -0,0 +1,2 @@
+ALTER TABLE "Moments" DROP COLUMN "Claimed";
+ALTER TABLE "Moments" DROP COLUMN "Capacity";
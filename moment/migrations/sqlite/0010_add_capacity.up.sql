@@ -0,0 +1,5 @@
+-- A public moment with a "Capacity" can only be found by its first "Capacity" finders. "Claimed"
+-- counts them and is raised in the same transaction as each find so they cannot oversubscribe it.
+-- Moments created before have no capacity and need no backfill.
+ALTER TABLE "Moments" ADD COLUMN "Capacity" INTEGER;
+ALTER TABLE "Moments" ADD COLUMN "Claimed" INTEGER NOT NULL DEFAULT 0;
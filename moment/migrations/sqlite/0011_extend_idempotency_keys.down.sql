@@ -0,0 +1,3 @@
+DROP INDEX "IX_IdempotencyKeys_ExpiresAt";
+ALTER TABLE "IdempotencyKeys" DROP COLUMN "Headers";
+ALTER TABLE "IdempotencyKeys" DROP COLUMN "ExpiresAt";
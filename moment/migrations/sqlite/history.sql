@@ -0,0 +1,7 @@
+-- The history of the migrations applied to the database. SQLite has no schemas, so the tables
+-- of [moment] are created in the main database and the SQLite Dialect drops the schema.
+CREATE TABLE IF NOT EXISTS "SchemaMigrations" (
+	"Version"     INTEGER  NOT NULL PRIMARY KEY,
+	"Name"        TEXT     NOT NULL,
+	"AppliedDate" DATETIME NOT NULL
+);
@@ -1,6 +1,7 @@
 package moment
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -32,12 +33,12 @@ const (
 	maxMessage = 256
 
 	// minLat and maxLat represent the max and min values of the [moment].[Moments].[Latitude] column.
-	minLat = -180
-	maxLat = 180
+	minLat = -90
+	maxLat = 90
 
 	// minLong and maxLong represents the max and min values of the [moment].[Moments].[Longitude] column.
-	minLong = -90
-	maxLong = 90
+	minLong = -180
+	maxLong = 180
 
 	// Datetime2 is the time.Time format this package uses to communicate DateTime2 values to Moment-Db.
 	Datetime2 = "2006-01-02 15:04:05"
@@ -70,33 +71,48 @@ func init() {
 }
 
 var (
-	ErrorPrivateHiddenMoment = errors.New("m *MomentsRow cannot be both private and hidden")
-	ErrorParameterEmpty      = errors.New("Parameter is empty.")
-	ErrorFieldInvalid        = errors.New("A struct field is not in the state required.")
+	ErrorPrivateHiddenMoment = invalid("hidden", "m *MomentsRow cannot be both private and hidden")
+	ErrorParameterEmpty      = invalid("", "Parameter is empty.")
+	ErrorFieldInvalid        = invalid("", "A struct field is not in the state required.")
 	ErrorTypeNotImplemented  = errors.New("Type switch does not handle this type.")
 )
 
-// MomentDB creates *db.Sql instance.
-func DB() *sql.DB {
-	db, err := sql.Open(driver, connStr)
-	if err != nil {
-		panic(err)
-	}
-	return db
-}
-
+// DbRunner runs the statements of this package. It is satisfied by *sql.DB and *sql.Tx.
+// Every statement is run through the Context methods so that a caller can cancel it or give it a deadline.
 type DbRunner interface {
 	Exec(string, ...interface{}) (sql.Result, error)
 	Query(string, ...interface{}) (*sql.Rows, error)
+	QueryRow(string, ...interface{}) *sql.Row
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
 }
 
+// DbRunnerTrans is a DbRunner that can begin the transactions of the Creater and Sharer methods.
 type DbRunnerTrans interface {
 	DbRunner
 	Begin() (*sql.Tx, error)
+	BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
 }
 
 type MomentClient struct {
-	err error
+	d Dialect
+	// radius is how far in meters from a moment a find is accepted, or 0 for DefaultFindRadius.
+	radius float64
+	err    error
+}
+
+// NewMomentClient is a constructor for the MomentClient struct. Its statements are written in
+// d; the zero MomentClient writes them for SQL Server.
+func NewMomentClient(d Dialect) *MomentClient {
+	return &MomentClient{d: d}
+}
+
+func (mc *MomentClient) dialect() Dialect {
+	if mc.d == nil {
+		return MSSQL
+	}
+	return mc.d
 }
 
 func (mc *MomentClient) Err() error {
@@ -104,50 +120,100 @@ func (mc *MomentClient) Err() error {
 }
 
 type Finder interface {
-	FindPublic(DbRunner, *FindsRow) (int64, error)
-	FindPrivate(DbRunner, *FindsRow) error
+	FindPublic(context.Context, DbRunnerTrans, *FindsRow) (int64, error)
+	FindPrivate(context.Context, DbRunner, *FindsRow) error
 }
 
 type Sharer interface {
-	Share(DbRunnerTrans, *SharesRow, []*RecipientsRow) error
+	Share(context.Context, DbRunnerTrans, *SharesRow, []*RecipientsRow) error
 }
 
 type Creater interface {
-	CreatePublic(DbRunnerTrans, *MomentsRow, []*MediaRow) error
-	CreatePrivate(DbRunnerTrans, *MomentsRow, []*MediaRow, []*FindsRow) error
+	CreatePublic(context.Context, DbRunnerTrans, *MomentsRow, []*MediaRow) error
+	CreatePrivate(context.Context, DbRunnerTrans, *MomentsRow, []*MediaRow, []*FindsRow) error
+}
+
+// Editor edits the hidden flag and the media messages of a moment that is not deleted.
+type Editor interface {
+	Edit(context.Context, DbRunnerTrans, *MomentEdit) error
+}
+
+// Deleter deletes a moment, which every selector then skips, and restores it within RestoreWindow.
+type Deleter interface {
+	Delete(context.Context, DbRunner, int64, time.Time) error
+	Restore(context.Context, DbRunner, int64, time.Time) error
 }
 
 type Modifier interface {
 	Finder
 	Sharer
 	Creater
+	Editor
+	Deleter
 }
 
-// FindPublic inserts a FindsRow into the [Moment-Db].[moment].[Finds] table with Found=true.
-func (mc *MomentClient) FindPublic(db DbRunner, f *FindsRow) (cnt int64, err error) {
+// FindPublic inserts a FindsRow into the [Moment-Db].[moment].[Finds] table with Found=true. It
+// claims a place on the moment in the same transaction and returns ErrorMomentFull if none are left.
+// A private moment is reported as ErrorMomentDNE; only its recipients find it, by FindPrivate.
+func (mc *MomentClient) FindPublic(ctx context.Context, db DbRunnerTrans, f *FindsRow) (cnt int64, err error) {
 	if err = f.isFound(); err != nil {
 		Error.Println(err)
 		return
 	}
 
-	fs := []*FindsRow{
-		f,
-	}
-	cnt, err = insert(db, fs)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
+		return
 	}
+	defer func() {
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				Error.Println(txerr)
+			}
+			Error.Println(err)
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+		}
+	}()
+
+	l, err := mc.live(ctx, tx, f.momentID, sq.Eq{public: true})
+	if err != nil {
+		return
+	}
+	if err = f.near(l, mc.findRadius()); err != nil {
+		return
+	}
+	if err = mc.claim(ctx, tx, f.momentID); err != nil {
+		return
+	}
+
+	fs := []*FindsRow{
+		f,
+	}
+	cnt, err = insert(ctx, mc.dialect(), tx, fs)
 	return
 }
 
 // FindPrivate updates a FindsRow in the [Moment-Db].[moment].[Finds] by setting Found=true.
-func (mc *MomentClient) FindPrivate(db DbRunner, f *FindsRow) (err error) {
+func (mc *MomentClient) FindPrivate(ctx context.Context, db DbRunner, f *FindsRow) (err error) {
 	if err = f.isFound(); err != nil {
 		Error.Println(err)
 		return
 	}
 
-	if err = update(db, f); err != nil {
+	l, err := mc.live(ctx, db, f.momentID)
+	if err != nil {
+		return
+	}
+	if err = f.near(l, mc.findRadius()); err != nil {
+		return
+	}
+	if err = update(ctx, mc.dialect(), db, f); err != nil {
 		Error.Println(err)
 	}
 	return
@@ -155,16 +221,17 @@ func (mc *MomentClient) FindPrivate(db DbRunner, f *FindsRow) (err error) {
 
 // Share is an exported package that allows the insertion of a
 // Shares instance into the [Moment-Db].[moment].[Shares] table.
-func (mc *MomentClient) Share(db DbRunnerTrans, s *SharesRow, rs []*RecipientsRow) (err error) {
+func (mc *MomentClient) Share(ctx context.Context, db DbRunnerTrans, s *SharesRow, rs []*RecipientsRow) (err error) {
 	if len(rs) == 0 || s == nil {
 		Error.Println(ErrorParameterEmpty)
 		err = ErrorParameterEmpty
 		return
 	}
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer func() {
@@ -175,12 +242,19 @@ func (mc *MomentClient) Share(db DbRunnerTrans, s *SharesRow, rs []*RecipientsRo
 			Error.Println(err)
 			return
 		}
-		tx.Commit()
+		if err = tx.Commit(); err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+		}
 	}()
 
-	id, err := insert(tx, s)
+	if _, err = mc.live(ctx, tx, s.momentID); err != nil {
+		return
+	}
+	id, err := insert(ctx, mc.dialect(), tx, s)
 	if err != nil {
 		Error.Println(err)
+		return
 	}
 	s.sharesID = id
 
@@ -190,24 +264,25 @@ func (mc *MomentClient) Share(db DbRunnerTrans, s *SharesRow, rs []*RecipientsRo
 			return
 		}
 	}
-	if _, err = insert(tx, rs); err != nil {
+	if _, err = insert(ctx, mc.dialect(), tx, rs); err != nil {
 		return
 	}
 	return
 }
 
-var ErrorMediaPointerNil = errors.New("md *Media is nil.")
+var ErrorMediaPointerNil = invalid("media", "md *Media is nil.")
 
 // CreatePublic creates a row in [Moment-Db].[moment].[Moments] where Public=true.
-func (mc *MomentClient) CreatePublic(db DbRunnerTrans, m *MomentsRow, ms []*MediaRow) (err error) {
+func (mc *MomentClient) CreatePublic(ctx context.Context, db DbRunnerTrans, m *MomentsRow, ms []*MediaRow) (err error) {
 	if len(ms) == 0 || m == nil {
 		Error.Println(ErrorParameterEmpty)
 		return ErrorParameterEmpty
 	}
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer func() {
@@ -218,11 +293,14 @@ func (mc *MomentClient) CreatePublic(db DbRunnerTrans, m *MomentsRow, ms []*Medi
 			Error.Println(err)
 			return
 		}
-		tx.Commit()
+		if err = tx.Commit(); err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+		}
 	}()
 
 	var mID int64
-	if mID, err = insert(tx, m); err != nil {
+	if mID, err = insert(ctx, mc.dialect(), tx, m); err != nil {
 		return
 	}
 	m.momentID = mID
@@ -233,26 +311,27 @@ func (mc *MomentClient) CreatePublic(db DbRunnerTrans, m *MomentsRow, ms []*Medi
 			return
 		}
 	}
-	if _, err = insert(tx, ms); err != nil {
+	if _, err = insert(ctx, mc.dialect(), tx, ms); err != nil {
 		return
 	}
 
 	return
 }
 
-var ErrorFindsPointerNil = errors.New("finds *Finds pointer is empty.")
+var ErrorFindsPointerNil = invalid("finds", "finds *Finds pointer is empty.")
 
 // CreatePrivate creates a MomentsRow in [Moment-Db].[moment].[Moments] where Public=true
 // and creates Finds in [Moment-Db].[moment].[Finds].
-func (mc *MomentClient) CreatePrivate(db DbRunnerTrans, m *MomentsRow, ms []*MediaRow, fs []*FindsRow) (err error) {
+func (mc *MomentClient) CreatePrivate(ctx context.Context, db DbRunnerTrans, m *MomentsRow, ms []*MediaRow, fs []*FindsRow) (err error) {
 	if m == nil || len(ms) == 0 || len(fs) == 0 {
 		Error.Println(ErrorParameterEmpty)
 		return ErrorParameterEmpty
 	}
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer func() {
@@ -263,10 +342,13 @@ func (mc *MomentClient) CreatePrivate(db DbRunnerTrans, m *MomentsRow, ms []*Med
 			Error.Println(err)
 			return
 		}
-		tx.Commit()
+		if err = tx.Commit(); err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+		}
 	}()
 
-	mID, err := insert(tx, m)
+	mID, err := insert(ctx, mc.dialect(), tx, m)
 	if err != nil {
 		Error.Println(err)
 		return
@@ -289,11 +371,11 @@ func (mc *MomentClient) CreatePrivate(db DbRunnerTrans, m *MomentsRow, ms []*Med
 		}
 	}
 
-	if _, err = insert(tx, ms); err != nil {
+	if _, err = insert(ctx, mc.dialect(), tx, ms); err != nil {
 		Error.Println(err)
 		return
 	}
-	if _, err = insert(tx, fs); err != nil {
+	if _, err = insert(ctx, mc.dialect(), tx, fs); err != nil {
 		Error.Println(err)
 		return
 	}
@@ -301,96 +383,158 @@ func (mc *MomentClient) CreatePrivate(db DbRunnerTrans, m *MomentsRow, ms []*Med
 	return
 }
 
-func insert(db DbRunner, i interface{}) (resVal int64, err error) {
+// insert inserts the rows of i and returns the generated ID of a MomentsRow or SharesRow,
+// or else the number of rows inserted.
+func insert(ctx context.Context, d Dialect, db DbRunner, i interface{}) (resVal int64, err error) {
 	var insert sq.InsertBuilder
 	switch v := i.(type) {
 	case []*FindsRow:
-		insert = sq.
-			Insert(momentSchema+"."+finds).
-			Columns(momentID, userID, found, findDate)
-		for _, f := range v {
-			insert = insert.Values(f.momentID, f.userID, f.found, f.findDate)
+		values := make([][]interface{}, len(v))
+		for j, f := range v {
+			values[j] = append([]interface{}{f.momentID, f.userID, f.found, f.findDate}, f.attempt.values()...)
 		}
+		return insertRows(ctx, d, db, schFinds, []string{momentID, userID, found, findDate, findLatitude, findLongitude, findAccuracy}, values)
 	case []*RecipientsRow:
-		insert = sq.
-			Insert(schRecipients).
-			Columns(sharesID, all, recipientID)
-		for _, r := range v {
-			insert = insert.Values(r.sharesID, r.all, r.recipientID)
+		values := make([][]interface{}, len(v))
+		for j, r := range v {
+			values[j] = []interface{}{r.sharesID, r.all, r.recipientID}
 		}
+		return insertRows(ctx, d, db, schRecipients, []string{sharesID, all, recipientID}, values)
 	case []*MediaRow:
-		insert = sq.
-			Insert(momentSchema+"."+media).
-			Columns(momentID, message, mtype, dir)
-		for _, md := range v {
-			insert = insert.Values(md.momentID, md.message, md.mType, md.dir)
+		values := make([][]interface{}, len(v))
+		for j, md := range v {
+			values[j] = []interface{}{md.momentID, md.message, md.mType, md.dir}
 		}
+		return insertRows(ctx, d, db, schMedia, []string{momentID, message, mtype, dir}, values)
+	case *IdempotencyKeysRow:
+		values := [][]interface{}{{v.userID, v.key, v.hash, v.createDate, v.expiresAt}}
+		return insertRows(ctx, d, db, schIdempotencyKeys, []string{userID, idempotencyKey, requestHash, createDate, expiresAt}, values)
 	case *MomentsRow:
-		insert = sq.
-			Insert(momentSchema+"."+moments).
-			Columns(userID, latStr, longStr, public, hidden, createDate).
-			Values(v.userID, v.latitude, v.longitude, v.public, v.hidden, v.createDate)
+		insert = d.
+			InsertID(schMoments, iD, userID, latStr, longStr, cellStr, public, hidden, createDate, expiresAt, releaseDate, capacity).
+			Values(v.userID, v.latitude, v.longitude, v.cell, v.public, v.hidden, v.createDate, v.expiresAt, v.releaseDate, v.capacity)
 	case *SharesRow:
-		insert = sq.
-			Insert(schShares).
-			Columns(momentID, userID).
+		insert = d.
+			InsertID(schShares, iD, momentID, userID).
 			Values(v.momentID, v.userID)
 	default:
 		return resVal, ErrorTypeNotImplemented
 	}
 
-	res, err := insert.RunWith(db).Exec()
-	if err != nil {
-		Error.Println(err)
-		return
-	}
-
-	switch i.(type) {
-	case *SharesRow:
-		resVal, err = res.LastInsertId()
-	case *MomentsRow:
-		resVal, err = res.LastInsertId()
-	default:
-		resVal, err = res.RowsAffected()
-	}
+	err = insert.PlaceholderFormat(format{d}).RunWith(db).QueryRowContext(ctx).Scan(&resVal)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 	}
 
 	return
 }
 
-func update(db DbRunner, i interface{}) (err error) {
+const (
+	// maxParams is the most parameters SQL Server accepts in one request. sp_executesql, through
+	// which the driver sends a statement, takes two of them for the statement and its declarations.
+	maxParams = 2100 - 2
+
+	// maxRowValues is the most rows SQL Server accepts in the VALUES of one INSERT.
+	maxRowValues = 1000
+)
+
+// chunkRows returns how many rows of columns columns one INSERT can hold.
+func chunkRows(columns int) int {
+	if n := maxParams / columns; n < maxRowValues {
+		return n
+	}
+	return maxRowValues
+}
+
+// insertRows inserts values into columns of table and returns the number of rows inserted.
+// It splits values into as many INSERTs as the limits of SQL Server require; run it in a
+// transaction so that either every chunk is inserted or none is. When a later chunk fails the
+// error is a ChunkError, which reports how far the insert got.
+func insertRows(ctx context.Context, d Dialect, db DbRunner, table string, columns []string, values [][]interface{}) (n int64, err error) {
+	size := chunkRows(len(columns))
+	chunks := (len(values) + size - 1) / size
+	for c := 0; c < chunks; c++ {
+		rows := values[c*size:]
+		if len(rows) > size {
+			rows = rows[:size]
+		}
+
+		insert := sq.Insert(table).Columns(columns...)
+		for _, r := range rows {
+			insert = insert.Values(r...)
+		}
+		var res sql.Result
+		var cnt int64
+		res, err = insert.PlaceholderFormat(format{d}).RunWith(db).ExecContext(ctx)
+		if err == nil {
+			cnt, err = res.RowsAffected()
+		}
+		if err != nil {
+			Error.Println(err)
+			err = dbError(ctxError(ctx, err))
+			if chunks > 1 {
+				err = &ChunkError{Chunk: c + 1, Chunks: chunks, Rows: n, Err: err}
+			}
+			return n, err
+		}
+		n += cnt
+	}
+	return n, nil
+}
+
+var ErrorFindsRowDNE = notFound("momentID", "No Finds row exists for this momentID and userID.")
+
+func update(ctx context.Context, d Dialect, db DbRunner, i interface{}) (err error) {
 	var query sq.UpdateBuilder
 	switch v := i.(type) {
 	case *FindsRow:
-		query = sq.Update(momentSchema+"."+finds).
+		a := v.attempt.values()
+		query = sq.Update(schFinds).
 			Set(found, v.found).
 			Set(findDate, v.findDate).
+			Set(findLatitude, a[0]).
+			Set(findLongitude, a[1]).
+			Set(findAccuracy, a[2]).
 			Where(sq.Eq{momentID: v.momentID}).
 			Where(sq.Eq{userID: v.userID})
 	default:
 		return ErrorTypeNotImplemented
 	}
 
-	_, err = query.RunWith(db).Exec()
+	res, err := query.PlaceholderFormat(format{d}).RunWith(db).ExecContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+
+	cnt, err := res.RowsAffected()
 	if err != nil {
 		Error.Println(err)
+		return dbError(ctxError(ctx, err))
+	}
+	if cnt == 0 {
+		return ErrorFindsRowDNE
 	}
 	return
 }
 
 type Newer interface {
-	NewMomentsRow(*Location, string, bool, bool, *time.Time) *MomentsRow
+	NewMomentsRow(*Location, string, bool, bool, *time.Time, *time.Time, *time.Time, *int) *MomentsRow
 	NewLocation(float32, float32) *Location
 	NewMediaRow(int64, string, uint8, string) *MediaRow
-	NewFindsRow(int64, string, bool, *time.Time) *FindsRow
+	NewFindsRow(int64, string, bool, *time.Time, *FindAttempt) *FindsRow
+	NewFindAttempt(*Location, float64) *FindAttempt
 	NewSharesRow(int64, int64, string) *SharesRow
 	NewRecipientsRow(int64, bool, string) *RecipientsRow
+	NewPageRequest(int, string, Sort) *PageRequest
+	NewIdempotencyKeysRow(string, string, []byte, *time.Time) *IdempotencyKeysRow
+	NewMomentEdit(int64, bool, []string) *MomentEdit
 }
 
-// NewMoment is a constructor for the MomentsRow struct.
-func (mc *MomentClient) NewMomentsRow(l *Location, uID string, p bool, h bool, c *time.Time) (m *MomentsRow) {
+// NewMoment is a constructor for the MomentsRow struct. A nil e never expires, a nil r is
+// released when it is created and a nil n can be found by any number of finders.
+func (mc *MomentClient) NewMomentsRow(l *Location, uID string, p bool, h bool, c *time.Time, e *time.Time, r *time.Time, n *int) (m *MomentsRow) {
 	if mc.err != nil {
 		return
 	}
@@ -399,6 +543,8 @@ func (mc *MomentClient) NewMomentsRow(l *Location, uID string, p bool, h bool, c
 
 	m.setLocation(l)
 	m.setCreateDate(c)
+	m.setExpiresAt(e)
+	m.setReleaseDate(r)
 	m.setUserID(uID)
 	if m.err != nil {
 		Error.Println(m.err)
@@ -413,6 +559,12 @@ func (mc *MomentClient) NewMomentsRow(l *Location, uID string, p bool, h bool, c
 		mc.err = ErrorPrivateHiddenMoment
 		return
 	}
+	m.setCapacity(n)
+	if m.err != nil {
+		Error.Println(m.err)
+		mc.err = m.err
+		return
+	}
 
 	return
 }
@@ -423,18 +575,27 @@ type MomentsRow struct {
 	Location
 	mID
 	uID
+	// cell is the geohash of Location that location selectors look moments up by.
+	cell       string
 	public     bool
 	hidden     bool
 	createDate *time.Time
-	err        error
+	// expiresAt is when the moment expires, or nil if it never does.
+	expiresAt *time.Time
+	// releaseDate is when a time capsule can first be seen and found, or nil for a moment
+	// released when it is created.
+	releaseDate *time.Time
+	// capacity is how many can find a public moment, or nil if any number can.
+	capacity *int
+	err      error
 }
 
 // String returns a string representation of a MomentsRow instance.
 func (m MomentsRow) String() string {
-	return fmt.Sprintf("id: %v, userID: %v, Location: %v, public: %v, hidden: %v, creatDate: %v", m.momentID, m.userID, m.Location, m.public, m.hidden, m.createDate)
+	return fmt.Sprintf("id: %v, userID: %v, Location: %v, public: %v, hidden: %v, creatDate: %v, expiresAt: %v, releaseDate: %v, capacity: %v", m.momentID, m.userID, m.Location, m.public, m.hidden, m.createDate, m.expiresAt, m.releaseDate, m.capacity)
 }
 
-var ErrorLocationIsNil = errors.New("l *Location is nil")
+var ErrorLocationIsNil = invalid("location", "l *Location is nil")
 
 func (m *MomentsRow) setLocation(l *Location) {
 	if m.err != nil {
@@ -446,6 +607,7 @@ func (m *MomentsRow) setLocation(l *Location) {
 		return
 	}
 	m.Location = *l
+	m.cell = cellOf(*l, cellPrecision)
 	return
 }
 
@@ -470,9 +632,9 @@ func (m *MomentsRow) setUserID(u string) {
 	return
 }
 
-var ErrorMediaDNE = errors.New("m.mType is set to DNE, therefore m.dir must remain empty.")
-var ErrorMediaExistsDirDNE = errors.New("m.mType is not DNE, therefore m.dir must be set.")
-var ErrorMessageLong = errors.New("m must be >= " + strconv.Itoa(minMessage) + " AND <= " + strconv.Itoa(maxMessage) + ".")
+var ErrorMediaDNE = invalid("dir", "m.mType is set to DNE, therefore m.dir must remain empty.")
+var ErrorMediaExistsDirDNE = invalid("dir", "m.mType is not DNE, therefore m.dir must be set.")
+var ErrorMessageLong = invalid("message", "m must be >= "+strconv.Itoa(minMessage)+" AND <= "+strconv.Itoa(maxMessage)+".")
 
 // NewMedia is a constructor for the MediaRow struct.
 func (mc *MomentClient) NewMediaRow(mID int64, m string, mType uint8, d string) (mr *MediaRow) {
@@ -556,11 +718,12 @@ func (mr *MediaRow) setMomentID(mID int64) {
 	return
 }
 
-var ErrorFoundEmptyFindDate = errors.New("fr.found=true, therefore fr.findDate must not be empty")
-var ErrorNotFoundFindDateExists = errors.New("fr.found=false, therefore fr.findDate must be empty.")
+var ErrorFoundEmptyFindDate = invalid("findDate", "fr.found=true, therefore fr.findDate must not be empty")
+var ErrorNotFoundFindDateExists = invalid("findDate", "fr.found=false, therefore fr.findDate must be empty.")
 
-// NewFind is a constructor for the FindsRow struct
-func (mc *MomentClient) NewFindsRow(mID int64, uID string, f bool, fd *time.Time) (fr *FindsRow) {
+// NewFind is a constructor for the FindsRow struct. a is where a find is attempted from and is
+// nil on the finds of the recipients of a private moment.
+func (mc *MomentClient) NewFindsRow(mID int64, uID string, f bool, fd *time.Time, a *FindAttempt) (fr *FindsRow) {
 	if mc.err != nil {
 		return
 	}
@@ -577,6 +740,7 @@ func (mc *MomentClient) NewFindsRow(mID int64, uID string, f bool, fd *time.Time
 	}
 
 	fr.found = f
+	fr.attempt = a
 
 	emptyTime := time.Time{}
 	if fr.found && *fr.findDate == emptyTime {
@@ -599,16 +763,19 @@ type FindsRow struct {
 	uID
 	found    bool
 	findDate *time.Time
-	err      error
+	// attempt is where the find was attempted from, or nil until it is found.
+	attempt *FindAttempt
+	err     error
 }
 
 // String returns the string representation of FindsRow
 func (f FindsRow) String() string {
-	return fmt.Sprintf("momentID: %v, userID: %v, found: %v, findDate: %v",
+	return fmt.Sprintf("momentID: %v, userID: %v, found: %v, findDate: %v, attempt: %v",
 		f.momentID,
 		f.userID,
 		f.found,
-		f.findDate)
+		f.findDate,
+		f.attempt)
 }
 
 func (f *FindsRow) isFound() error {
@@ -711,8 +878,8 @@ type RecipientsRow struct {
 	err         error
 }
 
-var ErrorAllRecipientExists = errors.New("s.all=true, therefore s.recipientID must be \"\"")
-var ErrorNotAllRecipientDNE = errors.New("s.all=false, therefore s.recipientID must be set")
+var ErrorAllRecipientExists = invalid("recipientID", "s.all=true, therefore s.recipientID must be \"\"")
+var ErrorNotAllRecipientDNE = invalid("recipientID", "s.all=false, therefore s.recipientID must be set")
 
 func (mc *MomentClient) NewRecipientsRow(sharesID int64, all bool, recipientID string) (r *RecipientsRow) {
 	if mc.err != nil {
@@ -756,8 +923,8 @@ func (r *RecipientsRow) setRecipientID(u string) {
 	r.recipientID = u
 }
 
-var ErrorLatitude = errors.New("Latitude must be between -180 and 180.")
-var ErrorLongitude = errors.New("Longitude must be between -90 and 90.")
+var ErrorLatitude = invalid("latitude", "Latitude must be between -90 and 90.")
+var ErrorLongitude = invalid("longitude", "Longitude must be between -180 and 180.")
 
 // NewLocation is a constructor for the Location struct.
 func (mc *MomentClient) NewLocation(lat float32, long float32) (l *Location) {
@@ -852,7 +1019,7 @@ func (s *sID) setSharesID(id int64) (err error) {
 	return
 }
 
-var ErrorTimePtrNil = errors.New("t *time.Time is set to nil")
+var ErrorTimePtrNil = invalid("", "t *time.Time is set to nil")
 
 // checkTime ensures that the value of t is a valid address.
 func checkTime(t *time.Time) (err error) {
@@ -862,7 +1029,7 @@ func checkTime(t *time.Time) (err error) {
 	return
 }
 
-var ErrorMomentID = errors.New("momentID invalid")
+var ErrorMomentID = invalid("momentID", "momentID invalid")
 
 // checkMomentID ensures that id is greater 0.
 func checkMomentID(id int64) (err error) {
@@ -872,7 +1039,7 @@ func checkMomentID(id int64) (err error) {
 	return
 }
 
-var ErrorMediaTypeDNE = errors.New("*t must be >= " + strconv.Itoa(minMediaType) + " AND <= " + strconv.Itoa(maxMediaType))
+var ErrorMediaTypeDNE = invalid("type", "*t must be >= "+strconv.Itoa(minMediaType)+" AND <= "+strconv.Itoa(maxMediaType))
 
 // checkMediaType ensures that t is less than maxMediaType.
 func checkMediaType(t uint8) (err error) {
@@ -883,8 +1050,8 @@ func checkMediaType(t uint8) (err error) {
 }
 
 var (
-	ErrorUserIDShort = errors.New("len(*id) (userID) must be >= " + strconv.Itoa(minUserChars) + ".")
-	ErrorUserIDLong  = errors.New("len(*id) (userID) must be <= " + strconv.Itoa(maxUserChars) + ".")
+	ErrorUserIDShort = invalid("userID", "len(*id) (userID) must be >= "+strconv.Itoa(minUserChars)+".")
+	ErrorUserIDLong  = invalid("userID", "len(*id) (userID) must be <= "+strconv.Itoa(maxUserChars)+".")
 )
 
 // checkUserID ensures that the length of id is between minUserChars and maxUserChars.
@@ -914,7 +1081,7 @@ func checkUserIDShort(id string) (err error) {
 	return
 }
 
-var ErrorSharesID = errors.New("sharesID invalid")
+var ErrorSharesID = invalid("sharesID", "sharesID invalid")
 
 // checkSharesID ensures that the id is greater than 0, and returns ErrorSharesID on error.
 func checkSharesID(id int64) (err error) {
@@ -924,7 +1091,7 @@ func checkSharesID(id int64) (err error) {
 	return
 }
 
-var ErrorFoundFalseFindDateNil = errors.New("A found row must have f.found=true and f.findDate=*time.Time{}")
+var ErrorFoundFalseFindDateNil = invalid("findDate", "A found row must have f.found=true and f.findDate=*time.Time{}")
 
 const (
 	momentsAlias    = "m"
@@ -1017,6 +1184,14 @@ type Moment struct {
 	media      []*MediaRow
 	finds      []*FindsRow
 	shares     []*SharesRow
+	// heading is set on the moments of a location selector.
+	heading *heading
+	// releaseDate is set on the time capsules of UserLeft and UserPending.
+	releaseDate *time.Time
+	// capacity and claimed are set on the public moments of a location selector that can only
+	// be found by their first capacity finders, of whom claimed have found them.
+	capacity *int
+	claimed  int
 }
 
 func (m Moment) String() string {
@@ -1045,28 +1220,44 @@ func (m Moment) String() string {
 	return s
 }
 
+// Client runs the statements of a Store on the DbRunner passed to each of its methods.
 type Client interface {
 	LocationSelector
 	UserSelector
 	Modifier
+	Builder
+}
+
+// Builder constructs the rows passed to a Store. Once a constructor rejects its input every
+// following constructor returns nil and Err returns the error.
+type Builder interface {
 	Newer
 	Err() error
 }
 
+// LocationSelector selects the moments within a radius in meters of a Location one Page at a
+// time. A radius of 0 selects the moments within DefaultRadius.
 type LocationSelector interface {
-	LocationShared(DbRunner, *Location, string) ([]*Moment, error)
-	LocationPublic(DbRunner, *Location) ([]*Moment, error)
-	LocationHidden(DbRunner, *Location) ([]*Moment, error)
-	LocationLost(DbRunner, *Location, string) ([]*Moment, error)
+	LocationShared(context.Context, DbRunner, *Location, float64, string, *PageRequest) (*Page, error)
+	LocationPublic(context.Context, DbRunner, *Location, float64, *PageRequest) (*Page, error)
+	LocationHidden(context.Context, DbRunner, *Location, float64, *PageRequest) (*Page, error)
+	LocationLost(context.Context, DbRunner, *Location, float64, string, *PageRequest) (*Page, error)
 }
 
-func (mc *MomentClient) LocationShared(db DbRunner, l *Location, me string) ([]*Moment, error) {
+func (mc *MomentClient) LocationShared(ctx context.Context, db DbRunner, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
 	if l == nil || me == "" {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(l, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
@@ -1082,20 +1273,32 @@ func (mc *MomentClient) LocationShared(db DbRunner, l *Location, me string) ([]*
 		Join(schMedia+" "+mediaAlias+" ON "+mdMomentID+" = "+miD).
 		Join(schShares+" "+sharesAlias+" ON "+sMomentID+" = "+miD).
 		Join(schRecipients+" "+recipientsAlias+" ON "+rSharesID+" = "+siD).
-		Where(mLat+" BETWEEN ? AND ?", l.latitude-1, l.latitude+1).
-		Where(mLong+" BETWEEN ? AND ?", l.longitude-1, l.longitude+1).
-		Where("("+rRecipientID+" = ? OR "+rAll+" = 1)", me)
+		Where("("+rRecipientID+" = ? OR "+rAll+" = "+d.Bool(true)+")", me).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)).
+		Where(released(now)), d)
 
-	return mc.selectMoments(db, query)
+	rs, err := mc.selectMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
-func (mc *MomentClient) LocationPublic(db DbRunner, l *Location) ([]*Moment, error) {
+func (mc *MomentClient) LocationPublic(ctx context.Context, db DbRunner, l *Location, radius float64, pr *PageRequest) (*Page, error) {
 	if l == nil {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(l, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
@@ -1104,72 +1307,117 @@ func (mc *MomentClient) LocationPublic(db DbRunner, l *Location) ([]*Moment, err
 			mdType,
 			mdDir,
 			mCreateDate,
-			mUserID).
+			mUserID,
+			mCapacity,
+			mClaimed).
 		From(schMoments+" "+momentsAlias).
 		Join(schMedia+" "+mediaAlias+" ON "+mdMomentID+" = "+miD).
-		Where(mLat+" BETWEEN ? AND ?", l.latitude-1, l.latitude+1).
-		Where(mLong+" BETWEEN ? AND ?", l.longitude-1, l.longitude+1).
-		Where(mPublic + " = true").
-		Where(mHidden + " = false")
-
-	return mc.selectPublicMoments(db, query)
+		Where(mPublic+" = "+d.Bool(true)).
+		Where(mHidden+" = "+d.Bool(false)).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)).
+		Where(released(now)).
+		Where(notFull()), d)
+
+	rs, err := mc.selectPublicMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
-func (mc *MomentClient) LocationHidden(db DbRunner, l *Location) ([]*Moment, error) {
+func (mc *MomentClient) LocationHidden(ctx context.Context, db DbRunner, l *Location, radius float64, pr *PageRequest) (*Page, error) {
 	if l == nil {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(l, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
-			mLong).
+			mLong,
+			mCapacity,
+			mClaimed).
 		From(schMoments+" "+momentsAlias).
-		Where(mLat+" BETWEEN ? AND ?", l.latitude-1, l.latitude+1).
-		Where(mLong+" BETWEEN ? AND ?", l.longitude-1, l.longitude+1).
-		Where(mPublic + " = true").
-		Where(mHidden + " = true")
-
-	return mc.selectLostMoments(db, query)
+		Where(mPublic+" = "+d.Bool(true)).
+		Where(mHidden+" = "+d.Bool(true)).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)).
+		Where(released(now)).
+		Where(notFull()), d)
+
+	rs, err := mc.selectLostMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
-func (mc *MomentClient) LocationLost(db DbRunner, l *Location, me string) ([]*Moment, error) {
+func (mc *MomentClient) LocationLost(ctx context.Context, db DbRunner, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
 	if l == nil || me == "" {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(l, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
-			mLong).
+			mLong,
+			mCapacity,
+			mClaimed).
 		From(schMoments+" "+momentsAlias).
 		Join(schFinds+" "+findsAlias+" ON "+fMomentID+" = "+miD).
-		Where(mLat+" BETWEEN ? AND ?", l.latitude-1, l.latitude+1).
-		Where(mLong+" BETWEEN ? AND ?", l.longitude-1, l.longitude+1).
-		Where(mPublic+" = false").
-		Where(mHidden+" = false").
-		Where(fUserID+" = ?", me)
+		Where(mPublic+" = "+d.Bool(false)).
+		Where(mHidden+" = "+d.Bool(false)).
+		Where(fUserID+" = ?", me).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)).
+		Where(released(now)), d)
 
-	return mc.selectLostMoments(db, query)
+	rs, err := mc.selectLostMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
+// UserSelector selects the moments of a user one Page at a time. It cannot sort by distance.
 type UserSelector interface {
-	UserShared(DbRunner, string, string) ([]*Moment, error)
-	UserLeft(DbRunner, string) ([]*Moment, error)
-	UserFound(DbRunner, string) ([]*Moment, error)
+	UserShared(context.Context, DbRunner, string, string, *PageRequest) (*Page, error)
+	UserLeft(context.Context, DbRunner, string, *PageRequest) (*Page, error)
+	UserFound(context.Context, DbRunner, string, *PageRequest) (*Page, error)
+	UserPending(context.Context, DbRunner, string, *PageRequest) (*Page, error)
 }
 
-func (mc *MomentClient) UserShared(db DbRunner, you string, me string) ([]*Moment, error) {
+func (mc *MomentClient) UserShared(ctx context.Context, db DbRunner, you string, me string, pr *PageRequest) (*Page, error) {
 	if me == "" || you == "" {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
@@ -1186,17 +1434,30 @@ func (mc *MomentClient) UserShared(db DbRunner, you string, me string) ([]*Momen
 		Join(schShares+" "+sharesAlias+" ON "+sMomentID+" = "+miD).
 		Join(schRecipients+" "+recipientsAlias+" ON "+rSharesID+" = "+siD).
 		Where(sUserID+" = ?", you).
-		Where("("+rRecipientID+" = ? OR "+rAll+" = true)", me)
+		Where("("+rRecipientID+" = ? OR "+rAll+" = "+d.Bool(true)+")", me).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(time.Now().UTC())), d)
 
-	return mc.selectMoments(db, query)
+	rs, err := mc.selectMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
-func (mc *MomentClient) UserLeft(db DbRunner, me string) (rs []*Moment, err error) {
+func (mc *MomentClient) UserLeft(ctx context.Context, db DbRunner, me string, pr *PageRequest) (*Page, error) {
 	if me == "" {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	now := time.Now().UTC()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
@@ -1208,22 +1469,36 @@ func (mc *MomentClient) UserLeft(db DbRunner, me string) (rs []*Moment, err erro
 			mPublic,
 			mHidden,
 			fUserID,
-			fFindDate).
+			fFindDate,
+			mReleaseDate).
 		From(schMoments+" "+momentsAlias).
 		Join(schMedia+" "+mediaAlias+" ON "+mdMomentID+" = "+miD).
-		Join(schFinds+" "+findsAlias+" ON "+fMomentID+" = "+miD).
-		Where(mUserID+" = ?", me)
+		LeftJoin(schFinds+" "+findsAlias+" ON "+fMomentID+" = "+miD).
+		Where(mUserID+" = ?", me).
+		Where(sq.Or{sq.NotEq{fUserID: nil}, sq.Gt{mReleaseDate: now}}).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(now)), d)
 
-	return mc.selectLeftMoments(db, query)
+	rs, err := mc.selectLeftMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
-func (mc *MomentClient) UserFound(db DbRunner, me string) ([]*Moment, error) {
+func (mc *MomentClient) UserFound(ctx context.Context, db DbRunner, me string, pr *PageRequest) (*Page, error) {
 	if me == "" {
 		Error.Println(ErrorParameterEmpty)
 		return nil, ErrorParameterEmpty
 	}
 
-	query := sq.
+	p, err := pr.pager(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mc.dialect()
+	query := p.apply(sq.
 		Select(
 			miD,
 			mLat,
@@ -1240,16 +1515,23 @@ func (mc *MomentClient) UserFound(db DbRunner, me string) ([]*Moment, error) {
 		Join(schMedia+" "+mediaAlias+" ON "+mdMomentID+" = "+miD).
 		Join(schFinds+" "+findsAlias+" ON "+fMomentID+" = "+miD).
 		Where(fUserID+" = ?", me).
-		Where(fFound + " = true")
+		Where(fFound+" = "+d.Bool(true)).
+		Where(sq.Eq{mDeletedAt: nil}).
+		Where(notExpired(time.Now().UTC())), d)
 
-	return mc.selectFoundMoments(db, query)
+	rs, err := mc.selectFoundMoments(ctx, db, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.page(rs), nil
 }
 
-func (mc *MomentClient) selectMoments(db DbRunner, query sq.SelectBuilder) (rs []*Moment, err error) {
+func (mc *MomentClient) selectMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
 
-	rows, err := query.RunWith(db).Query()
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer rows.Close()
@@ -1268,6 +1550,7 @@ func (mc *MomentClient) selectMoments(db DbRunner, query sq.SelectBuilder) (rs [
 		&m.userID,
 		&m.public,
 		&m.hidden,
+		p.dest(),
 	}
 
 	rm := make(map[int64]*Moment)
@@ -1278,6 +1561,9 @@ func (mc *MomentClient) selectMoments(db DbRunner, query sq.SelectBuilder) (rs [
 		}
 
 		if r, ok := rm[m.momentID]; !ok {
+			if !p.admit(m.momentID) {
+				break
+			}
 			r = &Moment{
 				momentID:   m.momentID,
 				userID:     m.userID,
@@ -1288,31 +1574,33 @@ func (mc *MomentClient) selectMoments(db DbRunner, query sq.SelectBuilder) (rs [
 				media:      []*MediaRow{&MediaRow{message: md.message, mType: md.mType, dir: md.dir}},
 			}
 			rm[m.momentID] = r
+			rs = append(rs, r)
 		} else {
 			r.media = append(r.media, &MediaRow{message: md.message, mType: md.mType, dir: md.dir})
 		}
 	}
 	if err = rows.Err(); err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 
-	rs = momentMaptoSlice(rm)
-
 	return
 
 }
 
-func (mc *MomentClient) selectPublicMoments(db DbRunner, query sq.SelectBuilder) (rs []*Moment, err error) {
-	rows, err := query.RunWith(db).Query()
+func (mc *MomentClient) selectPublicMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer rows.Close()
 
 	m := new(MomentsRow)
 	md := new(MediaRow)
+	var c int
 	dest := []interface{}{
 		&m.momentID,
 		&m.latitude,
@@ -1322,6 +1610,9 @@ func (mc *MomentClient) selectPublicMoments(db DbRunner, query sq.SelectBuilder)
 		&md.dir,
 		&m.createDate,
 		&m.userID,
+		&m.capacity,
+		&c,
+		p.dest(),
 	}
 
 	rm := make(map[int64]*Moment)
@@ -1332,40 +1623,50 @@ func (mc *MomentClient) selectPublicMoments(db DbRunner, query sq.SelectBuilder)
 		}
 
 		if r, ok := rm[m.momentID]; !ok {
+			if !p.admit(m.momentID) {
+				break
+			}
 			r = &Moment{
 				momentID: m.momentID,
 				userID:   m.userID,
 				Location: Location{latitude: m.latitude, longitude: m.longitude},
 				media:    []*MediaRow{&MediaRow{message: md.message, mType: md.mType, dir: md.dir}},
+				capacity: m.capacity,
+				claimed:  c,
 			}
 			rm[m.momentID] = r
+			rs = append(rs, r)
 		} else {
 			r.media = append(r.media, &MediaRow{message: md.message, mType: md.mType, dir: md.dir})
 		}
 	}
 	if err = rows.Err(); err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 
-	rs = momentMaptoSlice(rm)
-
 	return
 }
 
-func (mc *MomentClient) selectLostMoments(db DbRunner, query sq.SelectBuilder) (rs []*Moment, err error) {
-	rows, err := query.RunWith(db).Query()
+func (mc *MomentClient) selectLostMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer rows.Close()
 
 	m := new(MomentsRow)
+	var c int
 	dest := []interface{}{
 		&m.momentID,
 		&m.latitude,
 		&m.longitude,
+		&m.capacity,
+		&c,
+		p.dest(),
 	}
 
 	rs = make([]*Moment, 0)
@@ -1374,23 +1675,30 @@ func (mc *MomentClient) selectLostMoments(db DbRunner, query sq.SelectBuilder) (
 			Error.Println(err)
 			return
 		}
+		if !p.admit(m.momentID) {
+			break
+		}
 		rs = append(rs,
 			&Moment{
 				momentID: m.momentID,
 				Location: Location{latitude: m.latitude, longitude: m.longitude},
+				capacity: m.capacity,
+				claimed:  c,
 			})
 	}
 	if err = rows.Err(); err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	return
 }
 
-func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (rs []*Moment, err error) {
-	rows, err := query.RunWith(db).Query()
+func (mc *MomentClient) selectLeftMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer rows.Close()
@@ -1398,6 +1706,8 @@ func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (
 	m := new(MomentsRow)
 	md := new(MediaRow)
 	f := new(FindsRow)
+	// A time capsule nobody has been given or found yet has no [Finds] row.
+	var finder sql.NullString
 	dest := []interface{}{
 		&m.momentID,
 		&m.latitude,
@@ -1408,20 +1718,27 @@ func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (
 		&m.createDate,
 		&m.public,
 		&m.hidden,
-		&f.userID,
+		&finder,
 		&f.findDate,
+		&m.releaseDate,
+		p.dest(),
 	}
 
 	var mdMap, fMap map[string]bool
 	rm := make(map[int64]*Moment)
+	now := time.Now()
 
 	for rows.Next() {
 		if err = rows.Scan(dest...); err != nil {
 			Error.Println(err)
 			return
 		}
+		f.userID = finder.String
 
 		if r, ok := rm[m.momentID]; !ok {
+			if !p.admit(m.momentID) {
+				break
+			}
 			r = &Moment{
 				momentID:   m.momentID,
 				userID:     m.userID,
@@ -1430,15 +1747,21 @@ func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (
 				Location:   Location{latitude: m.latitude, longitude: m.longitude},
 				createDate: m.createDate,
 				media:      []*MediaRow{&MediaRow{message: md.message, mType: md.mType, dir: md.dir}},
-				finds:      []*FindsRow{&FindsRow{uID: uID{userID: f.userID}, findDate: f.findDate}},
+			}
+			if m.releaseDate != nil && m.releaseDate.After(now) {
+				r.releaseDate = m.releaseDate
 			}
 			rm[m.momentID] = r
+			rs = append(rs, r)
 
 			mdMap = make(map[string]bool)
 			fMap = make(map[string]bool)
 
 			mdMap[md.dir] = true
-			fMap[f.userID] = true
+			if finder.Valid {
+				r.finds = []*FindsRow{&FindsRow{uID: uID{userID: f.userID}, findDate: f.findDate}}
+				fMap[f.userID] = true
+			}
 
 		} else {
 			if _, ok = mdMap[md.dir]; !ok {
@@ -1446,7 +1769,7 @@ func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (
 				mdMap[md.dir] = true
 			}
 
-			if _, ok = fMap[f.userID]; !ok {
+			if _, ok = fMap[f.userID]; !ok && finder.Valid {
 				r.finds = append(r.finds, &FindsRow{uID: uID{userID: f.userID}, findDate: f.findDate})
 				fMap[f.userID] = true
 			}
@@ -1455,18 +1778,18 @@ func (mc *MomentClient) selectLeftMoments(db DbRunner, query sq.SelectBuilder) (
 	}
 	if err = rows.Err(); err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 
-	rs = momentMaptoSlice(rm)
-
 	return
 }
 
-func (mc *MomentClient) selectFoundMoments(db DbRunner, query sq.SelectBuilder) (rs []*Moment, err error) {
-	rows, err := query.RunWith(db).Query()
+func (mc *MomentClient) selectFoundMoments(ctx context.Context, db DbRunner, query sq.SelectBuilder, p *pager) (rs []*Moment, err error) {
+	rows, err := query.PlaceholderFormat(format{mc.dialect()}).RunWith(db).QueryContext(ctx)
 	if err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 	defer rows.Close()
@@ -1487,6 +1810,7 @@ func (mc *MomentClient) selectFoundMoments(db DbRunner, query sq.SelectBuilder)
 		&m.public,
 		&m.hidden,
 		&f.findDate,
+		p.dest(),
 	}
 
 	rm := make(map[int64]*Moment)
@@ -1497,6 +1821,9 @@ func (mc *MomentClient) selectFoundMoments(db DbRunner, query sq.SelectBuilder)
 		}
 
 		if r, ok := rm[m.momentID]; !ok {
+			if !p.admit(m.momentID) {
+				break
+			}
 			r = &Moment{
 				momentID:   m.momentID,
 				userID:     m.userID,
@@ -1512,6 +1839,7 @@ func (mc *MomentClient) selectFoundMoments(db DbRunner, query sq.SelectBuilder)
 				},
 			}
 			rm[m.momentID] = r
+			rs = append(rs, r)
 		} else {
 			r.media = append(r.media, &MediaRow{message: md.message, mType: md.mType, dir: md.dir})
 		}
@@ -1519,17 +1847,9 @@ func (mc *MomentClient) selectFoundMoments(db DbRunner, query sq.SelectBuilder)
 	}
 	if err = rows.Err(); err != nil {
 		Error.Println(err)
+		err = dbError(ctxError(ctx, err))
 		return
 	}
 
-	rs = momentMaptoSlice(rm)
-
-	return
-}
-
-func momentMaptoSlice(m map[int64]*Moment) (s []*Moment) {
-	for _, v := range m {
-		s = append(s, v)
-	}
 	return
 }
@@ -1,11 +1,12 @@
 package moment
 
 import (
-	// "errors"
-	"database/sql"
+	"context"
+	"errors"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/stretchr/testify/assert"
 	// "math/rand"
+	sqldriver "database/sql/driver"
 	"fmt"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"os"
@@ -32,9 +33,11 @@ func TestMain(m *testing.M) {
 	os.Exit(call)
 }
 
+// TestMomentDB expects DB to fail, since no Moment-Db answers the tests.
 func TestMomentDB(t *testing.T) {
-	db := DB()
-	assert.IsType(t, new(sql.DB), db)
+	db, err := DB(context.Background(), PoolConfig{PingAttempts: 1})
+	assert.NotNil(t, err)
+	assert.Nil(t, db)
 }
 
 func TestCheckTime(t *testing.T) {
@@ -195,15 +198,15 @@ func TestNewFindsRow(t *testing.T) {
 
 	for _, v := range tests {
 		mc := new(MomentClient)
-		_ = mc.NewFindsRow(v.momentID, v.userID, v.found, v.findDate)
+		_ = mc.NewFindsRow(v.momentID, v.userID, v.found, v.findDate, nil)
 		assert.Exactly(t, v.expected, mc.Err())
 	}
 }
 
 func TestFindsRowString(t *testing.T) {
 	mc := new(MomentClient)
-	f := mc.NewFindsRow(1, tUser, false, &time.Time{})
-	expected := fmt.Sprintf("momentID: %v, userID: %v, found: %v, findDate: %v", f.momentID, f.userID, f.found, f.findDate)
+	f := mc.NewFindsRow(1, tUser, false, &time.Time{}, nil)
+	expected := fmt.Sprintf("momentID: %v, userID: %v, found: %v, findDate: %v, attempt: <nil>", f.momentID, f.userID, f.found, f.findDate)
 	actual := f.String()
 	assert.Equal(t, expected, actual)
 }
@@ -237,28 +240,41 @@ func TestSharesRowString(t *testing.T) {
 
 func TestNewMomentsRow(t *testing.T) {
 	type test struct {
-		location   *Location
-		userID     string
-		public     bool
-		hidden     bool
-		createDate *time.Time
-		expected   error
+		location    *Location
+		userID      string
+		public      bool
+		hidden      bool
+		createDate  *time.Time
+		expiresAt   *time.Time
+		releaseDate *time.Time
+		capacity    *int
+		expected    error
 	}
 
 	mc := new(MomentClient)
 	lo := mc.NewLocation(lat, long)
 	cd := time.Now().UTC()
+	soon := cd.Add(24 * time.Hour)
+	later := cd.Add(48 * time.Hour)
+	one, zero := 1, 0
 	tests := []test{
-		test{lo, tUser, true, false, &cd, nil},
-		test{nil, tUser, true, false, &cd, ErrorLocationIsNil},
-		test{lo, tUser, true, false, &cd, nil},
-		test{lo, tUser, false, false, &cd, nil},
-		test{lo, tUser, false, true, &cd, ErrorPrivateHiddenMoment},
+		test{lo, tUser, true, false, &cd, nil, nil, nil, nil},
+		test{nil, tUser, true, false, &cd, nil, nil, nil, ErrorLocationIsNil},
+		test{lo, tUser, true, false, &cd, &later, nil, nil, nil},
+		test{lo, tUser, false, false, &cd, nil, nil, nil, nil},
+		test{lo, tUser, false, true, &cd, nil, nil, nil, ErrorPrivateHiddenMoment},
+		test{lo, tUser, true, false, &cd, &cd, nil, nil, ErrorExpiresAt},
+		test{lo, tUser, false, false, &cd, &later, &soon, nil, nil},
+		test{lo, tUser, false, false, &cd, nil, &cd, nil, ErrorReleaseDate},
+		test{lo, tUser, false, false, &cd, &soon, &later, nil, ErrorReleaseDate},
+		test{lo, tUser, true, true, &cd, nil, nil, &one, nil},
+		test{lo, tUser, true, false, &cd, nil, nil, &zero, ErrorCapacity},
+		test{lo, tUser, false, false, &cd, nil, nil, &one, ErrorCapacity},
 	}
 
 	for _, v := range tests {
 		mc := new(MomentClient)
-		_ = mc.NewMomentsRow(v.location, v.userID, v.public, v.hidden, v.createDate)
+		_ = mc.NewMomentsRow(v.location, v.userID, v.public, v.hidden, v.createDate, v.expiresAt, v.releaseDate, v.capacity)
 		assert.Exactly(t, v.expected, mc.Err())
 	}
 }
@@ -266,8 +282,8 @@ func TestNewMomentsRow(t *testing.T) {
 func TestMomentsRowString(t *testing.T) {
 	mc := new(MomentClient)
 	dt := time.Now().UTC()
-	m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt)
-	expected := fmt.Sprintf("id: %v, userID: %v, Location: %v, public: %v, hidden: %v, creatDate: %v", m.momentID, m.userID, m.Location, m.public, m.hidden, m.createDate)
+	m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil)
+	expected := fmt.Sprintf("id: %v, userID: %v, Location: %v, public: %v, hidden: %v, creatDate: %v, expiresAt: %v, releaseDate: %v, capacity: %v", m.momentID, m.userID, m.Location, m.public, m.hidden, m.createDate, m.expiresAt, m.releaseDate, m.capacity)
 	actual := m.String()
 	assert.Equal(t, expected, actual)
 }
@@ -310,29 +326,38 @@ func TestMomentString(t *testing.T) {
 }
 
 var (
-	MomentsRowRegexpStr = fmt.Sprintf(`^INSERT INTO \%s\.\%s \(\%s,\%s,\%s,\%s,\%s,\%s\) VALUES \(\?,\?,\?,\?,\?,\?\)$`,
+	MomentsRowRegexpStr = fmt.Sprintf(`^INSERT INTO \%s\.\%s \(\%s,\%s,\%s,\%s,\%s,\%s,\%s,\%s,\%s,\%s\) OUTPUT INSERTED\.\%s VALUES \(\?,\?,\?,\?,\?,\?,\?,\?,\?,\?\)$`,
 		momentSchema,
 		moments,
 		userID,
 		latStr,
 		longStr,
+		cellStr,
 		public,
 		hidden,
-		createDate)
+		createDate,
+		expiresAt,
+		releaseDate,
+		capacity,
+		iD)
 
-	FindsRowRegexpStr = fmt.Sprintf(`^INSERT INTO \%s\.\%s \(\%s,\%s,\%s,\%s\) VALUES (\(\?,\?,\?,\?\)(,|$))+`,
+	FindsRowRegexpStr = fmt.Sprintf(`^INSERT INTO \%s\.\%s \(\%s,\%s,\%s,\%s,\%s,\%s,\%s\) VALUES (\(\?,\?,\?,\?,\?,\?,\?\)(,|$))+`,
 		momentSchema,
 		finds,
 		momentID,
 		userID,
 		found,
-		findDate)
+		findDate,
+		findLatitude,
+		findLongitude,
+		findAccuracy)
 
-	SharesRowRegexpStr = fmt.Sprintf(`INSERT INTO \%s\.\%s \(\%s,\%s\) VALUES \(\?,\?\)$`,
+	SharesRowRegexpStr = fmt.Sprintf(`INSERT INTO \%s\.\%s \(\%s,\%s\) OUTPUT INSERTED\.\%s VALUES \(\?,\?\)$`,
 		momentSchema,
 		shares,
 		momentID,
-		userID)
+		userID,
+		iD)
 
 	RecipientsRowRegexpStr = fmt.Sprintf(`INSERT INTO \%s\.\%s \(\%s,\%s,\%s\) VALUES \(\?,\?,\?\)$`,
 		momentSchema,
@@ -357,8 +382,8 @@ func TestFindPublic(t *testing.T) {
 		assert.Nil(t, err)
 
 		mc := new(MomentClient)
-		f := mc.NewFindsRow(1, tUser, false, &time.Time{})
-		_, err = mc.FindPublic(db, f)
+		f := mc.NewFindsRow(1, tUser, false, &time.Time{}, nil)
+		_, err = mc.FindPublic(context.Background(), db, f)
 		assert.Equal(t, ErrorFieldInvalid, err)
 	})
 
@@ -367,19 +392,48 @@ func TestFindPublic(t *testing.T) {
 
 		mc := new(MomentClient)
 		dt := time.Now().UTC()
-		f := mc.NewFindsRow(1, tUser, true, &dt)
+		f := mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long))
 
+		mock.ExpectBegin()
+		expectLive(mock, livePublicRegexp, nil, nil, f.momentID, true)
+		mock.ExpectExec(claimRegexp).
+			WithArgs(f.momentID, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectExec(FindsRowRegexpStr).
-			WithArgs(f.momentID, f.userID, f.found, f.findDate).
+			WithArgs(f.momentID, f.userID, f.found, f.findDate, lat, long, 5.0).
 			WillReturnResult(sqlmock.NewResult(f.momentID, 1))
+		mock.ExpectCommit()
 
-		cnt, err := mc.FindPublic(db, f)
+		cnt, err := mc.FindPublic(context.Background(), db, f)
 		assert.Nil(t, err)
 		assert.Equal(t, int64(1), cnt)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Commit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+
+		mc := new(MomentClient)
+		dt := time.Now().UTC()
+		f := mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long))
+
+		mock.ExpectBegin()
+		expectLive(mock, livePublicRegexp, nil, nil, f.momentID, true)
+		mock.ExpectExec(claimRegexp).
+			WithArgs(f.momentID, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(FindsRowRegexpStr).
+			WithArgs(f.momentID, f.userID, f.found, f.findDate, lat, long, 5.0).
+			WillReturnResult(sqlmock.NewResult(f.momentID, 1))
+		mock.ExpectCommit().WillReturnError(sqldriver.ErrBadConn)
+
+		_, err = mc.FindPublic(context.Background(), db, f)
+		assert.Equal(t, KindUnavailable, KindOf(err))
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestFindPrivate(t *testing.T) {
@@ -388,8 +442,8 @@ func TestFindPrivate(t *testing.T) {
 		assert.Nil(t, err)
 
 		mc := new(MomentClient)
-		f := mc.NewFindsRow(1, tUser, false, &time.Time{})
-		err = mc.FindPrivate(db, f)
+		f := mc.NewFindsRow(1, tUser, false, &time.Time{}, nil)
+		err = mc.FindPrivate(context.Background(), db, f)
 		assert.Equal(t, ErrorFieldInvalid, err)
 	})
 
@@ -398,20 +452,24 @@ func TestFindPrivate(t *testing.T) {
 
 		dt := time.Now().UTC()
 		mc := new(MomentClient)
-		f := mc.NewFindsRow(1, tUser, true, &dt)
+		f := mc.NewFindsRow(1, tUser, true, &dt, tAttempt(lat, long))
 
-		s := fmt.Sprintf(`^UPDATE \%s\.\%s SET \%s = \?, \%s = \? WHERE \%s = \? AND \%s = \?$`,
+		s := fmt.Sprintf(`^UPDATE \%s\.\%s SET \%s = \?, \%s = \?, \%s = \?, \%s = \?, \%s = \? WHERE \%s = \? AND \%s = \?$`,
 			momentSchema,
 			finds,
 			found,
 			findDate,
+			findLatitude,
+			findLongitude,
+			findAccuracy,
 			momentID,
 			userID)
+		expectLive(mock, liveRegexp, nil, nil, f.momentID)
 		mock.ExpectExec(s).
-			WithArgs(f.found, f.findDate, f.momentID, f.userID).
+			WithArgs(f.found, f.findDate, lat, long, 5.0, f.momentID, f.userID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err = mc.FindPrivate(db, f)
+		err = mc.FindPrivate(context.Background(), db, f)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -424,7 +482,7 @@ func TestCreatePrivate(t *testing.T) {
 		assert.Nil(t, err)
 
 		mc := new(MomentClient)
-		err = mc.CreatePrivate(db, nil, nil, nil)
+		err = mc.CreatePrivate(context.Background(), db, nil, nil, nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -435,28 +493,28 @@ func TestCreatePrivate(t *testing.T) {
 		mock.ExpectBegin()
 
 		dt := time.Now().UTC()
-		mock.ExpectExec(MomentsRowRegexpStr).
-			WithArgs(tUser, lat, long, false, false, &dt).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(MomentsRowRegexpStr).
+			WithArgs(tUser, lat, long, "s00000000", false, false, &dt, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
 
 		mock.ExpectExec(MediaRowRegexpStr).
 			WithArgs(1, "Helloworld.", DNE, "").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		mock.ExpectExec(FindsRowRegexpStr).
-			WithArgs(1, tUser2, false, &time.Time{}, 1, tUser3, false, &time.Time{}).
+			WithArgs(1, tUser2, false, &time.Time{}, nil, nil, nil, 1, tUser3, false, &time.Time{}, nil, nil, nil).
 			WillReturnResult(sqlmock.NewResult(0, 2))
 
 		mock.ExpectCommit()
 
 		mc := new(MomentClient)
-		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt)
+		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil)
 		md := mc.NewMediaRow(0, "Helloworld.", DNE, "")
-		f1 := mc.NewFindsRow(0, tUser2, false, &time.Time{})
-		f2 := mc.NewFindsRow(0, tUser3, false, &time.Time{})
+		f1 := mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil)
+		f2 := mc.NewFindsRow(0, tUser3, false, &time.Time{}, nil)
 		assert.Nil(t, mc.Err())
 
-		err = mc.CreatePrivate(db, m, []*MediaRow{md}, []*FindsRow{f1, f2})
+		err = mc.CreatePrivate(context.Background(), db, m, []*MediaRow{md}, []*FindsRow{f1, f2})
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -467,7 +525,7 @@ func TestCreatePublic(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		err = mc.CreatePublic(db, nil, nil)
+		err = mc.CreatePublic(context.Background(), db, nil, nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -477,9 +535,9 @@ func TestCreatePublic(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		mock.ExpectExec(MomentsRowRegexpStr).
-			WithArgs(tUser, lat, long, false, false, &dt).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(MomentsRowRegexpStr).
+			WithArgs(tUser, lat, long, "s00000000", false, false, &dt, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
 
 		mock.ExpectExec(MediaRowRegexpStr).
 			WithArgs(1, "Helloworld.", DNE, "").
@@ -488,13 +546,37 @@ func TestCreatePublic(t *testing.T) {
 		mock.ExpectCommit()
 
 		mc := new(MomentClient)
-		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt)
+		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil)
 		md := mc.NewMediaRow(0, "Helloworld.", DNE, "")
 		assert.Nil(t, mc.Err())
 
-		err = mc.CreatePublic(db, m, []*MediaRow{md})
+		err = mc.CreatePublic(context.Background(), db, m, []*MediaRow{md})
 		assert.Nil(t, err)
 
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+	t.Run("Commit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		dt := time.Now().UTC()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(MomentsRowRegexpStr).
+			WithArgs(tUser, lat, long, "s00000000", false, false, &dt, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
+		mock.ExpectExec(MediaRowRegexpStr).
+			WithArgs(1, "Helloworld.", DNE, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit().WillReturnError(sqldriver.ErrBadConn)
+
+		mc := new(MomentClient)
+		m := mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil)
+		md := mc.NewMediaRow(0, "Helloworld.", DNE, "")
+		assert.Nil(t, mc.Err())
+
+		err = mc.CreatePublic(context.Background(), db, m, []*MediaRow{md})
+		assert.Equal(t, KindUnavailable, KindOf(err))
+
 		assert.Nil(t, mock.ExpectationsWereMet())
 	})
 }
@@ -503,7 +585,7 @@ func TestShare(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		err = mc.Share(db, nil, nil)
+		err = mc.Share(context.Background(), db, nil, nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -512,10 +594,11 @@ func TestShare(t *testing.T) {
 		mc := new(MomentClient)
 
 		mock.ExpectBegin()
+		expectLive(mock, liveRegexp, nil, nil, 1)
 
-		mock.ExpectExec(SharesRowRegexpStr).
+		mock.ExpectQuery(SharesRowRegexpStr).
 			WithArgs(1, tUser).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
 
 		mock.ExpectExec(RecipientsRowRegexpStr).
 			WithArgs(1, false, tUser2).
@@ -528,11 +611,42 @@ func TestShare(t *testing.T) {
 		t.Log(s)
 		t.Log(rs[0])
 
-		err = mc.Share(db, s, rs)
+		err = mc.Share(context.Background(), db, s, rs)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("chunks", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		mc := new(MomentClient)
+
+		rs := tRecipients(mc, tUser2, 1500)
+		assert.Nil(t, mc.Err())
+		chunk := func(rows int) string {
+			return fmt.Sprintf(`^INSERT INTO \%s\.\%s \(\%s,\%s,\%s\) VALUES (\(\?,\?,\?\),){%d}\(\?,\?,\?\)$`,
+				momentSchema, recipients, sharesID, all, recipientID, rows-1)
+		}
+
+		mock.ExpectBegin()
+		expectLive(mock, liveRegexp, nil, nil, 1)
+		mock.ExpectQuery(SharesRowRegexpStr).
+			WithArgs(1, tUser).
+			WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
+		mock.ExpectExec(chunk(699)).WillReturnResult(sqlmock.NewResult(0, 699))
+		mock.ExpectExec(chunk(699)).WillReturnError(errors.New("UNIQUE constraint failed"))
+		mock.ExpectRollback()
+
+		err = mc.Share(context.Background(), db, mc.NewSharesRow(0, 1, tUser), rs)
+		assert.Equal(t, "chunk 2 of 3 failed after 699 rows: UNIQUE constraint failed", err.Error())
+		var ce *ChunkError
+		if assert.True(t, errors.As(err, &ce)) {
+			assert.Equal(t, ChunkError{Chunk: 2, Chunks: 3, Rows: 699, Err: ce.Err}, *ce)
+		}
+		assert.Equal(t, KindConflict, KindOf(err))
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
 }
 
 func Test_insert(t *testing.T) {
@@ -540,16 +654,35 @@ func Test_insert(t *testing.T) {
 	assert.Nil(t, err)
 
 	invalidParameter := 1
-	_, err = insert(db, invalidParameter)
+	_, err = insert(context.Background(), MSSQL, db, invalidParameter)
 	assert.Equal(t, ErrorTypeNotImplemented, err)
 }
 
+func Test_chunkRows(t *testing.T) {
+	type test struct {
+		columns  int
+		expected int
+	}
+	tests := []test{
+		test{1, maxRowValues},
+		test{2, maxRowValues},
+		test{3, 699},
+		test{4, 524},
+		test{8, 262},
+	}
+
+	for _, v := range tests {
+		assert.Equal(t, v.expected, chunkRows(v.columns), "%d columns", v.columns)
+		assert.True(t, v.expected*v.columns+2 <= 2100, "%d columns", v.columns)
+	}
+}
+
 func Test_update(t *testing.T) {
 	db, _, err := sqlmock.New()
 	assert.Nil(t, err)
 
 	invalidParameter := 1
-	err = update(db, invalidParameter)
+	err = update(context.Background(), MSSQL, db, invalidParameter)
 	assert.Equal(t, ErrorTypeNotImplemented, err)
 }
 
@@ -557,7 +690,7 @@ func TestLocationShared(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.LocationShared(db, nil, "")
+		_, err = mc.LocationShared(context.Background(), db, nil, 0, "", nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -576,7 +709,8 @@ func TestLocationShared(t *testing.T) {
 		` + momentsAlias + `\.\` + createDate + `, 
 		` + momentsAlias + `\.\` + userID + `, 
 		` + momentsAlias + `\.\` + public + `, 
-		` + momentsAlias + `\.\` + hidden + ` 
+		` + momentsAlias + `\.\` + hidden + `, 
+		` + havRegexp + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + media + ` ` + mediaAlias + `
 		  ON ` + mediaAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
@@ -584,15 +718,16 @@ func TestLocationShared(t *testing.T) {
 		  ON ` + sharesAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
 		JOIN \` + momentSchema + `\.\` + recipients + ` ` + recipientsAlias + `
 		  ON ` + recipientsAlias + `\.\` + sharesID + ` = ` + sharesAlias + `\.\` + iD + `
-		WHERE ` + momentsAlias + `\.\` + latStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + longStr + ` BETWEEN \? AND \?
-			  AND \(` + recipientsAlias + `\.\` + recipientID + ` = \? OR ` + recipientsAlias + `\.\` + all + ` = 1\)$`)
+		WHERE \(` + recipientsAlias + `\.\` + recipientID + ` = \? OR ` + recipientsAlias + `\.\` + all + ` = 1\)
+			  AND ` + visibleRegexp + `
+			  AND ` + releasedRegexp + `
+			  AND ` + cellsRegexp + nearestRegexp)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
 
-		mock.ExpectQuery(s).WithArgs(lat-1, lat+1, long-1, long+1, tUser).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tNearestArgs(tUser, sqlmock.AnyArg(), sqlmock.AnyArg())...).WillReturnRows(rows)
 
-		_, err = mc.LocationShared(db, mc.NewLocation(lat, long), tUser)
+		_, err = mc.LocationShared(context.Background(), db, mc.NewLocation(lat, long), 0, tUser, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -603,7 +738,7 @@ func TestLocationPublic(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.LocationPublic(db, nil)
+		_, err = mc.LocationPublic(context.Background(), db, nil, 0, nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -620,20 +755,25 @@ func TestLocationPublic(t *testing.T) {
 		` + mediaAlias + `\.\` + mtype + `, 
 		` + mediaAlias + `\.\` + dir + `, 
 		` + momentsAlias + `\.\` + createDate + `, 
-		` + momentsAlias + `\.\` + userID + ` 
+		` + momentsAlias + `\.\` + userID + `, 
+		` + momentsAlias + `\.\` + capacity + `, 
+		` + momentsAlias + `\.\` + claimed + `, 
+		` + havRegexp + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + media + ` ` + mediaAlias + `
 		  ON ` + mediaAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
-		WHERE ` + momentsAlias + `\.\` + latStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + longStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + public + ` = true 
-			  AND ` + momentsAlias + `\.\` + hidden + ` = false$`)
+		WHERE ` + momentsAlias + `\.\` + public + ` = 1 
+			  AND ` + momentsAlias + `\.\` + hidden + ` = 0
+			  AND ` + visibleRegexp + `
+			  AND ` + releasedRegexp + `
+			  AND ` + notFullRegexp + `
+			  AND ` + cellsRegexp + nearestRegexp)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
 
-		mock.ExpectQuery(s).WithArgs(lat-1, lat+1, long-1, long+1).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tNearestArgs(sqlmock.AnyArg(), sqlmock.AnyArg())...).WillReturnRows(rows)
 
-		_, err = mc.LocationPublic(db, mc.NewLocation(lat, long))
+		_, err = mc.LocationPublic(context.Background(), db, mc.NewLocation(lat, long), 0, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -644,7 +784,7 @@ func TestLocationHidden(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.LocationHidden(db, nil)
+		_, err = mc.LocationHidden(context.Background(), db, nil, 0, nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -656,17 +796,22 @@ func TestLocationHidden(t *testing.T) {
 		^SELECT 
 		` + momentsAlias + `\.\` + iD + `, 
 		` + momentsAlias + `\.\` + latStr + `, 
-		` + momentsAlias + `\.\` + longStr + ` 
+		` + momentsAlias + `\.\` + longStr + `, 
+		` + momentsAlias + `\.\` + capacity + `, 
+		` + momentsAlias + `\.\` + claimed + `, 
+		` + havRegexp + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
-		WHERE ` + momentsAlias + `\.\` + latStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + longStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + public + ` = true 
-			  AND ` + momentsAlias + `\.\` + hidden + ` = true$`)
+		WHERE ` + momentsAlias + `\.\` + public + ` = 1 
+			  AND ` + momentsAlias + `\.\` + hidden + ` = 1
+			  AND ` + visibleRegexp + `
+			  AND ` + releasedRegexp + `
+			  AND ` + notFullRegexp + `
+			  AND ` + cellsRegexp + nearestRegexp)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
-		mock.ExpectQuery(s).WithArgs(lat-1, lat+1, long-1, long+1).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tNearestArgs(sqlmock.AnyArg(), sqlmock.AnyArg())...).WillReturnRows(rows)
 
-		_, err = mc.LocationHidden(db, mc.NewLocation(lat, long))
+		_, err = mc.LocationHidden(context.Background(), db, mc.NewLocation(lat, long), 0, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -677,7 +822,7 @@ func TestLocationLost(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.LocationLost(db, nil, "")
+		_, err = mc.LocationLost(context.Background(), db, nil, 0, "", nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -689,20 +834,24 @@ func TestLocationLost(t *testing.T) {
 		^SELECT 
 		` + momentsAlias + `\.\` + iD + `, 
 		` + momentsAlias + `\.\` + latStr + `, 
-		` + momentsAlias + `\.\` + longStr + ` 
+		` + momentsAlias + `\.\` + longStr + `, 
+		` + momentsAlias + `\.\` + capacity + `, 
+		` + momentsAlias + `\.\` + claimed + `, 
+		` + havRegexp + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + finds + ` ` + findsAlias + `
 		  ON ` + findsAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
-		WHERE ` + momentsAlias + `\.\` + latStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + longStr + ` BETWEEN \? AND \?
-			  AND ` + momentsAlias + `\.\` + public + ` = false 
-			  AND ` + momentsAlias + `\.\` + hidden + ` = false 
-			  AND ` + findsAlias + `\.\` + userID + ` = \?$`)
+		WHERE ` + momentsAlias + `\.\` + public + ` = 0 
+			  AND ` + momentsAlias + `\.\` + hidden + ` = 0 
+			  AND ` + findsAlias + `\.\` + userID + ` = \?
+			  AND ` + visibleRegexp + `
+			  AND ` + releasedRegexp + `
+			  AND ` + cellsRegexp + nearestRegexp)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
-		mock.ExpectQuery(s).WithArgs(lat-1, lat+1, long-1, long+1, tUser).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tNearestArgs(tUser, sqlmock.AnyArg(), sqlmock.AnyArg())...).WillReturnRows(rows)
 
-		_, err = mc.LocationLost(db, mc.NewLocation(lat, long), tUser)
+		_, err = mc.LocationLost(context.Background(), db, mc.NewLocation(lat, long), 0, tUser, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -713,7 +862,7 @@ func TestUserShared(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.UserShared(db, "", "")
+		_, err = mc.UserShared(context.Background(), db, "", "", nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -732,7 +881,8 @@ func TestUserShared(t *testing.T) {
 		` + momentsAlias + `\.\` + createDate + `, 
 		` + momentsAlias + `\.\` + userID + `,
 		` + momentsAlias + `\.\` + public + `,
-		` + momentsAlias + `\.\` + hidden + `
+		` + momentsAlias + `\.\` + hidden + `, 
+		` + momentsAlias + `\.\` + createDate + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + media + ` ` + mediaAlias + `
 		  ON ` + mediaAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
@@ -741,12 +891,12 @@ func TestUserShared(t *testing.T) {
 		JOIN \` + momentSchema + `\.\` + recipients + ` ` + recipientsAlias + `
 		  ON ` + recipientsAlias + `\.\` + sharesID + ` = ` + sharesAlias + `\.\` + iD + `
 		WHERE ` + sharesAlias + `\.\` + userID + ` = \?
-			  AND \(` + recipientsAlias + `\.\` + recipientID + ` = \? OR ` + recipientsAlias + `\.\` + all + ` = true\)$`)
+			  AND \(` + recipientsAlias + `\.\` + recipientID + ` = \? OR ` + recipientsAlias + `\.\` + all + ` = 1\) AND ` + visibleRegexp + windowRegexp + ` ORDER BY ` + momentsAlias + `\.\` + createDate + ` DESC, ` + momentsAlias + `\.\` + iD + ` DESC$`)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
-		mock.ExpectQuery(s).WithArgs(tUser, tUser2).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tUser, tUser2, sqlmock.AnyArg(), tUser, tUser2, sqlmock.AnyArg()).WillReturnRows(rows)
 
-		_, err = mc.UserShared(db, tUser, tUser2)
+		_, err = mc.UserShared(context.Background(), db, tUser, tUser2, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -757,7 +907,7 @@ func TestUserLeft(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.UserLeft(db, "")
+		_, err = mc.UserLeft(context.Background(), db, "", nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -777,18 +927,22 @@ func TestUserLeft(t *testing.T) {
 		` + momentsAlias + `\.\` + public + `,
 		` + momentsAlias + `\.\` + hidden + `,
 		` + findsAlias + `\.\` + userID + `,
-		` + findsAlias + `\.\` + findDate + `
+		` + findsAlias + `\.\` + findDate + `, 
+		` + momentsAlias + `\.\` + releaseDate + `, 
+		` + momentsAlias + `\.\` + createDate + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + media + ` ` + mediaAlias + `
 		  ON ` + mediaAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
-		JOIN \` + momentSchema + `\.\` + finds + ` ` + findsAlias + `
+		LEFT JOIN \` + momentSchema + `\.\` + finds + ` ` + findsAlias + `
 		  ON ` + findsAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
-		WHERE ` + momentsAlias + `\.\` + userID + ` = \?$`)
+		WHERE ` + momentsAlias + `\.\` + userID + ` = \?
+			  AND \(` + findsAlias + `\.\` + userID + ` IS NOT NULL OR ` + momentsAlias + `\.\` + releaseDate + ` > \?\)
+			  AND ` + visibleRegexp + windowRegexp + ` ORDER BY ` + momentsAlias + `\.\` + createDate + ` DESC, ` + momentsAlias + `\.\` + iD + ` DESC$`)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
-		mock.ExpectQuery(s).WithArgs(tUser).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tUser, sqlmock.AnyArg(), sqlmock.AnyArg(), tUser, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
 
-		_, err = mc.UserLeft(db, tUser)
+		_, err = mc.UserLeft(context.Background(), db, tUser, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -800,7 +954,7 @@ func TestUserFound(t *testing.T) {
 	t.Run("Parameter Checks", func(t *testing.T) {
 		db, _, err := sqlmock.New()
 		mc := new(MomentClient)
-		_, err = mc.UserFound(db, "")
+		_, err = mc.UserFound(context.Background(), db, "", nil)
 		assert.Equal(t, ErrorParameterEmpty, err)
 	})
 
@@ -820,19 +974,20 @@ func TestUserFound(t *testing.T) {
 		` + momentsAlias + `\.\` + userID + `,
 		` + momentsAlias + `\.\` + public + `,
 		` + momentsAlias + `\.\` + hidden + `,
-		` + findsAlias + `\.\` + findDate + `
+		` + findsAlias + `\.\` + findDate + `, 
+		` + momentsAlias + `\.\` + createDate + ` AS \[SortKey\]
 		FROM \` + momentSchema + `\.\` + moments + ` ` + momentsAlias + `  
 		JOIN \` + momentSchema + `\.\` + media + ` ` + mediaAlias + `
 		  ON ` + mediaAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
 		JOIN \` + momentSchema + `\.\` + finds + ` ` + findsAlias + `
 		  ON ` + findsAlias + `\.\` + momentID + ` = ` + momentsAlias + `\.\` + iD + `
 		WHERE ` + findsAlias + `\.\` + userID + ` = \?
-			  AND ` + findsAlias + `\.\` + found + ` = true$`)
+			  AND ` + findsAlias + `\.\` + found + ` = 1 AND ` + visibleRegexp + windowRegexp + ` ORDER BY ` + momentsAlias + `\.\` + createDate + ` DESC, ` + momentsAlias + `\.\` + iD + ` DESC$`)
 
 		rows := sqlmock.NewRows([]string{"NoColumns"})
-		mock.ExpectQuery(s).WithArgs(tUser).WillReturnRows(rows)
+		mock.ExpectQuery(s).WithArgs(tUser, sqlmock.AnyArg(), tUser, sqlmock.AnyArg()).WillReturnRows(rows)
 
-		_, err = mc.UserFound(db, tUser)
+		_, err = mc.UserFound(context.Background(), db, tUser, nil)
 		assert.Nil(t, err)
 
 		assert.Nil(t, mock.ExpectationsWereMet())
@@ -852,15 +1007,15 @@ func Test_selectMoments(t *testing.T) {
 	query := fakeSelect
 
 	dt := time.Now().UTC()
-	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden}).
-		AddRow(1, lat, long, "Hello there.", DNE, "", &dt, tUser, false, false).
-		AddRow(1, lat, long, "Enjoy this photo.", Image, "D:/ImageDir/image.png", &dt, tUser, false, false).
-		AddRow(2, lat, long, "Where am I? :p", DNE, "", &dt, tUser, true, true)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden, sortKey}).
+		AddRow(1, lat, long, "Hello there.", DNE, "", &dt, tUser, false, false, tDate).
+		AddRow(1, lat, long, "Enjoy this photo.", Image, "D:/ImageDir/image.png", &dt, tUser, false, false, tDate).
+		AddRow(2, lat, long, "Where am I? :p", DNE, "", &dt, tUser, true, true, tDate)
 
 	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
 
 	mc := new(MomentClient)
-	rs, err := mc.selectMoments(db, query)
+	rs, err := mc.selectMoments(context.Background(), db, query, tPager(t))
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(rs))
 
@@ -872,17 +1027,19 @@ func Test_selectPublicMoments(t *testing.T) {
 	assert.Nil(t, err)
 
 	dt := time.Now().UTC()
-	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID}).
-		AddRow(1, lat, long, "message 1", DNE, "", &dt, tUser).
-		AddRow(1, lat, long, "message 2", DNE, "", &dt, tUser).
-		AddRow(2, lat, long, "message 3", DNE, "", &dt, tUser)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, capacity, claimed, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", &dt, tUser, nil, 0, tDate).
+		AddRow(1, lat, long, "message 2", DNE, "", &dt, tUser, nil, 0, tDate).
+		AddRow(2, lat, long, "message 3", DNE, "", &dt, tUser, 5, 2, tDate)
 
 	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
 
 	mc := new(MomentClient)
-	rs, err := mc.selectPublicMoments(db, fakeSelect)
+	rs, err := mc.selectPublicMoments(context.Background(), db, fakeSelect, tPager(t))
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(rs))
+	assert.Nil(t, rs[0].remaining())
+	assert.Equal(t, 3, *rs[1].remaining())
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -890,15 +1047,15 @@ func Test_selectLostMoments(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.Nil(t, err)
 
-	rows := sqlmock.NewRows([]string{iD, latStr, longStr}).
-		AddRow(1, lat, long).
-		AddRow(2, lat, long).
-		AddRow(3, lat, long)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, capacity, claimed, sortKey}).
+		AddRow(1, lat, long, nil, 0, tDate).
+		AddRow(2, lat, long, nil, 0, tDate).
+		AddRow(3, lat, long, nil, 0, tDate)
 
 	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
 
 	mc := new(MomentClient)
-	rs, err := mc.selectLostMoments(db, fakeSelect)
+	rs, err := mc.selectLostMoments(context.Background(), db, fakeSelect, tPager(t))
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(rs))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -909,19 +1066,25 @@ func Test_selectLeftMoments(t *testing.T) {
 	assert.Nil(t, err)
 
 	dt := time.Now().UTC()
-	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, public, hidden, userID, findDate}).
-		AddRow(1, lat, long, "message 1", DNE, "", &dt, false, false, tUser2, &dt).
-		AddRow(2, lat, long, "message 2", DNE, "", &dt, false, false, tUser2, &dt).
-		AddRow(2, lat, long, "message 3", Image, "D:/Image/image.png", &dt, false, false, tUser2, &dt).
-		AddRow(3, lat, long, "message 4", DNE, "", &dt, false, false, tUser2, &dt).
-		AddRow(3, lat, long, "message 4", DNE, "", &dt, false, false, tUser3, &dt)
+	release := dt.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, public, hidden, userID, findDate, releaseDate, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", &dt, false, false, tUser2, &dt, nil, tDate).
+		AddRow(2, lat, long, "message 2", DNE, "", &dt, false, false, tUser2, &dt, nil, tDate).
+		AddRow(2, lat, long, "message 3", Image, "D:/Image/image.png", &dt, false, false, tUser2, &dt, nil, tDate).
+		AddRow(3, lat, long, "message 4", DNE, "", &dt, false, false, tUser2, &dt, nil, tDate).
+		AddRow(3, lat, long, "message 4", DNE, "", &dt, false, false, tUser3, &dt, nil, tDate).
+		AddRow(4, lat, long, "message 5", DNE, "", &dt, true, false, nil, nil, &release, tDate)
 
 	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
 
 	mc := new(MomentClient)
-	rs, err := mc.selectLeftMoments(db, fakeSelect)
+	rs, err := mc.selectLeftMoments(context.Background(), db, fakeSelect, tPager(t))
 	assert.Nil(t, err)
-	assert.Equal(t, 3, len(rs))
+	assert.Equal(t, 4, len(rs))
+	assert.Equal(t, 2, len(rs[2].finds))
+	assert.Nil(t, rs[2].releaseDate)
+	assert.Empty(t, rs[3].finds)
+	assert.True(t, release.Equal(*rs[3].releaseDate))
 
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
@@ -931,17 +1094,17 @@ func Test_selectFoundMoments(t *testing.T) {
 	assert.Nil(t, err)
 
 	dt := time.Now().UTC()
-	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden, findDate}).
-		AddRow(1, lat, long, "message 1", DNE, "", &dt, tUser, false, false, &dt).
-		AddRow(2, lat, long, "message 2", DNE, "", &dt, tUser, false, false, &dt).
-		AddRow(2, lat, long, "message 3", Image, "D:/Image/image.png", &dt, tUser, false, false, &dt).
-		AddRow(3, lat, long, "message 4", DNE, "", &dt, tUser, true, true, &dt).
-		AddRow(3, lat, long, "message 5", DNE, "", &dt, tUser, true, true, &dt)
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, public, hidden, findDate, sortKey}).
+		AddRow(1, lat, long, "message 1", DNE, "", &dt, tUser, false, false, &dt, tDate).
+		AddRow(2, lat, long, "message 2", DNE, "", &dt, tUser, false, false, &dt, tDate).
+		AddRow(2, lat, long, "message 3", Image, "D:/Image/image.png", &dt, tUser, false, false, &dt, tDate).
+		AddRow(3, lat, long, "message 4", DNE, "", &dt, tUser, true, true, &dt, tDate).
+		AddRow(3, lat, long, "message 5", DNE, "", &dt, tUser, true, true, &dt, tDate)
 
 	mock.ExpectQuery(fakeSelectRegexp).WillReturnRows(rows)
 
 	mc := new(MomentClient)
-	rs, err := mc.selectFoundMoments(db, fakeSelect)
+	rs, err := mc.selectFoundMoments(context.Background(), db, fakeSelect, tPager(t))
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(rs))
 
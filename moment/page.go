@@ -0,0 +1,296 @@
+package moment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"math"
+	"time"
+)
+
+// Sort orders the moments returned by a LocationSelector or UserSelector.
+type Sort string
+
+const (
+	// SortCreateDate returns the newest moments first.
+	SortCreateDate Sort = "createDate"
+	// SortDistance returns the moments nearest to the selector's Location first. It is the
+	// default of location selectors.
+	SortDistance Sort = "distance"
+
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+
+	sortKey = "[SortKey]"
+
+	// havSQL is the hav from the Location of havArgs to the moment of a selector.
+	havSQL = "(POWER(SIN(RADIANS(" + mLat + " - ?) / 2), 2) + ? * COS(RADIANS(" + mLat + ")) * POWER(SIN(RADIANS(" + mLong + " - ?) / 2), 2))"
+)
+
+var (
+	ErrorPageLimit    = invalid("limit", fmt.Sprintf("Limit must be between 1 and %d.", MaxPageLimit))
+	ErrorPageSort     = invalid("sort", "Sort must be createDate or distance.")
+	ErrorSortDistance = invalid("sort", "Only location selectors can sort by distance.")
+	ErrorCursor       = invalid("cursor", "Cursor is malformed or was issued for a different sort.")
+)
+
+// PageRequest asks a selector for at most limit moments in sort order, starting after the
+// moment a previous Page's Next cursor points at.
+type PageRequest struct {
+	limit int
+	sort  Sort
+	after *cursor
+}
+
+// Page is one page of a selector's moments. Next is empty on the last page and is otherwise
+// the opaque cursor of the following page.
+type Page struct {
+	Moments []*Moment
+	Next    string
+}
+
+// cursor is the keyset position of the last moment of a Page.
+type cursor struct {
+	Sort       Sort       `json:"s"`
+	CreateDate *time.Time `json:"d,omitempty"`
+	Hav        float64    `json:"k,omitempty"` // hav from the Location of the selector
+	ID         int64      `json:"id"`
+}
+
+func (c *cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrorCursor
+	}
+	c := new(cursor)
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, ErrorCursor
+	}
+	return c, nil
+}
+
+// NewPageRequest is a constructor for the PageRequest struct.
+// A limit of 0 requests DefaultPageLimit moments and an empty sort requests the default sort of
+// the selector: SortDistance for location selectors and SortCreateDate for the others.
+func (mc *MomentClient) NewPageRequest(limit int, c string, s Sort) (pr *PageRequest) {
+	if mc.err != nil {
+		return
+	}
+
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		Error.Println(ErrorPageLimit)
+		mc.err = ErrorPageLimit
+		return
+	}
+
+	if s != "" && s != SortCreateDate && s != SortDistance {
+		Error.Println(ErrorPageSort)
+		mc.err = ErrorPageSort
+		return
+	}
+
+	pr = &PageRequest{limit: limit, sort: s}
+	if c == "" {
+		return
+	}
+	pr.after, mc.err = decodeCursor(c)
+	if mc.err != nil || (s != "" && pr.after.Sort != s) || (pr.after.Sort == SortCreateDate && pr.after.CreateDate == nil) {
+		Error.Println(ErrorCursor)
+		mc.err = ErrorCursor
+		return nil
+	}
+	return
+}
+
+// pager applies a PageRequest to a selector query and cuts the moments it returns into a Page.
+//
+// A query returns at most one moment more than the limit, in the order of the Page. A location
+// selector narrows its query to the cells that cover its circle, or to the boxes of the circle
+// if no cells cover it, so that Moment-Db can use an index, and then to the circle by hav.
+type pager struct {
+	PageRequest
+	origin *Location
+	radius float64
+	cells  []cellRange
+	bounds []bounds
+
+	keyDate *time.Time
+	keyHav  float64
+
+	count int
+	last  cursor
+	more  bool
+}
+
+// pager returns the pager of pr for a selector within radius meters of origin. A nil pr
+// requests the first page in the default order, a radius of 0 requests DefaultRadius and a nil
+// origin rejects SortDistance.
+func (pr *PageRequest) pager(origin *Location, radius float64) (*pager, error) {
+	p := &pager{PageRequest: PageRequest{limit: DefaultPageLimit}, origin: origin, radius: radius}
+	if pr != nil {
+		p.PageRequest = *pr
+	}
+	if p.sort == "" {
+		p.sort = SortCreateDate
+		if origin != nil {
+			p.sort = SortDistance
+		}
+	}
+	if p.sort == SortDistance && origin == nil {
+		Error.Println(ErrorSortDistance)
+		return nil, ErrorSortDistance
+	}
+	if p.after != nil && p.after.Sort != p.sort {
+		Error.Println(ErrorCursor)
+		return nil, ErrorCursor
+	}
+
+	if origin != nil {
+		if p.radius == 0 {
+			p.radius = DefaultRadius
+		}
+		if p.radius < 0 || p.radius > MaxRadius {
+			Error.Println(ErrorRadius)
+			return nil, ErrorRadius
+		}
+		if p.cells = cover(*origin, p.radius); p.cells == nil {
+			p.bounds = boxes(*origin, p.radius)
+		}
+	}
+	return p, nil
+}
+
+// key returns the keyset position of moment id, created at d at l, in the order of p.
+func (p *pager) key(id int64, d *time.Time, l Location) *cursor {
+	c := &cursor{Sort: p.sort, ID: id}
+	if p.sort == SortDistance {
+		c.Hav = hav(*p.origin, l)
+	} else {
+		c.CreateDate = d
+	}
+	return c
+}
+
+// before reports whether c precedes o in the order of a Page.
+func (c *cursor) before(o *cursor) bool {
+	if c.Sort == SortDistance {
+		if c.Hav != o.Hav {
+			return c.Hav < o.Hav
+		}
+		return c.ID < o.ID
+	}
+	if !c.CreateDate.Equal(*o.CreateDate) {
+		return c.CreateDate.After(*o.CreateDate)
+	}
+	return c.ID > o.ID
+}
+
+// apply selects the sort key as the last column of query, restricts query to p's circle and
+// orders it. It also restricts query to the limit+1 moments after the last moment of the previous
+// page, which d selects by ID as query may return a row per medium of a moment.
+func (p *pager) apply(query sq.SelectBuilder, d Dialect) sq.SelectBuilder {
+	if p.cells != nil {
+		query = query.Where(cellPredicate(p.cells))
+	} else if p.bounds != nil {
+		query = query.Where(boxPredicate(p.bounds))
+	}
+	if p.origin != nil {
+		query = query.Where(havSQL+" <= ?", append(havArgs(*p.origin), havOf(p.radius))...)
+	}
+
+	if p.sort == SortDistance {
+		if p.after != nil {
+			query = query.Where(sq.Or{
+				sq.Expr(havSQL+" > ?", append(havArgs(*p.origin), p.after.Hav)...),
+				sq.And{sq.Expr(havSQL+" = ?", append(havArgs(*p.origin), p.after.Hav)...), sq.Gt{miD: p.after.ID}},
+			})
+		}
+		ids := d.Limit(query.
+			RemoveColumns().
+			Column(miD).
+			GroupBy(miD, mLat, mLong).
+			OrderByClause(havSQL+" ASC", havArgs(*p.origin)...).
+			OrderBy(miD+" ASC"), uint64(p.limit+1))
+		return query.
+			Column(havSQL+" AS "+sortKey, havArgs(*p.origin)...).
+			Where(sq.Expr(miD+" IN (?)", ids)).
+			OrderBy(sortKey+" ASC", miD+" ASC")
+	}
+
+	if p.after != nil {
+		query = query.Where(sq.Or{
+			sq.Lt{mCreateDate: *p.after.CreateDate},
+			sq.And{sq.Eq{mCreateDate: *p.after.CreateDate}, sq.Lt{miD: p.after.ID}},
+		})
+	}
+	ids := d.Limit(query.
+		RemoveColumns().
+		Column(miD).
+		GroupBy(miD, mCreateDate).
+		OrderBy(mCreateDate+" DESC", miD+" DESC"), uint64(p.limit+1))
+	return query.
+		Column(mCreateDate+" AS "+sortKey).
+		Where(sq.Expr(miD+" IN (?)", ids)).
+		OrderBy(mCreateDate+" DESC", miD+" DESC")
+}
+
+// havArgs returns the arguments of havSQL for o.
+func havArgs(o Location) []interface{} {
+	return []interface{}{float64(o.latitude), math.Cos(radians(o.latitude)), float64(o.longitude)}
+}
+
+// within reports whether l lies within p's circle.
+func (p *pager) within(l Location) bool {
+	return p.origin == nil || hav(*p.origin, l) <= havOf(p.radius)
+}
+
+// dest returns the scan destination of the column added by apply.
+func (p *pager) dest() interface{} {
+	if p.sort == SortDistance {
+		return &p.keyHav
+	}
+	return &p.keyDate
+}
+
+// admit is called with the ID of every new moment in row order. It records the moment as the
+// last of the page and returns true, or sets more and returns false once the page is full.
+func (p *pager) admit(id int64) bool {
+	if p.count == p.limit {
+		p.more = true
+		return false
+	}
+	p.count++
+	p.last = cursor{Sort: p.sort, ID: id}
+	if p.sort == SortDistance {
+		p.last.Hav = p.keyHav
+	} else if p.keyDate != nil {
+		d := *p.keyDate
+		p.last.CreateDate = &d
+	}
+	return true
+}
+
+// page returns rs as a Page and sets the heading of every moment of a location selector. Next
+// is set if moments were left out.
+func (p *pager) page(rs []*Moment) *Page {
+	if p.origin != nil {
+		for _, m := range rs {
+			m.heading = &heading{haversine(*p.origin, m.Location), bearing(*p.origin, m.Location)}
+		}
+	}
+
+	pg := &Page{Moments: rs}
+	if p.more {
+		pg.Next = p.last.encode()
+	}
+	return pg
+}
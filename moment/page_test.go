@@ -0,0 +1,347 @@
+package moment
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// windowRegexp matches the predicate by which a SortCreateDate selector of MSSQL reads at most
+// one moment more than the limit.
+const windowRegexp = ` AND m\.\[ID\] IN \(SELECT TOP \d+ m\.\[ID\] FROM .* GROUP BY m\.\[ID\], m\.\[CreateDate\] ` +
+	`ORDER BY m\.\[CreateDate\] DESC, m\.\[ID\] DESC\)`
+
+const (
+	// havRegexp matches havSQL.
+	havRegexp = `\(POWER\(SIN\(RADIANS\(m\.\[Latitude\] - \?\) / 2\), 2\) \+ \? \* COS\(RADIANS\(m\.\[Latitude\]\)\) \* ` +
+		`POWER\(SIN\(RADIANS\(m\.\[Longitude\] - \?\) / 2\), 2\)\)`
+	// nearestRegexp matches the predicates and order by which a SortDistance selector of MSSQL
+	// reads the at most 51 moments within its circle nearest to it.
+	nearestRegexp = ` AND ` + havRegexp + ` <= \? AND m\.\[ID\] IN \(SELECT TOP 51 m\.\[ID\] FROM .* ` +
+		`GROUP BY m\.\[ID\], m\.\[Latitude\], m\.\[Longitude\] ORDER BY ` + havRegexp + ` ASC, m\.\[ID\] ASC\) ` +
+		`ORDER BY \[SortKey\] ASC, m\.\[ID\] ASC$`
+)
+
+// tNearestArgs returns the arguments of the first page of a SortDistance selector of MSSQL
+// around (lat, long) whose predicates before its cells take the arguments where.
+func tNearestArgs(where ...sqldriver.Value) []sqldriver.Value {
+	hav := []sqldriver.Value{sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()}
+	filter := append(append(append(where, tCells()...), hav...), havOf(DefaultRadius))
+	args := append(append([]sqldriver.Value{}, hav...), filter...)
+	return append(append(args, filter...), hav...)
+}
+
+// tPager returns the pager of the first page in the default order.
+func tPager(t *testing.T) *pager {
+	p, err := (*PageRequest)(nil).pager(nil, 0)
+	assert.Nil(t, err)
+	return p
+}
+
+func fakeBase() sq.SelectBuilder {
+	return sq.Select(miD).From("t")
+}
+
+func TestNewPageRequest(t *testing.T) {
+	d := tDate
+	dateCursor := (&cursor{Sort: SortCreateDate, CreateDate: &d, ID: 3}).encode()
+	distCursor := (&cursor{Sort: SortDistance, Hav: 1e-9, ID: 3}).encode()
+
+	type test struct {
+		limit    int
+		cursor   string
+		sort     Sort
+		expected error
+	}
+	tests := []test{
+		test{0, "", "", nil},
+		test{MaxPageLimit, "", SortDistance, nil},
+		test{-1, "", "", ErrorPageLimit},
+		test{MaxPageLimit + 1, "", "", ErrorPageLimit},
+		test{10, "", "popularity", ErrorPageSort},
+		test{10, dateCursor, SortCreateDate, nil},
+		test{10, distCursor, SortDistance, nil},
+		test{10, distCursor, SortCreateDate, ErrorCursor},
+		test{10, distCursor, "", nil},
+		test{10, (&cursor{Sort: SortCreateDate, ID: 3}).encode(), SortCreateDate, ErrorCursor},
+		test{10, "not a cursor", SortCreateDate, ErrorCursor},
+	}
+
+	for _, v := range tests {
+		mc := new(MomentClient)
+		pr := mc.NewPageRequest(v.limit, v.cursor, v.sort)
+		assert.Exactly(t, v.expected, mc.Err())
+		if v.expected == nil {
+			assert.NotNil(t, pr)
+		}
+	}
+
+	mc := new(MomentClient)
+	pr := mc.NewPageRequest(0, "", "")
+	assert.Equal(t, &PageRequest{limit: DefaultPageLimit}, pr)
+}
+
+func TestPager(t *testing.T) {
+	mc := new(MomentClient)
+	l := mc.NewLocation(1, 2)
+	d := tDate
+
+	type test struct {
+		name     string
+		pr       *PageRequest
+		origin   *Location
+		radius   float64
+		sort     Sort
+		expected error
+	}
+	tests := []test{
+		test{"user default", nil, nil, 0, SortCreateDate, nil},
+		test{"location default", nil, l, 0, SortDistance, nil},
+		test{"location by date", &PageRequest{limit: 2, sort: SortCreateDate}, l, 0, SortCreateDate, nil},
+		test{"user by distance", &PageRequest{limit: 2, sort: SortDistance}, nil, 0, "", ErrorSortDistance},
+		test{"default cursor", &PageRequest{limit: 2, after: &cursor{Sort: SortCreateDate, CreateDate: &d, ID: 3}}, l, 0, "", ErrorCursor},
+		test{"max radius", nil, l, MaxRadius, SortDistance, nil},
+		test{"negative radius", nil, l, -1, "", ErrorRadius},
+		test{"radius too large", nil, l, MaxRadius + 1, "", ErrorRadius},
+	}
+
+	for _, v := range tests {
+		p, err := v.pr.pager(v.origin, v.radius)
+		assert.Exactly(t, v.expected, err, v.name)
+		if v.expected == nil {
+			assert.Equal(t, v.sort, p.sort, v.name)
+		}
+	}
+
+	p, err := (*PageRequest)(nil).pager(l, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(DefaultRadius), p.radius)
+}
+
+func TestPagerApply(t *testing.T) {
+	mc := new(MomentClient)
+	l := mc.NewLocation(1, 2)
+	cells, cellArgs, err := cellPredicate(cover(*l, DefaultRadius)).ToSql()
+	assert.Nil(t, err)
+	d := tDate
+
+	window := func(top string, where string, limit string) string {
+		return "m.[ID] IN (SELECT " + top + "m.[ID] FROM t " + where +
+			"GROUP BY m.[ID], m.[CreateDate] ORDER BY m.[CreateDate] DESC, m.[ID] DESC" + limit + ")"
+	}
+	keyset := "(m.[CreateDate] < ? OR (m.[CreateDate] = ? AND m.[ID] < ?))"
+
+	circle := havSQL + " <= ?"
+	circleArgs := append(havArgs(*l), havOf(DefaultRadius))
+	filter, filterArgs := cells+" AND "+circle, append(append([]interface{}{}, cellArgs...), circleArgs...)
+	nearest := func(top string, where string, limit string) string {
+		return "m.[ID] IN (SELECT " + top + "m.[ID] FROM t " + where +
+			"GROUP BY m.[ID], m.[Latitude], m.[Longitude] ORDER BY " + havSQL + " ASC, m.[ID] ASC" + limit + ")"
+	}
+	distKeyset := "(" + havSQL + " > ? OR (" + havSQL + " = ? AND m.[ID] > ?))"
+	distKeysetArgs := append(append(havArgs(*l), 1e-9), append(havArgs(*l), 1e-9, int64(3))...)
+	// distArgs returns the arguments of a SortDistance query with the predicates of args.
+	distArgs := func(args ...interface{}) []interface{} {
+		return append(append(append(havArgs(*l), args...), args...), havArgs(*l)...)
+	}
+
+	type test struct {
+		pr           *PageRequest
+		origin       *Location
+		dialect      Dialect
+		expectedSQL  string
+		expectedArgs []interface{}
+	}
+	tests := []test{
+		test{
+			nil,
+			nil,
+			MSSQL,
+			"SELECT m.[ID], m.[CreateDate] AS [SortKey] FROM t WHERE " + window("TOP 51 ", "", "") +
+				" ORDER BY m.[CreateDate] DESC, m.[ID] DESC",
+			nil,
+		},
+		test{
+			&PageRequest{limit: 2, sort: SortCreateDate, after: &cursor{Sort: SortCreateDate, CreateDate: &d, ID: 3}},
+			nil,
+			MSSQL,
+			"SELECT m.[ID], m.[CreateDate] AS [SortKey] FROM t WHERE " + keyset + " " +
+				"AND " + window("TOP 3 ", "WHERE "+keyset+" ", "") + " ORDER BY m.[CreateDate] DESC, m.[ID] DESC",
+			[]interface{}{d, d, int64(3), d, d, int64(3)},
+		},
+		test{
+			&PageRequest{limit: 2, sort: SortCreateDate, after: &cursor{Sort: SortCreateDate, CreateDate: &d, ID: 3}},
+			nil,
+			PostgreSQL,
+			"SELECT m.[ID], m.[CreateDate] AS [SortKey] FROM t WHERE " + keyset + " " +
+				"AND " + window("", "WHERE "+keyset+" ", " LIMIT 3") + " ORDER BY m.[CreateDate] DESC, m.[ID] DESC",
+			[]interface{}{d, d, int64(3), d, d, int64(3)},
+		},
+		test{
+			&PageRequest{limit: 2, sort: SortCreateDate, after: &cursor{Sort: SortCreateDate, CreateDate: &d, ID: 3}},
+			l,
+			MSSQL,
+			"SELECT m.[ID], m.[CreateDate] AS [SortKey] FROM t " +
+				"WHERE " + filter + " AND " + keyset + " " +
+				"AND " + window("TOP 3 ", "WHERE "+filter+" AND "+keyset+" ", "") + " ORDER BY m.[CreateDate] DESC, m.[ID] DESC",
+			append(append(append([]interface{}{}, filterArgs...), d, d, int64(3)), append(filterArgs, d, d, int64(3))...),
+		},
+		test{
+			nil,
+			l,
+			MSSQL,
+			"SELECT m.[ID], " + havSQL + " AS [SortKey] FROM t " +
+				"WHERE " + filter + " " +
+				"AND " + nearest("TOP 51 ", "WHERE "+filter+" ", "") + " ORDER BY [SortKey] ASC, m.[ID] ASC",
+			distArgs(filterArgs...),
+		},
+		test{
+			&PageRequest{limit: 2, sort: SortDistance, after: &cursor{Sort: SortDistance, Hav: 1e-9, ID: 3}},
+			l,
+			MSSQL,
+			"SELECT m.[ID], " + havSQL + " AS [SortKey] FROM t " +
+				"WHERE " + filter + " AND " + distKeyset + " " +
+				"AND " + nearest("TOP 3 ", "WHERE "+filter+" AND "+distKeyset+" ", "") + " ORDER BY [SortKey] ASC, m.[ID] ASC",
+			distArgs(append(append([]interface{}{}, filterArgs...), distKeysetArgs...)...),
+		},
+		test{
+			&PageRequest{limit: 2, sort: SortDistance, after: &cursor{Sort: SortDistance, Hav: 1e-9, ID: 3}},
+			l,
+			PostgreSQL,
+			"SELECT m.[ID], " + havSQL + " AS [SortKey] FROM t " +
+				"WHERE " + filter + " AND " + distKeyset + " " +
+				"AND " + nearest("", "WHERE "+filter+" AND "+distKeyset+" ", " LIMIT 3") + " ORDER BY [SortKey] ASC, m.[ID] ASC",
+			distArgs(append(append([]interface{}{}, filterArgs...), distKeysetArgs...)...),
+		},
+	}
+
+	assert.Regexp(t, "^"+havRegexp+"$", havSQL)
+	for _, v := range tests {
+		p, err := v.pr.pager(v.origin, 0)
+		assert.Nil(t, err)
+
+		sql, args, err := p.apply(fakeBase(), v.dialect).ToSql()
+		assert.Nil(t, err)
+		assert.Equal(t, v.expectedSQL, sql)
+		assert.Equal(t, v.expectedArgs, args)
+	}
+
+	// A circle that no cells cover is bounded by its boxes.
+	p, err := (*PageRequest)(nil).pager(l, 0)
+	assert.Nil(t, err)
+	p.cells, p.bounds = nil, boxes(*l, DefaultRadius)
+	sql, _, err := p.apply(fakeBase(), MSSQL).ToSql()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(sql, "SELECT m.[ID], "+havSQL+" AS [SortKey] FROM t "+
+		"WHERE ((m.[Latitude] >= ? AND m.[Latitude] <= ? AND m.[Longitude] >= ? AND m.[Longitude] <= ?)) AND "+circle+" AND m.[ID] IN (SELECT TOP 51 "), sql)
+	assert.True(t, p.within(*l))
+	assert.False(t, p.within(Location{latitude: 1.01, longitude: 2}))
+}
+
+// TestPagerDistance pages through ms as a SortDistance selector of Moment-Db would, which
+// reads the moments within the circle after the cursor in the order of hav and ID.
+func TestPagerDistance(t *testing.T) {
+	origin := Location{latitude: 0, longitude: 0}
+	// ms lie ~222, 0, ~111, ~111 and ~2000 meters from origin; 3 and 4 are equally far.
+	ms := []*Moment{
+		&Moment{momentID: 1, Location: Location{latitude: 0.002, longitude: 0}},
+		&Moment{momentID: 2, Location: Location{latitude: 0, longitude: 0}},
+		&Moment{momentID: 3, Location: Location{latitude: 0, longitude: 0.001}},
+		&Moment{momentID: 4, Location: Location{latitude: -0.001, longitude: 0}},
+		&Moment{momentID: 5, Location: Location{latitude: 0.018, longitude: 0}},
+	}
+
+	var ids []int64
+	var next string
+	for i := 0; i < 4; i++ {
+		mc := new(MomentClient)
+		p, err := mc.NewPageRequest(2, next, "").pager(&origin, 0)
+		assert.Nil(t, err)
+
+		var ks []*cursor
+		for _, m := range ms {
+			k := p.key(m.momentID, nil, m.Location)
+			if p.within(m.Location) && (p.after == nil || p.after.before(k)) {
+				ks = append(ks, k)
+			}
+		}
+		sort.Slice(ks, func(i, j int) bool { return ks[i].before(ks[j]) })
+
+		var rs []*Moment
+		for _, k := range ks {
+			p.keyHav = k.Hav
+			if !p.admit(k.ID) {
+				break
+			}
+			c := *ms[k.ID-1]
+			rs = append(rs, &c)
+		}
+		pg := p.page(rs)
+		assert.True(t, len(pg.Moments) == 2 || pg.Next == "", "only the last page is short")
+		for _, m := range pg.Moments {
+			ids = append(ids, m.momentID)
+			assert.NotNil(t, m.heading)
+		}
+		if next = pg.Next; next == "" {
+			break
+		}
+	}
+	assert.Equal(t, []int64{2, 3, 4, 1}, ids, "moment 5 lies outside DefaultRadius")
+
+	p, err := (*PageRequest)(nil).pager(&origin, 0)
+	assert.Nil(t, err)
+	pg := p.page([]*Moment{&Moment{momentID: 3, Location: Location{latitude: 0, longitude: 0.001}}})
+	assert.InDelta(t, 111.2, pg.Moments[0].heading.distance, 0.1)
+	assert.InDelta(t, 90, pg.Moments[0].heading.bearing, 1e-6)
+}
+
+func TestSelectPaged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	d1 := tDate.Add(2 * time.Hour)
+	d2 := tDate.Add(time.Hour)
+	d3 := tDate
+	rows := sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, capacity, claimed, sortKey}).
+		AddRow(7, lat, long, "message 1", DNE, "", d1, tUser, nil, 0, d1).
+		AddRow(7, lat, long, "message 2", DNE, "", d1, tUser, nil, 0, d1).
+		AddRow(5, lat, long, "message 3", DNE, "", d2, tUser, nil, 0, d2).
+		AddRow(6, lat, long, "message 4", DNE, "", d3, tUser, nil, 0, d3).
+		AddRow(6, lat, long, "message 5", DNE, "", d3, tUser, nil, 0, d3)
+	mock.ExpectQuery(".*").WillReturnRows(rows)
+
+	mc := new(MomentClient)
+	pg, err := mc.LocationPublic(context.Background(), db, mc.NewLocation(lat, long), 0, mc.NewPageRequest(2, "", SortCreateDate))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pg.Moments))
+	assert.Equal(t, int64(7), pg.Moments[0].momentID)
+	assert.Equal(t, 2, len(pg.Moments[0].media))
+	assert.Equal(t, int64(5), pg.Moments[1].momentID)
+
+	c, err := decodeCursor(pg.Next)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), c.ID)
+	assert.True(t, d2.Equal(*c.CreateDate))
+
+	args := append(append([]sqldriver.Value{sqlmock.AnyArg(), sqlmock.AnyArg()}, tCells()...),
+		sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), havOf(DefaultRadius), d2, d2, int64(5))
+	mock.ExpectQuery(`AND \(m\.\[CreateDate\] < \? OR \(m\.\[CreateDate\] = \? AND m\.\[ID\] < \?\)\)` + windowRegexp + ` ORDER BY`).
+		WithArgs(append(args, args...)...).
+		WillReturnRows(sqlmock.NewRows([]string{iD, latStr, longStr, message, mtype, dir, createDate, userID, capacity, claimed, sortKey}).
+			AddRow(6, lat, long, "message 4", DNE, "", d3, tUser, nil, 0, d3))
+
+	pg, err = mc.LocationPublic(context.Background(), db, mc.NewLocation(lat, long), 0, mc.NewPageRequest(2, pg.Next, SortCreateDate))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pg.Moments))
+	assert.Empty(t, pg.Next)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	_, err = mc.UserLeft(context.Background(), db, tUser, mc.NewPageRequest(2, "", SortDistance))
+	assert.Exactly(t, ErrorSortDistance, err)
+}
@@ -0,0 +1,137 @@
+package moment
+
+import (
+	"context"
+)
+
+var (
+	ErrorShareForbidden       = forbidden("momentID", "Only the author or a finder of a moment may share it.")
+	ErrorFindPrivateForbidden = forbidden("momentID", "Only a recipient of a private moment may find it.")
+	ErrorUserLeftForbidden    = forbidden("userID", "Only the owner may list the moments they left.")
+	ErrorUserFoundForbidden   = forbidden("userID", "Only the owner may list the moments they found.")
+	ErrorUserPendingForbidden = forbidden("userID", "Only the owner may list the time capsules waiting for them.")
+	ErrorEditForbidden        = forbidden("momentID", "Only the author of a moment may edit it.")
+	ErrorDeleteForbidden      = forbidden("momentID", "Only the author of a moment may delete or restore it.")
+)
+
+// Authorizer decides whether caller may perform an operation of a Store.
+// Every method returns nil when the operation is allowed and a KindForbidden DomainError when it
+// is not, except AuthorizeFindPublic, which reports a private moment as ErrorMomentDNE so that
+// callers who are not its recipients cannot tell that it exists.
+type Authorizer interface {
+	AuthorizeShare(ctx context.Context, s Store, caller string, momentID int64) error
+	AuthorizeFindPublic(ctx context.Context, s Store, caller string, momentID int64) error
+	AuthorizeFindPrivate(ctx context.Context, s Store, caller string, momentID int64) error
+	AuthorizeUserLeft(caller string, owner string) error
+	AuthorizeUserFound(caller string, owner string) error
+	AuthorizeUserPending(caller string, owner string) error
+	AuthorizeEdit(ctx context.Context, s Store, caller string, momentID int64) error
+	AuthorizeDelete(ctx context.Context, s Store, caller string, momentID int64) error
+}
+
+// Policy is the Authorizer that asks a Store for the facts its rules depend on. Its rules are:
+//
+//	Share        the caller is the author of the moment or has found it.
+//	FindPublic   the moment is public.
+//	FindPrivate  the caller is one of the moment's [Finds] recipients.
+//	UserLeft     the caller is the owner of the listed moments.
+//	UserFound    the caller is the owner of the listed finds.
+//	UserPending  the caller is the owner of the listed time capsules.
+//	Edit         the caller is the author of the moment.
+//	Delete       the caller is the author of the moment, also to restore it.
+type Policy struct{}
+
+// AuthorizeShare allows the author of a moment and anyone who found it to share it.
+func (p *Policy) AuthorizeShare(ctx context.Context, s Store, caller string, id int64) error {
+	author, err := s.IsAuthor(ctx, caller, id)
+	if err != nil || author {
+		return err
+	}
+
+	finder, err := s.HasFound(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	if !finder {
+		Error.Println(ErrorShareForbidden)
+		return ErrorShareForbidden
+	}
+	return nil
+}
+
+// AuthorizeFindPublic allows anyone to find a public moment. A private moment is found by its
+// recipients through AuthorizeFindPrivate, so to anyone else it does not exist.
+func (p *Policy) AuthorizeFindPublic(ctx context.Context, s Store, caller string, id int64) error {
+	public, err := s.IsPublic(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !public {
+		Error.Println(ErrorMomentDNE)
+		return ErrorMomentDNE
+	}
+	return nil
+}
+
+// AuthorizeFindPrivate allows only the recipients listed in [Finds] to find a private moment.
+func (p *Policy) AuthorizeFindPrivate(ctx context.Context, s Store, caller string, id int64) error {
+	recipient, err := s.IsRecipient(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	if !recipient {
+		Error.Println(ErrorFindPrivateForbidden)
+		return ErrorFindPrivateForbidden
+	}
+	return nil
+}
+
+// AuthorizeUserLeft allows only owner to list the moments owner left.
+func (p *Policy) AuthorizeUserLeft(caller string, owner string) error {
+	if caller == "" || caller != owner {
+		Error.Println(ErrorUserLeftForbidden)
+		return ErrorUserLeftForbidden
+	}
+	return nil
+}
+
+// AuthorizeUserFound allows only owner to list the moments owner found.
+func (p *Policy) AuthorizeUserFound(caller string, owner string) error {
+	if caller == "" || caller != owner {
+		Error.Println(ErrorUserFoundForbidden)
+		return ErrorUserFoundForbidden
+	}
+	return nil
+}
+
+// AuthorizeUserPending allows only owner to list the time capsules waiting for owner.
+func (p *Policy) AuthorizeUserPending(caller string, owner string) error {
+	if caller == "" || caller != owner {
+		Error.Println(ErrorUserPendingForbidden)
+		return ErrorUserPendingForbidden
+	}
+	return nil
+}
+
+// AuthorizeEdit allows only the author of a moment to edit it.
+func (p *Policy) AuthorizeEdit(ctx context.Context, s Store, caller string, id int64) error {
+	return authorizeAuthor(ctx, s, caller, id, ErrorEditForbidden)
+}
+
+// AuthorizeDelete allows only the author of a moment to delete or restore it.
+func (p *Policy) AuthorizeDelete(ctx context.Context, s Store, caller string, id int64) error {
+	return authorizeAuthor(ctx, s, caller, id, ErrorDeleteForbidden)
+}
+
+// authorizeAuthor returns forbidden unless caller is the author of moment id.
+func authorizeAuthor(ctx context.Context, s Store, caller string, id int64, forbidden error) error {
+	author, err := s.IsAuthor(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	if !author {
+		Error.Println(forbidden)
+		return forbidden
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package moment
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+)
+
+var (
+	authorRegexp    = `^SELECT 1 FROM \[moment\]\.\[Moments\] WHERE \[ID\] = \? AND \[UserID\] = \?$`
+	finderRegexp    = `^SELECT 1 FROM \[moment\]\.\[Finds\] WHERE \[Found\] = \? AND \[MomentID\] = \? AND \[UserID\] = \?$`
+	recipientRegexp = `^SELECT 1 FROM \[moment\]\.\[Finds\] WHERE \[MomentID\] = \? AND \[UserID\] = \?$`
+	publicRegexp    = `^SELECT 1 FROM \[moment\]\.\[Moments\] WHERE \[ID\] = \? AND \[Public\] = \?$`
+)
+
+// expectExists queues a query for regexp with args that returns a row when ok is true, or fails with err.
+func expectExists(mock sqlmock.Sqlmock, regexp string, ok bool, err error, args ...sqldriver.Value) {
+	q := mock.ExpectQuery(regexp).WithArgs(args...)
+	if err != nil {
+		q.WillReturnError(err)
+		return
+	}
+	rows := sqlmock.NewRows([]string{"1"})
+	if ok {
+		rows.AddRow(1)
+	}
+	q.WillReturnRows(rows)
+}
+
+func TestPolicyAuthorizeShare(t *testing.T) {
+	type test struct {
+		name     string
+		author   bool
+		finder   bool
+		dbErr    error
+		expected error
+	}
+	tests := []test{
+		test{"author", true, false, nil, nil},
+		test{"finder", false, true, nil, nil},
+		test{"stranger", false, false, nil, ErrorShareForbidden},
+		test{"unavailable", false, false, netError{}, ErrorUnavailable},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+
+			expectExists(mock, authorRegexp, v.author, v.dbErr, 1, tUser)
+			if !v.author && v.dbErr == nil {
+				expectExists(mock, finderRegexp, v.finder, nil, true, 1, tUser)
+			}
+
+			p := new(Policy)
+			err = p.AuthorizeShare(context.Background(), NewSQLStore(db, MSSQL), tUser, 1)
+			assert.True(t, errors.Is(err, v.expected), "%v", err)
+			if v.expected == ErrorShareForbidden {
+				assert.True(t, errors.Is(err, ErrorForbidden))
+			}
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPolicyAuthorizeFindPublic(t *testing.T) {
+	type test struct {
+		name     string
+		public   bool
+		expected error
+	}
+	tests := []test{
+		test{"public", true, nil},
+		test{"private", false, ErrorMomentDNE},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+
+			expectExists(mock, publicRegexp, v.public, nil, 1, true)
+
+			p := new(Policy)
+			err = p.AuthorizeFindPublic(context.Background(), NewSQLStore(db, MSSQL), tUser, 1)
+			assert.Exactly(t, v.expected, err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPolicyAuthorizeFindPrivate(t *testing.T) {
+	type test struct {
+		name      string
+		recipient bool
+		expected  error
+	}
+	tests := []test{
+		test{"recipient", true, nil},
+		test{"not a recipient", false, ErrorFindPrivateForbidden},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+
+			expectExists(mock, recipientRegexp, v.recipient, nil, 1, tUser)
+
+			p := new(Policy)
+			err = p.AuthorizeFindPrivate(context.Background(), NewSQLStore(db, MSSQL), tUser, 1)
+			assert.Exactly(t, v.expected, err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPolicyAuthorizeAuthor(t *testing.T) {
+	type test struct {
+		name     string
+		author   bool
+		expected error
+	}
+	tests := []test{
+		test{"author", true, nil},
+		test{"not the author", false, ErrorEditForbidden},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+
+			expectExists(mock, authorRegexp, v.author, nil, 1, tUser)
+			expectExists(mock, authorRegexp, v.author, nil, 1, tUser)
+
+			p := new(Policy)
+			s := NewSQLStore(db, MSSQL)
+			assert.Exactly(t, v.expected, p.AuthorizeEdit(context.Background(), s, tUser, 1))
+			err = p.AuthorizeDelete(context.Background(), s, tUser, 1)
+			if v.expected == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.Exactly(t, ErrorDeleteForbidden, err)
+			}
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPolicyAuthorizeUser(t *testing.T) {
+	type test struct {
+		caller   string
+		owner    string
+		expected bool
+	}
+	tests := []test{
+		test{tUser, tUser, true},
+		test{tUser, tUser2, false},
+		test{"", "", false},
+	}
+
+	p := new(Policy)
+	for _, v := range tests {
+		left := p.AuthorizeUserLeft(v.caller, v.owner)
+		found := p.AuthorizeUserFound(v.caller, v.owner)
+		pending := p.AuthorizeUserPending(v.caller, v.owner)
+		if v.expected {
+			assert.Nil(t, left)
+			assert.Nil(t, found)
+			assert.Nil(t, pending)
+			continue
+		}
+		assert.Exactly(t, ErrorUserLeftForbidden, left)
+		assert.Exactly(t, ErrorUserFoundForbidden, found)
+		assert.Exactly(t, ErrorUserPendingForbidden, pending)
+		assert.Equal(t, KindForbidden, KindOf(left))
+	}
+}
@@ -0,0 +1,83 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PoolConfig tunes the connection pool of a *sql.DB. Zero limits and lifetimes are unlimited,
+// as in database/sql.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// PingAttempts is how many times Open pings the database before it gives up. It waits
+	// PingBackoff after the first failed ping and twice as long after each one that follows.
+	PingAttempts int
+	PingBackoff  time.Duration
+}
+
+// DefaultPoolConfig is the PoolConfig of the service when the environment configures none.
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+	PingAttempts:    5,
+	PingBackoff:     500 * time.Millisecond,
+}
+
+// DB opens the connection pool of the Moment-Db named by MomentDBConnStr. Call it once at
+// startup and share the *sql.DB it returns.
+func DB(ctx context.Context, c PoolConfig) (*sql.DB, error) {
+	return Open(ctx, driver, connStr, c)
+}
+
+// Open opens a connection pool with driverName, applies c to it and pings the database until
+// it answers, so that a wrong dsn or an unreachable database fails at startup.
+func Open(ctx context.Context, driverName string, dsn string, c PoolConfig) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+
+	if err = ping(ctx, db, c.PingAttempts, c.PingBackoff); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type pinger interface {
+	PingContext(context.Context) error
+}
+
+// ping pings db up to attempts times, doubling backoff after every failed ping.
+func ping(ctx context.Context, db pinger, attempts int, backoff time.Duration) (err error) {
+	for i := 1; ; i++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		Error.Println(err)
+		if i >= attempts {
+			return dbError(ctxError(ctx, err))
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return dbError(ctx.Err())
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
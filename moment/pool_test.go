@@ -0,0 +1,74 @@
+package moment
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// flakyPinger fails its first fails pings.
+type flakyPinger struct {
+	fails int
+	pings int
+}
+
+func (p *flakyPinger) PingContext(context.Context) error {
+	p.pings++
+	if p.pings <= p.fails {
+		return errors.New("dial tcp: connection refused")
+	}
+	return nil
+}
+
+func Test_ping(t *testing.T) {
+	type test struct {
+		name     string
+		fails    int
+		attempts int
+		pings    int
+		ok       bool
+	}
+	tests := []test{
+		test{"first ping", 0, 3, 1, true},
+		test{"after retries", 2, 3, 3, true},
+		test{"out of attempts", 3, 3, 3, false},
+		test{"no retries", 1, 1, 1, false},
+	}
+
+	for _, v := range tests {
+		p := &flakyPinger{fails: v.fails}
+		err := ping(context.Background(), p, v.attempts, time.Millisecond)
+		assert.Equal(t, v.ok, err == nil, v.name)
+		assert.Equal(t, v.pings, p.pings, v.name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	p := &flakyPinger{fails: 100}
+	err := ping(ctx, p, 100, time.Hour)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, errors.Is(err, ErrorUnavailable))
+	assert.Equal(t, 1, p.pings)
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	c := PoolConfig{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute, PingAttempts: 1}
+
+	db, err := Open(ctx, SQLiteDriver, "file:"+filepath.Join(t.TempDir(), "moment.db"), c)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, db.Stats().MaxOpenConnections)
+	assert.Nil(t, db.Close())
+
+	db, err = Open(ctx, "oracle", "", c)
+	assert.NotNil(t, err)
+	assert.Nil(t, db)
+
+	db, err = OpenSQLite(ctx, filepath.Join(t.TempDir(), "moment.db"), c)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+	assert.Nil(t, db.Close())
+}
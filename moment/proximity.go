@@ -0,0 +1,106 @@
+package moment
+
+import (
+	"fmt"
+)
+
+const (
+	findLatitude  = "[FindLatitude]"
+	findLongitude = "[FindLongitude]"
+	findAccuracy  = "[FindAccuracy]"
+
+	// DefaultFindRadius is how far in meters from a moment a find may be attempted until a
+	// Store is given another radius by SetFindRadius.
+	DefaultFindRadius = 100
+	// MaxFindAccuracy is the largest accuracy in meters of the GPS fix a find may be attempted with.
+	MaxFindAccuracy = 100
+)
+
+var (
+	ErrorFindAccuracy       = invalid("accuracy", fmt.Sprintf("Accuracy must be between 0 and %d meters.", MaxFindAccuracy))
+	ErrorFindRadius         = invalid("findRadius", fmt.Sprintf("The find radius must be more than 0 and at most %d meters.", MaxRadius))
+	ErrorFindAttemptMissing = invalid("location", "A find must carry the location it was attempted from.")
+	ErrorFindTooFar         = forbidden("location", "The moment is too far away to be found.")
+)
+
+// Proximity is implemented by the Stores that reject a find attempted too far from its moment.
+type Proximity interface {
+	// SetFindRadius sets how far in meters from a moment a find may be attempted.
+	SetFindRadius(meters float64) error
+}
+
+// FindAttempt is where a find was attempted from: the Location of the finder and the accuracy
+// in meters of its GPS fix.
+type FindAttempt struct {
+	Location
+	accuracy float64
+}
+
+// NewFindAttempt is a constructor for the FindAttempt struct.
+func (mc *MomentClient) NewFindAttempt(l *Location, accuracy float64) (a *FindAttempt) {
+	if mc.err != nil {
+		return
+	}
+	if l == nil {
+		Error.Println(ErrorLocationIsNil)
+		mc.err = ErrorLocationIsNil
+		return
+	}
+	if !(accuracy >= 0 && accuracy <= MaxFindAccuracy) {
+		Error.Println(ErrorFindAccuracy)
+		mc.err = ErrorFindAccuracy
+		return
+	}
+	return &FindAttempt{Location: *l, accuracy: accuracy}
+}
+
+// String returns the string representation of FindAttempt.
+func (a FindAttempt) String() string {
+	return fmt.Sprintf("%v, accuracy: %v", a.Location, a.accuracy)
+}
+
+// values returns the [FindLatitude], [FindLongitude] and [FindAccuracy] of a, all NULL if a is nil.
+func (a *FindAttempt) values() []interface{} {
+	if a == nil {
+		return []interface{}{nil, nil, nil}
+	}
+	return []interface{}{a.latitude, a.longitude, a.accuracy}
+}
+
+// near returns nil if f was attempted within radius meters of l, widened by the accuracy of the
+// fix it was attempted with.
+func (f *FindsRow) near(l Location, radius float64) error {
+	if f.attempt == nil {
+		Error.Println(ErrorFindAttemptMissing)
+		return ErrorFindAttemptMissing
+	}
+	if haversine(f.attempt.Location, l) > radius+f.attempt.accuracy {
+		Error.Println(ErrorFindTooFar)
+		return ErrorFindTooFar
+	}
+	return nil
+}
+
+func checkFindRadius(meters float64) error {
+	if !(meters > 0 && meters <= MaxRadius) {
+		Error.Println(ErrorFindRadius)
+		return ErrorFindRadius
+	}
+	return nil
+}
+
+// SetFindRadius sets how far in meters from a moment FindPublic and FindPrivate accept a find.
+func (mc *MomentClient) SetFindRadius(meters float64) error {
+	if err := checkFindRadius(meters); err != nil {
+		return err
+	}
+	mc.radius = meters
+	return nil
+}
+
+func (mc *MomentClient) findRadius() float64 {
+	if mc.radius == 0 {
+		return DefaultFindRadius
+	}
+	return mc.radius
+}
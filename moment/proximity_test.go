@@ -0,0 +1,106 @@
+package moment
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"math"
+	"testing"
+	"time"
+)
+
+// tAttempt returns a FindAttempt from la, lo with a fix accurate to 5 meters.
+func tAttempt(la float32, lo float32) *FindAttempt {
+	return &FindAttempt{Location: Location{latitude: la, longitude: lo}, accuracy: 5}
+}
+
+func TestNewFindAttempt(t *testing.T) {
+	type test struct {
+		location *Location
+		accuracy float64
+		expected error
+	}
+	mc := new(MomentClient)
+	lo := mc.NewLocation(lat, long)
+	tests := []test{
+		test{lo, 0, nil},
+		test{lo, MaxFindAccuracy, nil},
+		test{nil, 5, ErrorLocationIsNil},
+		test{lo, -1, ErrorFindAccuracy},
+		test{lo, MaxFindAccuracy + 1, ErrorFindAccuracy},
+		test{lo, math.NaN(), ErrorFindAccuracy},
+	}
+
+	for _, v := range tests {
+		mc := new(MomentClient)
+		a := mc.NewFindAttempt(v.location, v.accuracy)
+		assert.Exactly(t, v.expected, mc.Err())
+		if v.expected == nil {
+			assert.Equal(t, v.accuracy, a.accuracy)
+		}
+	}
+}
+
+func TestFindsRowNear(t *testing.T) {
+	type test struct {
+		name     string
+		attempt  *FindAttempt
+		radius   float64
+		expected error
+	}
+	// 0.001 degrees of latitude is about 111 meters.
+	tests := []test{
+		test{"on the spot", tAttempt(0, 0), DefaultFindRadius, nil},
+		test{"within the radius", tAttempt(0.0008, 0), DefaultFindRadius, nil},
+		test{"within the accuracy", &FindAttempt{Location{latitude: 0.001}, 20}, DefaultFindRadius, nil},
+		test{"too far", tAttempt(0.001, 0), DefaultFindRadius, ErrorFindTooFar},
+		test{"wider radius", tAttempt(0.001, 0), 200, nil},
+		test{"no attempt", nil, DefaultFindRadius, ErrorFindAttemptMissing},
+	}
+
+	for _, v := range tests {
+		f := &FindsRow{attempt: v.attempt}
+		assert.Exactly(t, v.expected, f.near(Location{}, v.radius), v.name)
+	}
+}
+
+func TestSetFindRadius(t *testing.T) {
+	type test struct {
+		meters   float64
+		expected error
+	}
+	tests := []test{
+		test{1, nil},
+		test{MaxRadius, nil},
+		test{0, ErrorFindRadius},
+		test{MaxRadius + 1, ErrorFindRadius},
+	}
+
+	for _, v := range tests {
+		mc := new(MomentClient)
+		assert.Exactly(t, v.expected, mc.SetFindRadius(v.meters))
+		if v.expected == nil {
+			assert.Equal(t, v.meters, mc.findRadius())
+		} else {
+			assert.Equal(t, float64(DefaultFindRadius), mc.findRadius())
+		}
+	}
+}
+
+func TestFindTooFar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mc := new(MomentClient)
+	dt := time.Now().UTC()
+
+	mock.ExpectBegin()
+	expectLive(mock, livePublicRegexp, nil, nil, 1, true)
+	mock.ExpectRollback()
+	_, err = mc.FindPublic(context.Background(), db, mc.NewFindsRow(1, tUser, true, &dt, tAttempt(1, 1)))
+	assert.Exactly(t, ErrorFindTooFar, err)
+	assert.Equal(t, KindForbidden, KindOf(err))
+
+	expectLive(mock, liveRegexp, nil, nil, 1)
+	assert.Exactly(t, ErrorFindAttemptMissing, mc.FindPrivate(context.Background(), db, mc.NewFindsRow(1, tUser, true, &dt, nil)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
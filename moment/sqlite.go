@@ -0,0 +1,78 @@
+package moment
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"modernc.org/sqlite"
+	"time"
+)
+
+// SQLiteDriver is the database/sql driver of the SQLite backend. It runs the statements of this
+// package on modernc.org/sqlite, a SQLite that needs neither cgo nor a database server.
+const SQLiteDriver = "moment-sqlite"
+
+func init() {
+	sql.Register(SQLiteDriver, &sqliteUTC{new(sqlite.Driver)})
+}
+
+// NewSQLiteStore opens the SQLite database at path, creating it if necessary, and returns the
+// Store backed by it. Its tables are created by the migrations of its Migrator.
+func NewSQLiteStore(path string) (*SQLStore, error) {
+	db, err := OpenSQLite(context.Background(), path, PoolConfig{PingAttempts: 1})
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLStore(db, SQLite), nil
+}
+
+// OpenSQLite opens the SQLite database at path with Open, creating it if necessary. SQLite
+// allows one writer at a time, so the pool keeps a single connection whatever c allows.
+func OpenSQLite(ctx context.Context, path string, c PoolConfig) (*sql.DB, error) {
+	c.MaxOpenConns, c.MaxIdleConns = 1, 1
+	return Open(ctx, SQLiteDriver, "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite", c)
+}
+
+// sqliteArgs converts every time in args to UTC. SQLite keeps DATETIME columns as text, which
+// orders chronologically only while every value is written in the same zone.
+func sqliteArgs(args []sqldriver.NamedValue) []sqldriver.NamedValue {
+	for i, a := range args {
+		if t, ok := a.Value.(time.Time); ok {
+			args[i].Value = t.UTC()
+		}
+	}
+	return args
+}
+
+// sqliteUTC opens connections that pass every time argument in UTC.
+type sqliteUTC struct {
+	d sqldriver.Driver
+}
+
+func (d *sqliteUTC) Open(name string) (sqldriver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{c.(sqliteBaseConn)}, nil
+}
+
+// sqliteBaseConn is the part of a modernc.org/sqlite connection that sqliteConn wraps.
+type sqliteBaseConn interface {
+	sqldriver.Conn
+	sqldriver.ConnBeginTx
+	sqldriver.ExecerContext
+	sqldriver.QueryerContext
+}
+
+type sqliteConn struct {
+	sqliteBaseConn
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	return c.sqliteBaseConn.ExecContext(ctx, query, sqliteArgs(args))
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	return c.sqliteBaseConn.QueryContext(ctx, query, sqliteArgs(args))
+}
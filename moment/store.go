@@ -0,0 +1,202 @@
+package moment
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+// Store persists moments, their media, finds and shares, and selects them one Page at a time.
+// Its methods have the semantics of the MomentClient methods of the same name. SQLStore runs
+// them on Moment-Db and MemoryStore keeps them in process.
+type Store interface {
+	FindPublic(context.Context, *FindsRow) (int64, error)
+	FindPrivate(context.Context, *FindsRow) error
+	Share(context.Context, *SharesRow, []*RecipientsRow) error
+	CreatePublic(context.Context, *MomentsRow, []*MediaRow) error
+	CreatePrivate(context.Context, *MomentsRow, []*MediaRow, []*FindsRow) error
+	Edit(context.Context, *MomentEdit) error
+	Delete(context.Context, int64, time.Time) error
+	Restore(context.Context, int64, time.Time) error
+
+	LocationShared(context.Context, *Location, float64, string, *PageRequest) (*Page, error)
+	LocationPublic(context.Context, *Location, float64, *PageRequest) (*Page, error)
+	LocationHidden(context.Context, *Location, float64, *PageRequest) (*Page, error)
+	LocationLost(context.Context, *Location, float64, string, *PageRequest) (*Page, error)
+	UserShared(context.Context, string, string, *PageRequest) (*Page, error)
+	UserLeft(context.Context, string, *PageRequest) (*Page, error)
+	UserFound(context.Context, string, *PageRequest) (*Page, error)
+	UserPending(context.Context, string, *PageRequest) (*Page, error)
+
+	// IsAuthor reports whether user left moment id.
+	IsAuthor(ctx context.Context, user string, id int64) (bool, error)
+	// HasFound reports whether user found moment id.
+	HasFound(ctx context.Context, user string, id int64) (bool, error)
+	// IsRecipient reports whether user is listed in the [Finds] of moment id, found or not.
+	IsRecipient(ctx context.Context, user string, id int64) (bool, error)
+	// IsPublic reports whether moment id is public.
+	IsPublic(ctx context.Context, id int64) (bool, error)
+}
+
+// SQLStore is the Store backed by Moment-Db. It runs the statements of MomentClient on db.
+type SQLStore struct {
+	db DbRunnerTrans
+	mc MomentClient
+}
+
+// NewSQLStore is a constructor for the SQLStore struct. The statements it runs on db are
+// written in d.
+func NewSQLStore(db DbRunnerTrans, d Dialect) *SQLStore {
+	return &SQLStore{db: db, mc: MomentClient{d: d}}
+}
+
+// Migrator returns the Migrator of the Moment-Db of s.
+func (s *SQLStore) Migrator() (*Migrator, error) {
+	return NewMigrator(s.db, s.mc.dialect())
+}
+
+// BackfillCells sets the [Cell] of the moments of s that have none. See MomentClient.BackfillCells.
+func (s *SQLStore) BackfillCells(ctx context.Context, batch int) (int64, error) {
+	return s.mc.BackfillCells(ctx, s.db, batch)
+}
+
+// Reap deletes the moments of s that expired by at. See MomentClient.Reap.
+func (s *SQLStore) Reap(ctx context.Context, at time.Time) (int64, error) {
+	return s.mc.Reap(ctx, s.db, at)
+}
+
+// Purge deletes the moments of s deleted more than RestoreWindow before at. See MomentClient.Purge.
+func (s *SQLStore) Purge(ctx context.Context, at time.Time) (int64, error) {
+	return s.mc.Purge(ctx, s.db, at)
+}
+
+// SweepKeys deletes the idempotency keys of s that expired by at. See MomentClient.SweepKeys.
+func (s *SQLStore) SweepKeys(ctx context.Context, at time.Time) (int64, error) {
+	return s.mc.SweepKeys(ctx, s.db, at)
+}
+
+func (s *SQLStore) ReserveKey(ctx context.Context, k *IdempotencyKeysRow) (*Response, error) {
+	return s.mc.ReserveKey(ctx, s.db, k)
+}
+
+func (s *SQLStore) CompleteKey(ctx context.Context, k *IdempotencyKeysRow, res *Response) error {
+	return s.mc.CompleteKey(ctx, s.db, k, res)
+}
+
+func (s *SQLStore) ReleaseKey(ctx context.Context, k *IdempotencyKeysRow) error {
+	return s.mc.ReleaseKey(ctx, s.db, k)
+}
+
+func (s *SQLStore) FindPublic(ctx context.Context, f *FindsRow) (int64, error) {
+	return s.mc.FindPublic(ctx, s.db, f)
+}
+
+func (s *SQLStore) FindPrivate(ctx context.Context, f *FindsRow) error {
+	return s.mc.FindPrivate(ctx, s.db, f)
+}
+
+func (s *SQLStore) Share(ctx context.Context, sr *SharesRow, rs []*RecipientsRow) error {
+	return s.mc.Share(ctx, s.db, sr, rs)
+}
+
+func (s *SQLStore) CreatePublic(ctx context.Context, m *MomentsRow, ms []*MediaRow) error {
+	return s.mc.CreatePublic(ctx, s.db, m, ms)
+}
+
+func (s *SQLStore) CreatePrivate(ctx context.Context, m *MomentsRow, ms []*MediaRow, fs []*FindsRow) error {
+	return s.mc.CreatePrivate(ctx, s.db, m, ms, fs)
+}
+
+// SetFindRadius sets how far in meters from a moment FindPublic and FindPrivate accept a find.
+func (s *SQLStore) SetFindRadius(meters float64) error {
+	return s.mc.SetFindRadius(meters)
+}
+
+func (s *SQLStore) Edit(ctx context.Context, e *MomentEdit) error {
+	return s.mc.Edit(ctx, s.db, e)
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id int64, at time.Time) error {
+	return s.mc.Delete(ctx, s.db, id, at)
+}
+
+func (s *SQLStore) Restore(ctx context.Context, id int64, at time.Time) error {
+	return s.mc.Restore(ctx, s.db, id, at)
+}
+
+func (s *SQLStore) LocationShared(ctx context.Context, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.LocationShared(ctx, s.db, l, radius, me, pr)
+}
+
+func (s *SQLStore) LocationPublic(ctx context.Context, l *Location, radius float64, pr *PageRequest) (*Page, error) {
+	return s.mc.LocationPublic(ctx, s.db, l, radius, pr)
+}
+
+func (s *SQLStore) LocationHidden(ctx context.Context, l *Location, radius float64, pr *PageRequest) (*Page, error) {
+	return s.mc.LocationHidden(ctx, s.db, l, radius, pr)
+}
+
+func (s *SQLStore) LocationLost(ctx context.Context, l *Location, radius float64, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.LocationLost(ctx, s.db, l, radius, me, pr)
+}
+
+func (s *SQLStore) UserShared(ctx context.Context, you string, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.UserShared(ctx, s.db, you, me, pr)
+}
+
+func (s *SQLStore) UserLeft(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.UserLeft(ctx, s.db, me, pr)
+}
+
+func (s *SQLStore) UserFound(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.UserFound(ctx, s.db, me, pr)
+}
+
+func (s *SQLStore) UserPending(ctx context.Context, me string, pr *PageRequest) (*Page, error) {
+	return s.mc.UserPending(ctx, s.db, me, pr)
+}
+
+func (s *SQLStore) IsAuthor(ctx context.Context, user string, id int64) (bool, error) {
+	return exists(ctx, s.mc.dialect(), s.db, sq.
+		Select("1").
+		From(schMoments).
+		Where(sq.Eq{iD: id, userID: user}))
+}
+
+func (s *SQLStore) HasFound(ctx context.Context, user string, id int64) (bool, error) {
+	return exists(ctx, s.mc.dialect(), s.db, sq.
+		Select("1").
+		From(schFinds).
+		Where(sq.Eq{momentID: id, userID: user, found: true}))
+}
+
+func (s *SQLStore) IsRecipient(ctx context.Context, user string, id int64) (bool, error) {
+	return exists(ctx, s.mc.dialect(), s.db, sq.
+		Select("1").
+		From(schFinds).
+		Where(sq.Eq{momentID: id, userID: user}))
+}
+
+func (s *SQLStore) IsPublic(ctx context.Context, id int64) (bool, error) {
+	return exists(ctx, s.mc.dialect(), s.db, sq.
+		Select("1").
+		From(schMoments).
+		Where(sq.Eq{iD: id, public: true}))
+}
+
+// exists reports whether query returns at least one row.
+func exists(ctx context.Context, d Dialect, db DbRunner, query sq.SelectBuilder) (ok bool, err error) {
+	rows, err := query.PlaceholderFormat(format{d}).RunWith(db).QueryContext(ctx)
+	if err != nil {
+		Error.Println(err)
+		return false, dbError(ctxError(ctx, err))
+	}
+	defer rows.Close()
+
+	ok = rows.Next()
+	if err = rows.Err(); err != nil {
+		Error.Println(err)
+		return false, dbError(ctxError(ctx, err))
+	}
+	return
+}
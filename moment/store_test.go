@@ -0,0 +1,714 @@
+package moment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tStores returns, by backend, a Store of every backend that runs without a database server,
+// each holding in order of creation:
+//
+//	1  a public moment of tUser at (0,0), found by tUser2
+//	2  a hidden moment of tUser at (0.005,0.005), about 786 meters from (0,0)
+//	3  a private moment of tUser at (0,0) for tUser2 and tUser3, shared by tUser2 with tUser3
+//	4  a public moment of tUser2 at (5,5), shared by tUser2 with all
+//	5  a public moment of tUser at (0.002,0.002), about 314 meters from (0,0)
+func tStores(t *testing.T) map[string]Store {
+	sl, err := NewSQLiteStore(filepath.Join(t.TempDir(), "moment.db"))
+	assert.Nil(t, err)
+	m, err := sl.Migrator()
+	assert.Nil(t, err)
+	_, err = m.Up(context.Background())
+	assert.Nil(t, err)
+
+	ss := map[string]Store{"memory": NewMemoryStore(), "sqlite": sl}
+	for _, s := range ss {
+		tFill(t, s)
+	}
+	return ss
+}
+
+func tFill(t *testing.T, s Store) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+
+	n := 0
+	create := func(user string, la float32, lo float32, p bool, h bool, recipients ...string) {
+		d := tDate.Add(time.Duration(n) * time.Hour)
+		n++
+		m := mc.NewMomentsRow(mc.NewLocation(la, lo), user, p, h, &d, nil, nil, nil)
+		ms := []*MediaRow{mc.NewMediaRow(0, "message", DNE, "")}
+		var fs []*FindsRow
+		for _, r := range recipients {
+			fs = append(fs, mc.NewFindsRow(0, r, false, &time.Time{}, nil))
+		}
+		assert.Nil(t, mc.Err())
+		if p {
+			assert.Nil(t, s.CreatePublic(ctx, m, ms))
+		} else {
+			assert.Nil(t, s.CreatePrivate(ctx, m, ms, fs))
+		}
+	}
+	share := func(id int64, user string, all bool, recipient string) {
+		rs := []*RecipientsRow{mc.NewRecipientsRow(0, all, recipient)}
+		assert.Nil(t, s.Share(ctx, mc.NewSharesRow(0, id, user), rs))
+	}
+
+	create(tUser, 0, 0, true, false)
+	dt := tDate
+	_, err := s.FindPublic(ctx, mc.NewFindsRow(1, tUser2, true, &dt, tAttempt(0, 0)))
+	assert.Nil(t, err)
+	create(tUser, 0.005, 0.005, true, true)
+	create(tUser, 0, 0, false, false, tUser2, tUser3)
+	share(3, tUser2, false, tUser3)
+	create(tUser2, 5, 5, true, false)
+	share(4, tUser2, true, "")
+	create(tUser, 0.002, 0.002, true, false)
+	assert.Nil(t, mc.Err())
+}
+
+// tRecipients returns n RecipientsRows for the recipients prefix0000 and onwards.
+func tRecipients(mc *MomentClient, prefix string, n int) []*RecipientsRow {
+	rs := make([]*RecipientsRow, n)
+	for i := range rs {
+		rs[i] = mc.NewRecipientsRow(0, false, fmt.Sprintf("%s%04d", prefix, i))
+	}
+	return rs
+}
+
+func tIDs(pg *Page) (ids []int64) {
+	for _, m := range pg.Moments {
+		ids = append(ids, m.momentID)
+	}
+	return
+}
+
+func TestStoreSelectors(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+
+	for name, s := range tStores(t) {
+		type test struct {
+			name     string
+			sel      func() (*Page, error)
+			expected []int64
+		}
+		tests := []test{
+			test{"LocationPublic", func() (*Page, error) { return s.LocationPublic(ctx, l, 0, nil) }, []int64{1, 5}},
+			test{"LocationPublic by date", func() (*Page, error) { return s.LocationPublic(ctx, l, 0, mc.NewPageRequest(0, "", SortCreateDate)) }, []int64{5, 1}},
+			test{"LocationPublic within 100m", func() (*Page, error) { return s.LocationPublic(ctx, l, 100, nil) }, []int64{1}},
+			test{"LocationHidden", func() (*Page, error) { return s.LocationHidden(ctx, l, 0, nil) }, []int64{2}},
+			test{"LocationHidden within 500m", func() (*Page, error) { return s.LocationHidden(ctx, l, 500, nil) }, nil},
+			test{"LocationLost", func() (*Page, error) { return s.LocationLost(ctx, l, 0, tUser2, nil) }, []int64{3}},
+			test{"LocationShared", func() (*Page, error) { return s.LocationShared(ctx, l, 0, tUser3, nil) }, []int64{3}},
+			test{"LocationShared not shared", func() (*Page, error) { return s.LocationShared(ctx, l, 0, tUser2, nil) }, nil},
+			test{"UserShared", func() (*Page, error) { return s.UserShared(ctx, tUser2, tUser3, nil) }, []int64{4, 3}},
+			test{"UserShared with all", func() (*Page, error) { return s.UserShared(ctx, tUser2, tUser, nil) }, []int64{4}},
+			test{"UserLeft", func() (*Page, error) { return s.UserLeft(ctx, tUser, nil) }, []int64{3, 1}},
+			test{"UserFound", func() (*Page, error) { return s.UserFound(ctx, tUser2, nil) }, []int64{1}},
+		}
+
+		for _, v := range tests {
+			pg, err := v.sel()
+			assert.Nil(t, err, name, v.name)
+			assert.Equal(t, v.expected, tIDs(pg), name, v.name)
+			assert.Empty(t, pg.Next, name, v.name)
+		}
+
+		pg, err := s.LocationHidden(ctx, mc.NewLocation(50, 50), 0, nil)
+		assert.Nil(t, err, name)
+		assert.Empty(t, pg.Moments, name)
+
+		pg, err = s.LocationPublic(ctx, mc.NewLocation(0.001, 0.002), 0, nil)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []int64{5, 1}, tIDs(pg), name)
+		assert.InDelta(t, 111.2, pg.Moments[0].heading.distance, 0.5, name)
+		assert.InDelta(t, 0, pg.Moments[0].heading.bearing, 0.1, name)
+		assert.InDelta(t, 248.6, pg.Moments[1].heading.distance, 0.5, name)
+		assert.InDelta(t, 243.4, pg.Moments[1].heading.bearing, 0.1, name)
+
+		_, err = s.LocationPublic(ctx, l, MaxRadius+1, nil)
+		assert.Exactly(t, ErrorRadius, err, name)
+
+		pg, err = s.UserLeft(ctx, tUser, nil)
+		assert.Nil(t, err, name)
+		assert.Empty(t, pg.Moments[0].userID, name)
+		assert.Equal(t, 2, len(pg.Moments[0].finds), name)
+		assert.Equal(t, 1, len(pg.Moments[0].media), name)
+	}
+}
+
+func TestStorePaging(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+
+	for name, s := range tStores(t) {
+		for _, sort := range []Sort{SortCreateDate, SortDistance} {
+			var ids []int64
+			next := ""
+			for {
+				pg, err := s.LocationPublic(ctx, l, 0, mc.NewPageRequest(1, next, sort))
+				assert.Nil(t, err, name)
+				assert.Nil(t, mc.Err(), name)
+				ids = append(ids, tIDs(pg)...)
+				if next = pg.Next; next == "" {
+					break
+				}
+			}
+			if sort == SortDistance {
+				assert.Equal(t, []int64{1, 5}, ids, name)
+			} else {
+				assert.Equal(t, []int64{5, 1}, ids, name)
+			}
+		}
+
+		// Moment 5 lies in the cover of the circle but outside it, and is newer than moment 1.
+		for _, sort := range []Sort{SortCreateDate, SortDistance} {
+			pg, err := s.LocationPublic(ctx, l, 300, mc.NewPageRequest(1, "", sort))
+			assert.Nil(t, err, name)
+			assert.Equal(t, []int64{1}, tIDs(pg), name, sort)
+			assert.Empty(t, pg.Next, name, sort)
+		}
+
+		_, err := s.UserLeft(ctx, tUser, mc.NewPageRequest(1, "", SortDistance))
+		assert.Exactly(t, ErrorSortDistance, err, name)
+	}
+}
+
+// TestStoreEverywhere checks, for every backend, that a location selector anywhere on the globe,
+// including across the antimeridian and around the poles, returns a moment within its radius.
+// There are fewer moments than MaxPageLimit, so the moment is on the first page.
+func TestStoreEverywhere(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	rnd := rand.New(rand.NewSource(1))
+
+	type test struct {
+		l      *Location
+		radius float64
+		id     int64
+	}
+	var tests []test
+	ss := tStores(t)
+	for i := int64(6); i < MaxPageLimit; i++ {
+		l := tGlobe(rnd)
+		radius := rnd.Float64() * MaxRadius
+		m := tDestination(l, rnd.Float64()*radius, rnd.Float64()*360)
+		if haversine(l, m) > radius {
+			i--
+			continue
+		}
+		for _, s := range ss {
+			mr := mc.NewMomentsRow(mc.NewLocation(m.latitude, m.longitude), tUser, true, true, &tDate, nil, nil, nil)
+			assert.Nil(t, s.CreatePublic(ctx, mr, []*MediaRow{mc.NewMediaRow(0, "message", DNE, "")}))
+		}
+		tests = append(tests, test{mc.NewLocation(l.latitude, l.longitude), radius, i})
+	}
+	assert.Nil(t, mc.Err())
+
+	for name, s := range ss {
+		for _, v := range tests {
+			pg, err := s.LocationHidden(ctx, v.l, v.radius, mc.NewPageRequest(MaxPageLimit, "", ""))
+			assert.Nil(t, err, name)
+			assert.Contains(t, tIDs(pg), v.id, "%v: moment %d is within %v meters of %v", name, v.id, v.radius, v.l)
+		}
+	}
+}
+
+// TestStoreModify compares errors by Kind only: the backends report the same failures,
+// but SQLStore learns of them from constraint messages that do not name a field.
+func TestStoreModify(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	dt := tDate
+
+	for name, s := range tStores(t) {
+		type test struct {
+			name     string
+			modify   func() error
+			expected error
+		}
+		tests := []test{
+			test{"FindPublic of a missing moment", func() error {
+				_, err := s.FindPublic(ctx, mc.NewFindsRow(9, tUser3, true, &dt, tAttempt(0, 0)))
+				return err
+			}, ErrorMomentDNE},
+			test{"FindPublic twice", func() error {
+				_, err := s.FindPublic(ctx, mc.NewFindsRow(1, tUser2, true, &dt, tAttempt(0, 0)))
+				return err
+			}, ErrorFindsRowExists},
+			test{"FindPublic too far", func() error {
+				_, err := s.FindPublic(ctx, mc.NewFindsRow(1, tUser3, true, &dt, tAttempt(1, 1)))
+				return err
+			}, ErrorFindTooFar},
+			test{"FindPublic of a private moment", func() error {
+				_, err := s.FindPublic(ctx, mc.NewFindsRow(3, tUser3, true, &dt, tAttempt(0, 0)))
+				return err
+			}, ErrorMomentDNE},
+			test{"FindPublic not found", func() error {
+				_, err := s.FindPublic(ctx, mc.NewFindsRow(1, tUser3, false, &time.Time{}, nil))
+				return err
+			}, ErrorFieldInvalid},
+			test{"FindPrivate of a non-recipient", func() error {
+				return s.FindPrivate(ctx, mc.NewFindsRow(3, tUser, true, &dt, tAttempt(0, 0)))
+			}, ErrorFindsRowDNE},
+			test{"FindPrivate", func() error {
+				return s.FindPrivate(ctx, mc.NewFindsRow(3, tUser2, true, &dt, tAttempt(0, 0)))
+			}, nil},
+			test{"Share of a missing moment", func() error {
+				return s.Share(ctx, mc.NewSharesRow(0, 9, tUser), []*RecipientsRow{mc.NewRecipientsRow(0, true, "")})
+			}, ErrorMomentDNE},
+			test{"Share with a repeated recipient", func() error {
+				rs := []*RecipientsRow{mc.NewRecipientsRow(0, false, tUser3), mc.NewRecipientsRow(0, false, tUser3)}
+				return s.Share(ctx, mc.NewSharesRow(0, 1, tUser), rs)
+			}, ErrorRecipientsRowExists},
+			test{"Share with more recipients than one insert holds", func() error {
+				return s.Share(ctx, mc.NewSharesRow(0, 1, tUser), tRecipients(mc, "bulk", 1500))
+			}, nil},
+			test{"Share with a recipient repeated in the last chunk", func() error {
+				rs := append(tRecipients(mc, "fail", 1500), mc.NewRecipientsRow(0, false, "fail0000"))
+				return s.Share(ctx, mc.NewSharesRow(0, 1, tUser), rs)
+			}, ErrorRecipientsRowExists},
+			test{"Share without recipients", func() error {
+				return s.Share(ctx, mc.NewSharesRow(0, 1, tUser), nil)
+			}, ErrorParameterEmpty},
+			test{"CreatePrivate without finds", func() error {
+				return s.CreatePrivate(ctx, mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil), []*MediaRow{mc.NewMediaRow(0, "", DNE, "")}, nil)
+			}, ErrorParameterEmpty},
+			test{"CreatePrivate with a repeated recipient", func() error {
+				fs := []*FindsRow{mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil), mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil)}
+				return s.CreatePrivate(ctx, mc.NewMomentsRow(mc.NewLocation(lat, long), tUser, false, false, &dt, nil, nil, nil), []*MediaRow{mc.NewMediaRow(0, "", DNE, "")}, fs)
+			}, ErrorFindsRowExists},
+		}
+
+		for _, v := range tests {
+			err := v.modify()
+			if v.expected == nil {
+				assert.Nil(t, err, name, v.name)
+			} else {
+				assert.Equal(t, KindOf(v.expected), KindOf(err), name, v.name)
+			}
+			assert.Nil(t, mc.Err(), name, v.name)
+		}
+
+		ok, err := s.IsAuthor(ctx, tUser, 6)
+		assert.Nil(t, err, name)
+		assert.False(t, ok, name)
+
+		pg, err := s.UserShared(ctx, tUser, "bulk1499", nil)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []int64{1}, tIDs(pg), name)
+		pg, err = s.UserShared(ctx, tUser, "fail0042", nil)
+		assert.Nil(t, err, name)
+		assert.Empty(t, pg.Moments, "%v: a failed share inserts no chunk", name)
+
+		pg, err = s.UserFound(ctx, tUser2, nil)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []int64{3, 1}, tIDs(pg), name)
+		assert.True(t, dt.Equal(*pg.Moments[0].finds[0].findDate), name)
+	}
+}
+
+func TestStoreIdempotency(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	later := tDate.Add(KeyLease + time.Second)
+	expired := tDate.Add(KeyTTL)
+
+	for name, s := range tStores(t) {
+		ks := s.(IdempotencyStore)
+		k := mc.NewIdempotencyKeysRow(tUser, "create", []byte{1}, &tDate)
+		res := &Response{Status: 201, Header: map[string][]string{"Content-Type": {"application/json"}, "Location": {"/moments/1"}}, Body: []byte(`{}`)}
+
+		type test struct {
+			name     string
+			modify   func() (*Response, error)
+			expected *Response
+			err      error
+		}
+		tests := []test{
+			test{"ReserveKey", func() (*Response, error) { return ks.ReserveKey(ctx, k) }, nil, nil},
+			test{"ReserveKey while in progress", func() (*Response, error) { return ks.ReserveKey(ctx, k) }, nil, ErrorKeyInProgress},
+			test{"ReserveKey of another request", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser, "create", []byte{2}, &tDate))
+			}, nil, ErrorKeyReused},
+			test{"ReserveKey of another user", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser2, "create", []byte{2}, &tDate))
+			}, nil, nil},
+			test{"CompleteKey", func() (*Response, error) { return nil, ks.CompleteKey(ctx, k, res) }, nil, nil},
+			test{"CompleteKey twice", func() (*Response, error) { return nil, ks.CompleteKey(ctx, k, res) }, nil, ErrorKeyDNE},
+			test{"ReserveKey replays", func() (*Response, error) { return ks.ReserveKey(ctx, k) }, res, nil},
+			test{"ReserveKey after KeyLease replays", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser, "create", []byte{1}, &later))
+			}, res, nil},
+			test{"ReleaseKey of a response", func() (*Response, error) { return nil, ks.ReleaseKey(ctx, k) }, nil, nil},
+			test{"ReserveKey after ReleaseKey of a response", func() (*Response, error) { return ks.ReserveKey(ctx, k) }, res, nil},
+			test{"ReleaseKey", func() (*Response, error) {
+				return nil, ks.ReleaseKey(ctx, mc.NewIdempotencyKeysRow(tUser2, "create", []byte{2}, &tDate))
+			}, nil, nil},
+			test{"ReserveKey after ReleaseKey", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser2, "create", []byte{3}, &tDate))
+			}, nil, nil},
+			test{"ReserveKey of an abandoned key", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser2, "create", []byte{4}, &later))
+			}, nil, nil},
+			test{"CompleteKey of an abandoned key", func() (*Response, error) {
+				return nil, ks.CompleteKey(ctx, mc.NewIdempotencyKeysRow(tUser2, "create", []byte{3}, &tDate), res)
+			}, nil, ErrorKeyDNE},
+			test{"ReserveKey of an expired key", func() (*Response, error) {
+				return ks.ReserveKey(ctx, mc.NewIdempotencyKeysRow(tUser, "create", []byte{5}, &expired))
+			}, nil, nil},
+		}
+
+		for _, v := range tests {
+			res, err := v.modify()
+			assert.Exactly(t, v.err, err, name, v.name)
+			assert.Equal(t, v.expected, res, name, v.name)
+		}
+
+		r := s.(Reaper)
+		n, err := r.SweepKeys(ctx, expired.Add(KeyLease+time.Second))
+		assert.Nil(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+		n, err = r.SweepKeys(ctx, expired.Add(KeyTTL))
+		assert.Nil(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+		assert.Nil(t, mc.Err(), name)
+	}
+}
+
+func TestStorePolicy(t *testing.T) {
+	ctx := context.Background()
+	p := new(Policy)
+
+	for name, s := range tStores(t) {
+		assert.Nil(t, p.AuthorizeShare(ctx, s, tUser, 1), name)
+		assert.Nil(t, p.AuthorizeShare(ctx, s, tUser2, 1), name)
+		assert.Exactly(t, ErrorShareForbidden, p.AuthorizeShare(ctx, s, tUser3, 1), name)
+		assert.Exactly(t, ErrorShareForbidden, p.AuthorizeShare(ctx, s, tUser3, 3), name)
+		assert.Nil(t, p.AuthorizeFindPrivate(ctx, s, tUser3, 3), name)
+		assert.Exactly(t, ErrorFindPrivateForbidden, p.AuthorizeFindPrivate(ctx, s, tUser, 3), name)
+		assert.Nil(t, p.AuthorizeFindPublic(ctx, s, tUser3, 1), name)
+		assert.Exactly(t, ErrorMomentDNE, p.AuthorizeFindPublic(ctx, s, tUser3, 3), name)
+		assert.Exactly(t, ErrorMomentDNE, p.AuthorizeFindPublic(ctx, s, tUser3, 9), name)
+		assert.Nil(t, p.AuthorizeEdit(ctx, s, tUser, 1), name)
+		assert.Exactly(t, ErrorEditForbidden, p.AuthorizeEdit(ctx, s, tUser2, 1), name)
+		assert.Nil(t, p.AuthorizeDelete(ctx, s, tUser2, 4), name)
+		assert.Exactly(t, ErrorDeleteForbidden, p.AuthorizeDelete(ctx, s, tUser, 4), name)
+	}
+}
+
+func TestStoreEdit(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+
+	for name, s := range tStores(t) {
+		assert.Nil(t, s.Edit(ctx, mc.NewMomentEdit(1, true, []string{"edited"})), name)
+		assert.Exactly(t, ErrorMediaCount, s.Edit(ctx, mc.NewMomentEdit(1, false, []string{"edited", "twice"})), name)
+		assert.Exactly(t, ErrorPrivateHiddenMoment, s.Edit(ctx, mc.NewMomentEdit(3, true, []string{"edited"})), name)
+		assert.Exactly(t, ErrorMomentDNE, s.Edit(ctx, mc.NewMomentEdit(9, false, []string{"edited"})), name)
+		assert.Nil(t, mc.Err(), name)
+
+		pg, err := s.LocationHidden(ctx, mc.NewLocation(lat, long), 0, nil)
+		assert.Nil(t, err, name)
+		assert.Contains(t, tIDs(pg), int64(1), name)
+
+		assert.Nil(t, s.Edit(ctx, mc.NewMomentEdit(1, false, []string{"edited"})), name)
+		pg, err = s.LocationPublic(ctx, mc.NewLocation(lat, long), 0, nil)
+		assert.Nil(t, err, name)
+		assert.Contains(t, tIDs(pg), int64(1), name)
+		for _, m := range pg.Moments {
+			if m.momentID == 1 {
+				assert.Equal(t, "edited", m.media[0].message, name)
+			}
+		}
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+	now := time.Now().UTC()
+	created := now.Add(-48 * time.Hour)
+
+	for name, s := range tStores(t) {
+		for _, e := range []time.Time{now.Add(-time.Minute), now.Add(time.Hour)} {
+			e := e
+			m := mc.NewMomentsRow(l, tUser, true, false, &created, &e, nil, nil)
+			assert.Nil(t, s.CreatePublic(ctx, m, []*MediaRow{mc.NewMediaRow(0, "ephemeral", DNE, "")}), name)
+		}
+		assert.Nil(t, mc.Err(), name)
+
+		pg, err := s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(6), name)
+		assert.Contains(t, tIDs(pg), int64(7), name)
+		pg, err = s.UserLeft(ctx, tUser, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(6), name)
+
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(6, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Exactly(t, ErrorMomentExpired, err, name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(7, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Nil(t, err, name)
+
+		r := s.(Reaper)
+		n, err := r.Reap(ctx, now)
+		assert.Nil(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+		assert.Exactly(t, ErrorMomentNotDeleted, s.Restore(ctx, 6, now), name, "a reaped moment stays deleted")
+		n, err = r.Purge(ctx, now.Add(RestoreWindow))
+		assert.Nil(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(6, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Exactly(t, ErrorMomentDNE, err, name)
+	}
+}
+
+// TestStoreFindPublicPrivate checks that a public find of a private moment is reported as
+// ErrorMomentDNE even once it has expired or before it is released.
+func TestStoreFindPublicPrivate(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+	now := time.Now().UTC()
+	created := now.Add(-48 * time.Hour)
+	expired, release := now.Add(-time.Minute), now.Add(time.Hour)
+	ms := []*MediaRow{mc.NewMediaRow(0, "private", DNE, "")}
+
+	for name, s := range tStores(t) {
+		fs := []*FindsRow{mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil)}
+		assert.Nil(t, s.CreatePrivate(ctx, mc.NewMomentsRow(l, tUser, false, false, &created, &expired, nil, nil), ms, fs), name)
+		fs = []*FindsRow{mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil)}
+		assert.Nil(t, s.CreatePrivate(ctx, mc.NewMomentsRow(l, tUser, false, false, &now, nil, &release, nil), ms, fs), name)
+		assert.Nil(t, mc.Err(), name)
+
+		for _, id := range []int64{6, 7} {
+			_, err := s.FindPublic(ctx, mc.NewFindsRow(id, tUser3, true, &now, tAttempt(lat, long)))
+			assert.Exactly(t, ErrorMomentDNE, err, name)
+		}
+	}
+}
+
+func TestStoreCapsule(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+	now := time.Now().UTC()
+	release := now.Add(time.Hour)
+	ms := []*MediaRow{mc.NewMediaRow(0, "later", DNE, "")}
+
+	for name, s := range tStores(t) {
+		assert.Nil(t, s.CreatePublic(ctx, mc.NewMomentsRow(l, tUser, true, false, &now, nil, &release, nil), ms), name)
+		fs := []*FindsRow{mc.NewFindsRow(0, tUser2, false, &time.Time{}, nil)}
+		assert.Nil(t, s.CreatePrivate(ctx, mc.NewMomentsRow(l, tUser, false, false, &now, nil, &release, nil), ms, fs), name)
+		assert.Nil(t, mc.Err(), name)
+
+		pg, err := s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(6), name)
+		pg, err = s.LocationLost(ctx, l, 0, tUser2, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(7), name)
+
+		pg, err = s.UserLeft(ctx, tUser, nil)
+		assert.Nil(t, err, name)
+		assert.Subset(t, tIDs(pg), []int64{6, 7}, name)
+		for _, m := range pg.Moments {
+			if m.momentID == 6 || m.momentID == 7 {
+				assert.True(t, release.Equal(*m.releaseDate), name)
+				assert.Equal(t, 1, len(m.media), name)
+			}
+		}
+
+		pg, err = s.UserPending(ctx, tUser2, nil)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []int64{7}, tIDs(pg), name)
+		assert.Equal(t, tUser, pg.Moments[0].userID, name)
+		assert.Empty(t, pg.Moments[0].media, name)
+		pg, err = s.UserPending(ctx, tUser3, nil)
+		assert.Nil(t, err, name)
+		assert.Empty(t, pg.Moments, name)
+
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(6, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Exactly(t, ErrorMomentUnreleased, err, name)
+		assert.Exactly(t, ErrorMomentUnreleased, s.FindPrivate(ctx, mc.NewFindsRow(7, tUser2, true, &now, tAttempt(lat, long))), name)
+	}
+}
+
+func TestStoreProximity(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	dt := time.Now().UTC()
+	// About 555 meters north of moment 1 at (0,0).
+	far := mc.NewFindAttempt(mc.NewLocation(0.005, 0), 10)
+	assert.Nil(t, mc.Err())
+
+	for name, s := range tStores(t) {
+		_, err := s.FindPublic(ctx, mc.NewFindsRow(1, tUser3, true, &dt, far))
+		assert.Exactly(t, ErrorFindTooFar, err, name)
+
+		p := s.(Proximity)
+		assert.Exactly(t, ErrorFindRadius, p.SetFindRadius(0), name)
+		assert.Nil(t, p.SetFindRadius(1000), name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(1, tUser3, true, &dt, far))
+		assert.Nil(t, err, name)
+		assert.Nil(t, s.FindPrivate(ctx, mc.NewFindsRow(3, tUser3, true, &dt, far)), name)
+
+		if ss, ok := s.(*SQLStore); ok {
+			var la, lo, acc float64
+			err = ss.db.QueryRowContext(ctx, `SELECT "FindLatitude", "FindLongitude", "FindAccuracy" FROM "Finds" WHERE "MomentID" = 3 AND "UserID" = ?`, tUser3).
+				Scan(&la, &lo, &acc)
+			assert.Nil(t, err, name)
+			assert.InDelta(t, 0.005, la, 1e-6, name)
+			assert.Equal(t, float64(0), lo, name)
+			assert.Equal(t, float64(10), acc, name)
+		} else {
+			assert.Equal(t, far, s.(*MemoryStore).finds[findKey{3, tUser3}].attempt, name)
+		}
+	}
+}
+
+func TestStoreCapacity(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+	now := time.Now().UTC()
+	ms := []*MediaRow{mc.NewMediaRow(0, "first come", DNE, "")}
+	one, three := 1, 3
+
+	for name, s := range tStores(t) {
+		assert.Nil(t, s.CreatePublic(ctx, mc.NewMomentsRow(l, tUser, true, false, &now, nil, nil, &three), ms), name)
+		assert.Nil(t, s.CreatePublic(ctx, mc.NewMomentsRow(l, tUser, true, true, &now, nil, nil, &one), ms), name)
+		assert.Nil(t, mc.Err(), name)
+
+		pg, err := s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		for _, m := range pg.Moments {
+			if m.momentID == 6 {
+				assert.Equal(t, 3, *m.remaining(), name)
+			} else {
+				assert.Nil(t, m.remaining(), name)
+			}
+		}
+
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(6, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Nil(t, err, name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(6, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Equal(t, KindConflict, KindOf(err), name)
+		pg, err = s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		for _, m := range pg.Moments {
+			if m.momentID == 6 {
+				assert.Equal(t, 2, *m.remaining(), "a failed find does not claim a place: "+name)
+			}
+		}
+
+		// Concurrent finders take no more places than are left.
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = s.FindPublic(ctx, mc.NewFindsRow(6, fmt.Sprintf("finder%04d", i), true, &now, tAttempt(lat, long)))
+			}(i)
+		}
+		wg.Wait()
+		found := 0
+		for _, err := range errs {
+			if err == nil {
+				found++
+			} else {
+				assert.Exactly(t, ErrorMomentFull, err, name)
+			}
+		}
+		assert.Equal(t, 2, found, name)
+
+		pg, err = s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(6), name)
+		assert.Contains(t, tIDs(pg), int64(1), name)
+
+		pg, err = s.LocationHidden(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.Contains(t, tIDs(pg), int64(7), name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(7, tUser2, true, &now, tAttempt(lat, long)))
+		assert.Nil(t, err, name)
+		pg, err = s.LocationHidden(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(7), name)
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(7, tUser3, true, &now, tAttempt(lat, long)))
+		assert.Exactly(t, ErrorMomentFull, err, name)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	mc := new(MomentClient)
+	l := mc.NewLocation(lat, long)
+
+	for name, s := range tStores(t) {
+		assert.Nil(t, s.Delete(ctx, 1, tDate), name)
+		assert.Exactly(t, ErrorMomentDNE, s.Delete(ctx, 1, tDate), name)
+
+		pg, err := s.UserLeft(ctx, tUser, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(1), name)
+		pg, err = s.UserFound(ctx, tUser2, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(1), name)
+		pg, err = s.LocationPublic(ctx, l, 0, nil)
+		assert.Nil(t, err, name)
+		assert.NotContains(t, tIDs(pg), int64(1), name)
+
+		dt := tDate
+		_, err = s.FindPublic(ctx, mc.NewFindsRow(1, tUser3, true, &dt, tAttempt(0, 0)))
+		assert.Exactly(t, ErrorMomentDNE, err, name)
+		err = s.Share(ctx, mc.NewSharesRow(0, 1, tUser), []*RecipientsRow{mc.NewRecipientsRow(0, true, "")})
+		assert.Exactly(t, ErrorMomentDNE, err, name)
+		assert.Exactly(t, ErrorMomentDNE, s.Edit(ctx, mc.NewMomentEdit(1, false, []string{"edited"})), name)
+
+		assert.Exactly(t, ErrorMomentNotDeleted, s.Restore(ctx, 1, tDate.Add(RestoreWindow+time.Second)), name)
+		assert.Nil(t, s.Restore(ctx, 1, tDate.Add(RestoreWindow)), name)
+		assert.Exactly(t, ErrorMomentNotDeleted, s.Restore(ctx, 1, tDate), name)
+		pg, err = s.UserLeft(ctx, tUser, nil)
+		assert.Nil(t, err, name)
+		assert.Contains(t, tIDs(pg), int64(1), name)
+
+		p := s.(Reaper)
+		assert.Nil(t, s.Delete(ctx, 1, tDate), name)
+		assert.Nil(t, s.Delete(ctx, 3, tDate.Add(time.Hour)), name)
+		n, err := p.Purge(ctx, tDate.Add(RestoreWindow+time.Minute))
+		assert.Nil(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+		assert.Exactly(t, ErrorMomentNotDeleted, s.Restore(ctx, 1, tDate), name)
+		assert.Nil(t, s.Restore(ctx, 3, tDate.Add(RestoreWindow)), name)
+		assert.Nil(t, mc.Err(), name)
+	}
+}
+
+func TestStoreCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mc := new(MomentClient)
+
+	for name, s := range tStores(t) {
+		_, err := s.LocationPublic(ctx, mc.NewLocation(lat, long), 0, nil)
+		assert.True(t, errors.Is(err, context.Canceled), name)
+		assert.True(t, errors.Is(err, ErrorUnavailable), name)
+
+		_, err = s.IsAuthor(ctx, tUser, 1)
+		assert.True(t, errors.Is(err, ErrorUnavailable), name)
+	}
+}
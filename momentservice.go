@@ -1,125 +1,137 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"github.com/penutty/Moment-Service/moment"
 	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
 const (
-	MomentEndpoint = "/moment"
-
 	listenPort = ":8081"
+
+	// defaultSQLitePath is the database file of the sqlite store when MomentSQLitePath is unset.
+	defaultSQLitePath = "moment.db"
 )
 
 func main() {
-	a := new(app)
-	a.c = new(moment.MomentClient)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(context.Background(), os.Stdout, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	mux := http.NewServeMux()
+	a := new(app)
+	a.newBuilder = func() moment.Builder { return new(moment.MomentClient) }
+	a.p = new(moment.Policy)
 
-	mux.HandleFunc(MomentEndpoint, a.momentHandler)
+	var err error
+	if a.s, a.db, err = storeFromEnv(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err = checkSchema(context.Background(), a.s); err != nil {
+		log.Fatal(err)
+	}
+	a.keys, _ = a.s.(moment.IdempotencyStore)
+	if a.auth, err = authenticatorFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if a.timeouts, err = timeoutsFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	for _, v := range apiVersions {
+		if err = v.scheduleFromEnv(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	interval, err := reapIntervalFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if r, ok := a.s.(moment.Reaper); ok && interval > 0 {
+		go reap(context.Background(), r, interval, a.timeouts.reap)
+	}
+	radius, err := findRadiusFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if p, ok := a.s.(moment.Proximity); ok {
+		if err = p.SetFindRadius(radius); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if os.Getenv("MomentValidateRequests") != "" {
+		if a.spec, err = newSpecValidator(openAPIDocument); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	log.Fatal(http.ListenAndServe(listenPort, mux))
+	log.Fatal(http.ListenAndServe(listenPort, a.routes()))
 }
 
 var (
 	ErrorMethodNotImplemented = errors.New("Request method is not implemented by API endpoint.")
 	ErrorBadRequest           = errors.New("Request is invalid.")
+	ErrorStoreUnknown         = errors.New("MomentStore must be mssql, sqlite or memory.")
 )
 
-type app struct {
-	c moment.Client
-}
-
-func (a *app) momentHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		a.momentGetHandler(w, r)
-	case http.MethodPost:
-		a.momentPostHandler(w, r)
-	case http.MethodPatch:
-		a.momentPatchHandler(w, r)
-	default:
-		log.Println(ErrorMethodNotImplemented)
-		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
-		return
-	}
-
-}
-
-func (a *app) momentPostHandler(w http.ResponseWriter, r *http.Request) {
-	var err error
-	momentType := r.Form.Get("type")
-	switch momentType {
-	case "private":
-		err = a.postPrivateMoment(r)
-	case "public":
-		err = a.postPublicMoment(r)
+// storeFromEnv returns the moment.Store named by MomentStore and the connection pool it runs
+// on, configured by poolFromEnv. "mssql", the default, runs on the Moment-Db named by
+// MomentDBConnStr, "sqlite" on the SQLite file named by MomentSQLitePath and "memory" keeps
+// moments in process until exit, without a pool. The tables of mssql and sqlite are created
+// by the migrate subcommand.
+func storeFromEnv(ctx context.Context) (moment.Store, *sql.DB, error) {
+	var db *sql.DB
+	var d moment.Dialect
+	c, err := poolFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch os.Getenv("MomentStore") {
+	case "", "mssql":
+		db, err = moment.DB(ctx, c)
+		d = moment.MSSQL
+	case "sqlite":
+		path := os.Getenv("MomentSQLitePath")
+		if path == "" {
+			path = defaultSQLitePath
+		}
+		db, err = moment.OpenSQLite(ctx, path, c)
+		d = moment.SQLite
+	case "memory":
+		return moment.NewMemoryStore(), nil, nil
 	default:
-		log.Println(ErrorBadRequest)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
+		return nil, nil, ErrorStoreUnknown
 	}
 	if err != nil {
-		genErrorHandler(w, err)
-		return
+		return nil, nil, err
 	}
-	w.WriteHeader(http.StatusCreated)
+	return moment.NewSQLStore(db, d), db, nil
 }
 
-func (a *app) momentGetHandler(w http.ResponseWriter, r *http.Request) {
-
-	var err error
-	momentType := r.Form.Get("type")
-	switch momentType {
-	case "hidden":
-		err = a.getHiddenMoment(w, r)
-	case "lost":
-		err = a.getLostMoment(w, r)
-	case "sharedbyuser":
-		err = a.getSharedMomentbyUser(w, r)
-	case "sharedbylocation":
-		err = a.getSharedMomentbyLocation(w, r)
-	case "found":
-		err = a.getFoundMoment(w, r)
-	case "left":
-		err = a.getLeftMoment(w, r)
-	case "public":
-		err = a.getPublicMoment(w, r)
-	default:
-		log.Println(ErrorBadRequest)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
-	if err != nil {
-		genErrorHandler(w, err)
-		return
-	}
+type app struct {
+	s    moment.Store
+	db   *sql.DB
+	p    moment.Authorizer
+	keys moment.IdempotencyStore
+	auth *authenticator
+	spec *specValidator
+
+	newBuilder func() moment.Builder
+	timeouts   timeouts
 }
 
-func (a *app) momentPatchHandler(w http.ResponseWriter, r *http.Request) {
-	var err error
-	action := r.Form.Get("action")
-	switch action {
-	case "findpublic":
-		err = a.findPublicMoment(r)
-	case "findprivate":
-		err = a.findPrivateMoment(r)
-	case "share":
-		err = a.shareMoment(r)
-	default:
-		log.Println(ErrorBadRequest)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-	}
-	if err != nil {
-		genErrorHandler(w, err)
-		return
-	}
-	w.WriteHeader(http.StatusNoContent)
+// builder returns the moment.Builder of one request. A Builder keeps the first error of its
+// constructors, so requests never share one.
+func (a *app) builder() moment.Builder {
+	return a.newBuilder()
 }
 
 func (a *app) postPrivateMoment(r *http.Request) error {
@@ -131,37 +143,47 @@ func (a *app) postPrivateMoment(r *http.Request) error {
 		UserID string
 	}
 	type body struct {
-		Latitude   float32
-		Longitude  float32
-		UserID     string
-		Public     bool
-		Hidden     bool
-		CreateDate time.Time
-		Recipients []recipient
-		Media      []medium
+		Latitude    float32
+		Longitude   float32
+		Public      bool
+		Hidden      bool
+		CreateDate  time.Time
+		ExpiresAt   *time.Time
+		ReleaseDate *time.Time
+		Capacity    *int
+		Recipients  []recipient
+		Media       []medium
+	}
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
 	}
+
 	b := new(body)
 	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
 		return err
 	}
 
-	l := a.c.NewLocation(b.Latitude, b.Longitude)
-	m := a.c.NewMomentsRow(l, b.UserID, b.Public, b.Hidden, &b.CreateDate)
+	c := a.builder()
+	l := c.NewLocation(b.Latitude, b.Longitude)
+	m := c.NewMomentsRow(l, me, b.Public, b.Hidden, &b.CreateDate, b.ExpiresAt, b.ReleaseDate, b.Capacity)
 
 	var ms []*moment.MediaRow
 	for _, md := range b.Media {
-		ms = append(ms, a.c.NewMediaRow(0, md.Message, md.Mtype, ""))
+		ms = append(ms, c.NewMediaRow(0, md.Message, md.Mtype, ""))
 	}
 
 	var fs []*moment.FindsRow
 	for _, r := range b.Recipients {
-		fs = append(fs, a.c.NewFindsRow(0, r.UserID, false, &time.Time{}))
+		fs = append(fs, c.NewFindsRow(0, r.UserID, false, &time.Time{}, nil))
 	}
-	if err := a.c.Err(); err != nil {
+	if err := c.Err(); err != nil {
 		return err
 	}
 
-	if err := a.c.CreatePrivate(moment.DB(), m, ms, fs); err != nil {
+	ctx, cancel := withTimeout(r, a.timeouts.create)
+	defer cancel()
+	if err := a.s.CreatePrivate(ctx, m, ms, fs); err != nil {
 		return err
 	}
 	return nil
@@ -173,282 +195,469 @@ func (a *app) postPublicMoment(r *http.Request) error {
 		Mtype   uint8
 	}
 	type body struct {
-		Latitude   float32
-		Longitude  float32
-		UserID     string
-		Public     bool
-		Hidden     bool
-		CreateDate time.Time
-		Media      []medium
+		Latitude    float32
+		Longitude   float32
+		Public      bool
+		Hidden      bool
+		CreateDate  time.Time
+		ExpiresAt   *time.Time
+		ReleaseDate *time.Time
+		Capacity    *int
+		Media       []medium
+	}
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
 	}
+
 	b := new(body)
 	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
 		return err
 	}
 
-	l := a.c.NewLocation(b.Latitude, b.Longitude)
-	m := a.c.NewMomentsRow(l, b.UserID, b.Public, b.Hidden, &b.CreateDate)
+	c := a.builder()
+	l := c.NewLocation(b.Latitude, b.Longitude)
+	m := c.NewMomentsRow(l, me, b.Public, b.Hidden, &b.CreateDate, b.ExpiresAt, b.ReleaseDate, b.Capacity)
 
 	var ms []*moment.MediaRow
 	for _, md := range b.Media {
-		ms = append(ms, a.c.NewMediaRow(0, md.Message, md.Mtype, ""))
+		ms = append(ms, c.NewMediaRow(0, md.Message, md.Mtype, ""))
 	}
-	if err := a.c.Err(); err != nil {
+	if err := c.Err(); err != nil {
 		return err
 	}
 
-	if err := a.c.CreatePublic(moment.DB(), m, ms); err != nil {
+	ctx, cancel := withTimeout(r, a.timeouts.create)
+	defer cancel()
+	if err := a.s.CreatePublic(ctx, m, ms); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getHiddenMoment(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		latitude  float32
-		longitude float32
-	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+func (a *app) getHiddenMoment(w http.ResponseWriter, r *http.Request, lat float32, long float32, radius float64) error {
+	c := a.builder()
+	l := c.NewLocation(lat, long)
+	if err := c.Err(); err != nil {
 		return err
 	}
-
-	l := a.c.NewLocation(b.latitude, b.longitude)
-	if err := a.c.Err(); err != nil {
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	moments, err := a.c.LocationHidden(moment.DB(), l)
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.LocationHidden(ctx, l, radius, pr)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getLostMoment(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-		Me        string
-	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+func (a *app) getLostMoment(w http.ResponseWriter, r *http.Request, lat float32, long float32, radius float64) error {
+	me, err := caller(w, r)
+	if err != nil {
 		return err
 	}
 
-	l := a.c.NewLocation(b.Latitude, b.Longitude)
-	if err := a.c.Err(); err != nil {
+	c := a.builder()
+	l := c.NewLocation(lat, long)
+	if err := c.Err(); err != nil {
+		return err
+	}
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	moments, err := a.c.LocationLost(moment.DB(), l, b.Me)
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.LocationLost(ctx, l, radius, me, pr)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getSharedMomentbyLocation(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-		Me        string
-	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+func (a *app) getSharedMomentbyLocation(w http.ResponseWriter, r *http.Request, lat float32, long float32, radius float64) error {
+	me, err := caller(w, r)
+	if err != nil {
 		return err
 	}
 
-	l := a.c.NewLocation(b.Latitude, b.Longitude)
-	if err := a.c.Err(); err != nil {
+	c := a.builder()
+	l := c.NewLocation(lat, long)
+	if err := c.Err(); err != nil {
+		return err
+	}
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	moments, err := a.c.LocationShared(moment.DB(), l, b.Me)
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.LocationShared(ctx, l, radius, me, pr)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getSharedMomentbyUser(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		You string
-		Me  string
+func (a *app) getSharedMomentbyUser(w http.ResponseWriter, r *http.Request, you string) error {
+	me, err := caller(w, r)
+	if err != nil {
+		return err
 	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	moments, err := a.c.UserShared(moment.DB(), b.You, b.Me)
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.UserShared(ctx, you, me, pr)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getFoundMoment(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		Me string
+func (a *app) getFoundMoment(w http.ResponseWriter, r *http.Request, owner string) error {
+	me, err := caller(w, r)
+	if err != nil {
+		return err
 	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+	if err = a.p.AuthorizeUserFound(me, owner); err != nil {
 		return err
 	}
 
-	moments, err := a.c.UserFound(moment.DB(), b.Me)
+	pr, err := a.newPageRequest(r)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.UserFound(ctx, me, pr)
+	if err != nil {
+		return err
+	}
+
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getLeftMoment(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		Me string
+// getPendingMoment lists the private time capsules waiting for owner without their content.
+func (a *app) getPendingMoment(w http.ResponseWriter, r *http.Request, owner string) error {
+	me, err := caller(w, r)
+	if err != nil {
+		return err
 	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+	if err = a.p.AuthorizeUserPending(me, owner); err != nil {
 		return err
 	}
 
-	moments, err := a.c.UserLeft(moment.DB(), b.Me)
+	pr, err := a.newPageRequest(r)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.UserPending(ctx, me, pr)
+	if err != nil {
+		return err
+	}
+
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) getPublicMoment(w http.ResponseWriter, r *http.Request) error {
-	type body struct {
-		Latitude  float32
-		Longitude float32
+func (a *app) getLeftMoment(w http.ResponseWriter, r *http.Request, owner string) error {
+	me, err := caller(w, r)
+	if err != nil {
+		return err
 	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+	if err = a.p.AuthorizeUserLeft(me, owner); err != nil {
 		return err
 	}
 
-	l := a.c.NewLocation(b.Latitude, b.Longitude)
-	if err := a.c.Err(); err != nil {
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	moments, err := a.c.LocationPublic(moment.DB(), l)
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.UserLeft(ctx, me, pr)
 	if err != nil {
 		return err
 	}
 
-	if err = json.NewEncoder(w).Encode(moments); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) findPrivateMoment(r *http.Request) error {
-	type body struct {
-		MomentID int64
-		Me       string
+func (a *app) getPublicMoment(w http.ResponseWriter, r *http.Request, lat float32, long float32, radius float64) error {
+	c := a.builder()
+	l := c.NewLocation(lat, long)
+	if err := c.Err(); err != nil {
+		return err
 	}
-	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+	pr, err := a.newPageRequest(r)
+	if err != nil {
 		return err
 	}
 
-	dt := time.Now().UTC()
-	f := a.c.NewFindsRow(b.MomentID, b.Me, true, &dt)
-	if err := a.c.Err(); err != nil {
+	ctx, cancel := withTimeout(r, a.timeouts.list)
+	defer cancel()
+	page, err := a.s.LocationPublic(ctx, l, radius, pr)
+	if err != nil {
 		return err
 	}
 
-	if err := a.c.FindPrivate(moment.DB(), f); err != nil {
+	if err = writeMoments(w, r, page); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) findPublicMoment(r *http.Request) error {
+// newPageRequest reads the limit, cursor and sort query parameters of r.
+func (a *app) newPageRequest(r *http.Request) (*moment.PageRequest, error) {
+	q := queryParams(r)
+	limit := q.optionalInt(paramLimit)
+	if err := q.err(); err != nil {
+		return nil, err
+	}
+
+	c := a.builder()
+	pr := c.NewPageRequest(limit, q.get(paramCursor), moment.Sort(q.get(paramSort)))
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// findAttempt reads where the caller attempts a find from out of the body of r. A body without
+// coordinates yields a nil FindAttempt, which the Store rejects.
+func (a *app) findAttempt(r *http.Request) (*moment.FindAttempt, error) {
 	type body struct {
-		MomentID int64
-		Me       string
+		Latitude  *float32
+		Longitude *float32
+		Accuracy  float64
 	}
 	b := new(body)
-	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+	if err := peekBody(r, b); err != nil {
+		return nil, err
+	}
+	if b.Latitude == nil || b.Longitude == nil {
+		return nil, nil
+	}
+	c := a.builder()
+	f := c.NewFindAttempt(c.NewLocation(*b.Latitude, *b.Longitude), b.Accuracy)
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (a *app) findPrivateMoment(r *http.Request, momentID int64) error {
+	me, err := authenticatedUser(r)
+	if err != nil {
 		return err
 	}
 
+	at, err := a.findAttempt(r)
+	if err != nil {
+		return err
+	}
 	dt := time.Now().UTC()
-	f := a.c.NewFindsRow(b.MomentID, b.Me, true, &dt)
-	if err := a.c.Err(); err != nil {
+	c := a.builder()
+	f := c.NewFindsRow(momentID, me, true, &dt, at)
+	if err := c.Err(); err != nil {
 		return err
 	}
 
-	_, err := a.c.FindPublic(moment.DB(), f)
+	ctx, cancel := withTimeout(r, a.timeouts.find)
+	defer cancel()
+
+	if err := a.p.AuthorizeFindPrivate(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	if err := a.s.FindPrivate(ctx, f); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *app) findPublicMoment(r *http.Request, momentID int64) error {
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+
+	at, err := a.findAttempt(r)
+	if err != nil {
+		return err
+	}
+	dt := time.Now().UTC()
+	c := a.builder()
+	f := c.NewFindsRow(momentID, me, true, &dt, at)
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(r, a.timeouts.find)
+	defer cancel()
+
+	if err := a.p.AuthorizeFindPublic(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	_, err = a.s.FindPublic(ctx, f)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a *app) shareMoment(r *http.Request) error {
+func (a *app) shareMoment(r *http.Request, momentID int64) error {
 	type recipient struct {
 		All       bool
 		Recipient string
 	}
 	type body struct {
-		MomentID   int64
-		UserID     string
 		Recipients []recipient
 	}
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+
 	b := new(body)
 	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
 		return err
 	}
 
-	s := a.c.NewSharesRow(0, b.MomentID, b.UserID)
+	c := a.builder()
+	s := c.NewSharesRow(0, momentID, me)
 	var rs []*moment.RecipientsRow
 	for _, r := range b.Recipients {
-		rs = append(rs, a.c.NewRecipientsRow(0, r.All, r.Recipient))
+		rs = append(rs, c.NewRecipientsRow(0, r.All, r.Recipient))
 	}
-	if err := a.c.Err(); err != nil {
+	if err := c.Err(); err != nil {
 		return err
 	}
 
-	err := a.c.Share(moment.DB(), s, rs)
+	ctx, cancel := withTimeout(r, a.timeouts.share)
+	defer cancel()
+
+	if err = a.p.AuthorizeShare(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	err = a.s.Share(ctx, s, rs)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func genErrorHandler(w http.ResponseWriter, err error) {
+func (a *app) editMoment(r *http.Request, momentID int64) error {
+	type medium struct {
+		Message string
+	}
+	type body struct {
+		Hidden bool
+		Media  []medium
+	}
+	me, err := authenticatedUser(r)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return err
+	}
+
+	b := new(body)
+	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+		return err
+	}
+
+	var messages []string
+	for _, md := range b.Media {
+		messages = append(messages, md.Message)
+	}
+	c := a.builder()
+	e := c.NewMomentEdit(momentID, b.Hidden, messages)
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(r, a.timeouts.edit)
+	defer cancel()
+
+	if err = a.p.AuthorizeEdit(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	if err = a.s.Edit(ctx, e); err != nil {
+		return err
 	}
+	return nil
+}
+
+func (a *app) deleteMoment(r *http.Request, momentID int64) error {
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(r, a.timeouts.delete)
+	defer cancel()
+
+	if err = a.p.AuthorizeDelete(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	if err = a.s.Delete(ctx, momentID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *app) restoreMoment(r *http.Request, momentID int64) error {
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(r, a.timeouts.delete)
+	defer cancel()
+
+	if err = a.p.AuthorizeDelete(ctx, a.s, me, momentID); err != nil {
+		return err
+	}
+	if err = a.s.Restore(ctx, momentID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return nil
 }
@@ -2,12 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/penutty/Moment-Service/moment"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -53,33 +55,10 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func Test_momentHandler(t *testing.T) {
-	type test struct {
-		method         string
-		expectedStatus int
-	}
-	tests := []test{
-		test{http.MethodGet, http.StatusBadRequest},
-		test{http.MethodPost, http.StatusBadRequest},
-		test{http.MethodPatch, http.StatusBadRequest},
-		test{http.MethodDelete, http.StatusNotImplemented},
-	}
-
-	for _, v := range tests {
-		req := httptest.NewRequest(v.method, MomentEndpoint, nil)
-		rec := httptest.NewRecorder()
-
-		a := MockApp()
-		a.momentHandler(rec, req)
-		assert.Exactly(t, v.expectedStatus, rec.Code)
-	}
-}
-
 func Test_postPrivateMoment(t *testing.T) {
 	type body struct {
 		Latitude   float32
 		Longitude  float32
-		UserID     string
 		Public     bool
 		Hidden     bool
 		CreateDate time.Time
@@ -91,13 +70,13 @@ func Test_postPrivateMoment(t *testing.T) {
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong, tUser, false, false, time.Now().UTC(), defaultMedia, defaultRecipients}, nil},
+		test{body{tLat, tLong, false, false, time.Now().UTC(), defaultMedia, defaultRecipients}, nil},
 	}
 
 	for _, v := range tests {
 		j, err := json.Marshal(v.b)
 		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodPost, MomentEndpoint, bytes.NewReader(j))
+		req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, bytes.NewReader(j)), tUser)
 
 		a := MockApp()
 		err = a.postPrivateMoment(req)
@@ -109,7 +88,6 @@ func Test_postPublicMoment(t *testing.T) {
 	type body struct {
 		Latitude   float32
 		Longitude  float32
-		UserID     string
 		Public     bool
 		Hidden     bool
 		CreateDate time.Time
@@ -120,13 +98,13 @@ func Test_postPublicMoment(t *testing.T) {
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong, tUser, false, false, time.Now().UTC(), defaultMedia}, nil},
+		test{body{tLat, tLong, false, false, time.Now().UTC(), defaultMedia}, nil},
 	}
 
 	for _, v := range tests {
 		j, err := json.Marshal(v.b)
 		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodPost, MomentEndpoint, bytes.NewReader(j))
+		req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, bytes.NewReader(j)), tUser)
 
 		a := MockApp()
 		err = a.postPublicMoment(req)
@@ -135,226 +113,209 @@ func Test_postPublicMoment(t *testing.T) {
 }
 
 func Test_getHiddenMoment(t *testing.T) {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-	}
+	req := httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil)
+	rec := httptest.NewRecorder()
+
+	a := MockApp()
+	err := a.getHiddenMoment(rec, req, tLat, tLong, 0)
+	assert.Nil(t, err)
+}
+
+func Test_getLostMoment(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong}, nil},
+		test{tUser, nil},
+		test{"", ErrorUnauthorized},
 	}
-
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
+		req := asUser(httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
 
 		a := MockApp()
-		err = a.getHiddenMoment(rec, req)
-		assert.Exactly(t, v.expected, err)
+		err := a.getLostMoment(rec, req, tLat, tLong, 0)
+		assert.Equal(t, v.expected, err)
 	}
 }
 
-func Test_getLostMoment(t *testing.T) {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-		UserID    string
-	}
+func Test_getSharedMomentbyLocation(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong, tUser}, nil},
+		test{tUser, nil},
+		test{"", ErrorUnauthorized},
 	}
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
-		assert.Nil(t, err)
-
+		req := asUser(httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
+
 		a := MockApp()
-		err = a.getLostMoment(rec, req)
-		assert.Exactly(t, v.expected, err)
+		err := a.getSharedMomentbyLocation(rec, req, tLat, tLong, 0)
+		assert.Equal(t, v.expected, err)
 	}
 }
 
-func Test_getSharedMomentbyLocation(t *testing.T) {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-		UserID    string
-	}
+func Test_getSharedMomentbyUser(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong, tUser}, nil},
+		test{tUser1, nil},
+		test{"", ErrorUnauthorized},
 	}
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
-		assert.Nil(t, err)
-
+		req := asUser(httptest.NewRequest(http.MethodGet, UsersEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
+
 		a := MockApp()
-		err = a.getSharedMomentbyLocation(rec, req)
-		assert.Exactly(t, v.expected, err)
+		err := a.getSharedMomentbyUser(rec, req, tUser)
+		assert.Equal(t, v.expected, err)
 	}
 }
 
-func Test_getSharedMomentbyUser(t *testing.T) {
-	type body struct {
-		You string
-		Me  string
-	}
+func Test_getFoundMoment(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
+		owner    string
 		expected error
 	}
 	tests := []test{
-		test{body{tUser, tUser1}, nil},
+		test{tUser, tUser, nil},
+		test{tUser, tUser2, moment.ErrorUserFoundForbidden},
+		test{"", tUser, ErrorUnauthorized},
 	}
 
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
-		assert.Nil(t, err)
-
+		req := asUser(httptest.NewRequest(http.MethodGet, UsersEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
+
 		a := MockApp()
-		err = a.getSharedMomentbyUser(rec, req)
+		err := a.getFoundMoment(rec, req, v.owner)
 		assert.Exactly(t, v.expected, err)
 	}
 }
 
-func Test_getFoundMoment(t *testing.T) {
-	type body struct {
-		Me string
-	}
+func Test_getLeftMoment(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
+		owner    string
 		expected error
 	}
 	tests := []test{
-		test{body{tUser}, nil},
+		test{tUser, tUser, nil},
+		test{tUser, tUser2, moment.ErrorUserLeftForbidden},
+		test{"", tUser, ErrorUnauthorized},
 	}
 
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
-		assert.Nil(t, err)
-
+		req := asUser(httptest.NewRequest(http.MethodGet, UsersEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
+
 		a := MockApp()
-		err = a.getFoundMoment(rec, req)
+		err := a.getLeftMoment(rec, req, v.owner)
 		assert.Exactly(t, v.expected, err)
 	}
 }
 
-func Test_getLeftMoment(t *testing.T) {
-	type body struct {
-		Me string
-	}
+func Test_getPendingMoment(t *testing.T) {
 	type test struct {
-		req      body
+		me       string
+		owner    string
 		expected error
 	}
 	tests := []test{
-		test{body{tUser}, nil},
+		test{tUser, tUser, nil},
+		test{tUser, tUser2, moment.ErrorUserPendingForbidden},
+		test{"", tUser, ErrorUnauthorized},
 	}
 
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
-
+		req := asUser(httptest.NewRequest(http.MethodGet, UsersEndpoint, nil), v.me)
 		rec := httptest.NewRecorder()
+
 		a := MockApp()
-		err = a.getLeftMoment(rec, req)
+		err := a.getPendingMoment(rec, req, v.owner)
 		assert.Exactly(t, v.expected, err)
 	}
 }
 
 func Test_getPublicMoment(t *testing.T) {
-	type body struct {
-		Latitude  float32
-		Longitude float32
-	}
+	req := httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil)
+	rec := httptest.NewRecorder()
+
+	a := MockApp()
+	err := a.getPublicMoment(rec, req, tLat, tLong, 0)
+	assert.Nil(t, err)
+}
+
+// Test_newPageRequest reads every page request with the same app, so that an invalid page
+// request that left its error behind would fail the requests after it.
+func Test_newPageRequest(t *testing.T) {
 	type test struct {
-		req      body
+		target   string
 		expected error
 	}
 	tests := []test{
-		test{body{tLat, tLong}, nil},
+		test{"/?cursor=garbage", moment.ErrorCursor},
+		test{"/?limit=1", nil},
+		test{"/?limit=500", moment.ErrorPageLimit},
+		test{"/", nil},
 	}
 
+	a := MockApp()
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
+		req := httptest.NewRequest(http.MethodGet, v.target, nil)
 
-		rec := httptest.NewRecorder()
-		a := MockApp()
-		err = a.getPublicMoment(rec, req)
-		assert.Exactly(t, v.expected, err)
+		_, err := a.newPageRequest(req)
+		assert.Exactly(t, v.expected, err, v.target)
 	}
 }
 
 func Test_findPrivateMoment(t *testing.T) {
-	type body struct {
-		MomentID int64
-		Me       string
-	}
 	type test struct {
-		req      body
+		me       string
+		denied   error
 		expected error
 	}
 	tests := []test{
-		test{body{tMomentID, tUser}, nil},
+		test{tUser, nil, nil},
+		test{tUser, moment.ErrorFindPrivateForbidden, moment.ErrorFindPrivateForbidden},
+		test{"", nil, ErrorUnauthorized},
 	}
 
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
+		req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, nil), v.me)
 
 		a := MockApp()
-		err = a.findPrivateMoment(req)
+		a.p.(*MockPolicy).err = v.denied
+		err := a.findPrivateMoment(req, tMomentID)
 		assert.Exactly(t, v.expected, err)
 	}
 }
 
 func Test_findPublicMoment(t *testing.T) {
-	type body struct {
-		MomentID int64
-		Me       string
-	}
 	type test struct {
-		req      body
+		me       string
+		denied   error
 		expected error
 	}
 	tests := []test{
-		test{body{tMomentID, tUser}, nil},
+		test{tUser, nil, nil},
+		test{tUser, moment.ErrorMomentDNE, moment.ErrorMomentDNE},
+		test{"", nil, ErrorUnauthorized},
 	}
 
 	for _, v := range tests {
-		reqJson, err := json.Marshal(v.req)
-		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
+		req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, nil), v.me)
 
 		a := MockApp()
-		err = a.findPublicMoment(req)
+		a.p.(*MockPolicy).err = v.denied
+		err := a.findPublicMoment(req, tMomentID)
 		assert.Exactly(t, v.expected, err)
 	}
 }
@@ -365,73 +326,197 @@ func Test_shareMoment(t *testing.T) {
 		Recipient string
 	}
 	type body struct {
-		MomentID   int64
-		UserID     string
 		Recipients []recipient
 	}
 	type test struct {
 		req      body
+		denied   error
 		expected error
 	}
 	tests := []test{
 		test{body{
-			tMomentID,
-			tUser,
 			[]recipient{recipient{false, tUser1}, recipient{false, tUser2}},
-		}, nil},
+		}, nil, nil},
+		test{body{
+			[]recipient{recipient{false, tUser1}},
+		}, moment.ErrorShareForbidden, moment.ErrorShareForbidden},
 	}
 
 	for _, v := range tests {
 		reqJson, err := json.Marshal(v.req)
 		assert.Nil(t, err)
-		req := httptest.NewRequest(http.MethodGet, MomentEndpoint, bytes.NewReader(reqJson))
+		req := asUser(httptest.NewRequest(http.MethodPost, MomentsEndpoint, bytes.NewReader(reqJson)), tUser)
 
-		t.Logf("%s\n", v)
+		t.Logf("%v\n", v)
 		a := MockApp()
-		err = a.shareMoment(req)
+		a.p.(*MockPolicy).err = v.denied
+		err = a.shareMoment(req, tMomentID)
 		assert.Exactly(t, v.expected, err)
 	}
 }
 
-func MockApp() *app {
-	c := new(MockClient)
-	c.c = new(moment.MomentClient)
+// Test_storeFromEnv expects the mssql store to fail at startup, since no Moment-Db answers
+// the tests.
+func Test_storeFromEnv(t *testing.T) {
+	type test struct {
+		store string
+		ok    bool
+		pool  bool
+	}
+	tests := []test{
+		test{"", false, false},
+		test{"mssql", false, false},
+		test{"sqlite", true, true},
+		test{"memory", true, false},
+		test{"oracle", false, false},
+	}
+
+	t.Setenv("MomentSQLitePath", filepath.Join(t.TempDir(), "moment.db"))
+	t.Setenv("MomentDBPingAttempts", "1")
+	for _, v := range tests {
+		t.Setenv("MomentStore", v.store)
+		s, db, err := storeFromEnv(context.Background())
+		assert.Equal(t, v.ok, err == nil, v.store)
+		assert.Equal(t, v.ok, s != nil, v.store)
+		assert.Equal(t, v.pool, db != nil, v.store)
+		if db != nil {
+			db.Close()
+		}
+	}
+
+	t.Setenv("MomentStore", "oracle")
+	_, _, err := storeFromEnv(context.Background())
+	assert.Exactly(t, ErrorStoreUnknown, err)
+
+	t.Setenv("MomentStore", "memory")
+	t.Setenv("MomentDBPingAttempts", "0")
+	_, _, err = storeFromEnv(context.Background())
+	assert.Exactly(t, ErrorPoolInvalid, err)
+}
+
+// Test_routesMemoryStore creates and finds a public moment through the API of an app backed by
+// a moment.MemoryStore and reads it back.
+func Test_routesMemoryStore(t *testing.T) {
+	a := MockApp()
+	a.newBuilder = func() moment.Builder { return new(moment.MomentClient) }
+	a.s = moment.NewMemoryStore()
+	a.p = new(moment.Policy)
+
+	type test struct {
+		method   string
+		path     string
+		user     string
+		body     string
+		expected int
+	}
+	find := `{"Latitude":1,"Longitude":1,"Accuracy":5}`
+	tests := []test{
+		test{http.MethodPost, "/moments", tUser, `{"Latitude":1,"Longitude":1,"Public":true,"CreateDate":"2017-06-01T12:00:00Z","Media":[{"Message":"Hello.","Mtype":0}]}`, http.StatusCreated},
+		test{http.MethodPost, "/moments/1/finds", tUser2, `{"Latitude":1.01,"Longitude":1,"Accuracy":5}`, http.StatusForbidden},
+		test{http.MethodPost, "/moments/1/finds", tUser2, ``, http.StatusUnprocessableEntity},
+		test{http.MethodPost, "/moments/1/finds", tUser2, `{"Latitude":200,"Longitude":1,"Accuracy":5}`, http.StatusUnprocessableEntity},
+		test{http.MethodPost, "/moments/1/finds", tUser2, find, http.StatusCreated},
+		test{http.MethodPost, "/moments/1/finds", tUser2, find, http.StatusConflict},
+		test{http.MethodPost, "/moments/2/finds", tUser2, find, http.StatusNotFound},
+		test{http.MethodPost, "/moments/1/shares", tUser3, `{"Recipients":[{"All":true}]}`, http.StatusForbidden},
+		test{http.MethodPost, "/moments/1/shares", tUser2, `{"Recipients":[{"All":true}]}`, http.StatusCreated},
+		test{http.MethodPut, "/moments/1", tUser2, `{"Media":[{"Message":"Edited."}]}`, http.StatusForbidden},
+		test{http.MethodPut, "/moments/1", tUser, `{"Media":[]}`, http.StatusUnprocessableEntity},
+		test{http.MethodPut, "/moments/1", tUser, `{"Media":[{"Message":"Edited."},{"Message":"Twice."}]}`, http.StatusUnprocessableEntity},
+		test{http.MethodPut, "/moments/1", tUser, `{"Media":[{"Message":"Edited."}]}`, http.StatusNoContent},
+		test{http.MethodDelete, "/moments/1", tUser2, ``, http.StatusForbidden},
+		test{http.MethodDelete, "/moments/1", tUser, ``, http.StatusNoContent},
+		test{http.MethodPost, "/moments/1/finds", tUser3, find, http.StatusNotFound},
+		test{http.MethodPut, "/moments/1", tUser, `{"Media":[{"Message":"Deleted."}]}`, http.StatusNotFound},
+		test{http.MethodPost, "/moments/1/restore", tUser, ``, http.StatusNoContent},
+		test{http.MethodPost, "/moments/1/restore", tUser, ``, http.StatusNotFound},
+		test{http.MethodPost, "/moments", tUser, `{"Latitude":1,"Longitude":1,"Public":true,"Capacity":1,"CreateDate":"2017-06-01T12:00:00Z","Media":[{"Message":"Once.","Mtype":0}]}`, http.StatusCreated},
+		test{http.MethodPost, "/moments/2/finds", tUser2, find, http.StatusCreated},
+		test{http.MethodPost, "/moments/2/finds", tUser3, find, http.StatusConflict},
+		test{http.MethodPost, "/moments", tUser, `{"Latitude":1,"Longitude":1,"Capacity":1,"CreateDate":"2017-06-01T12:00:00Z","Recipients":[{"UserID":"` + tUser3 + `"}],"Media":[{"Message":"Private.","Mtype":0}]}`, http.StatusUnprocessableEntity},
+		test{http.MethodGet, "/users/" + tUser + "/moments/left?cursor=garbage", tUser, ``, http.StatusUnprocessableEntity},
+		test{http.MethodPost, "/moments", tUser, `{"Latitude":1,"Longitude":1,"CreateDate":"2017-06-01T12:00:00Z","Recipients":[{"UserID":"` + tUser3 + `"}],"Media":[{"Message":"Private.","Mtype":0}]}`, http.StatusCreated},
+		test{http.MethodPost, "/moments/3/finds", tUser2, find, http.StatusNotFound},
+		test{http.MethodPost, "/moments/3/finds", tUser3, find, http.StatusNotFound},
+		test{http.MethodPost, "/moments/3/finds", tUser2, `{"Private":true,"Latitude":1,"Longitude":1,"Accuracy":5}`, http.StatusForbidden},
+		test{http.MethodPost, "/moments/3/finds", tUser3, `{"Private":true,"Latitude":1,"Longitude":1,"Accuracy":5}`, http.StatusCreated},
+	}
+	for _, v := range tests {
+		req := httptest.NewRequest(v.method, v.path, bytes.NewBufferString(v.body))
+		req.Header.Set("Authorization", bearer(t, v.user))
+		rec := httptest.NewRecorder()
+
+		a.routes().ServeHTTP(rec, req)
+		assert.Exactly(t, v.expected, rec.Code, v.method+" "+v.path+": "+rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/users/"+tUser2+"/moments/found", nil)
+	req.Header.Set("Authorization", bearer(t, tUser2))
+	rec := httptest.NewRecorder()
 
+	a.routes().ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"message":"Edited."`)
+}
+
+func MockApp() *app {
 	a := new(app)
-	a.c = c
+	a.newBuilder = func() moment.Builder { return &MockClient{c: new(moment.MomentClient)} }
+	a.s = new(MockStore)
+	a.p = &MockPolicy{p: new(moment.Policy)}
+	a.auth = MockAuthenticator()
 	return a
 }
 
-type MockClient struct {
-	c moment.Client
+// MockPolicy applies moment.Policy's user rules and answers every store backed rule with err.
+type MockPolicy struct {
+	p   moment.Authorizer
+	err error
 }
 
-func (mc *MockClient) Err() error {
-	return mc.c.Err()
+func (mp *MockPolicy) AuthorizeShare(ctx context.Context, s moment.Store, caller string, momentID int64) error {
+	return mp.err
 }
 
-func (mc *MockClient) FindPublic(db moment.DbRunner, f *moment.FindsRow) (int64, error) {
-	return 1, nil
+func (mp *MockPolicy) AuthorizeFindPublic(ctx context.Context, s moment.Store, caller string, momentID int64) error {
+	return mp.err
 }
 
-func (mc *MockClient) FindPrivate(db moment.DbRunner, f *moment.FindsRow) error {
-	return nil
+func (mp *MockPolicy) AuthorizeFindPrivate(ctx context.Context, s moment.Store, caller string, momentID int64) error {
+	return mp.err
 }
 
-func (mc *MockClient) Share(db moment.DbRunnerTrans, s *moment.SharesRow, ms []*moment.RecipientsRow) error {
-	return nil
+func (mp *MockPolicy) AuthorizeEdit(ctx context.Context, s moment.Store, caller string, momentID int64) error {
+	return mp.err
 }
 
-func (mc *MockClient) CreatePublic(db moment.DbRunnerTrans, m *moment.MomentsRow, ms []*moment.MediaRow) error {
-	return nil
+func (mp *MockPolicy) AuthorizeDelete(ctx context.Context, s moment.Store, caller string, momentID int64) error {
+	return mp.err
 }
 
-func (mc *MockClient) CreatePrivate(db moment.DbRunnerTrans, m *moment.MomentsRow, ms []*moment.MediaRow, fs []*moment.FindsRow) error {
-	return nil
+func (mp *MockPolicy) AuthorizeUserLeft(caller string, owner string) error {
+	return mp.p.AuthorizeUserLeft(caller, owner)
+}
+
+func (mp *MockPolicy) AuthorizeUserFound(caller string, owner string) error {
+	return mp.p.AuthorizeUserFound(caller, owner)
+}
+
+func (mp *MockPolicy) AuthorizeUserPending(caller string, owner string) error {
+	return mp.p.AuthorizeUserPending(caller, owner)
+}
+
+// MockClient builds rows with a moment.MomentClient.
+type MockClient struct {
+	c moment.Builder
+}
+
+func (mc *MockClient) Err() error {
+	return mc.c.Err()
 }
 
-func (mc *MockClient) NewMomentsRow(l *moment.Location, userID string, public bool, hidden bool, createDate *time.Time) *moment.MomentsRow {
-	return mc.c.NewMomentsRow(l, userID, public, hidden, createDate)
+func (mc *MockClient) NewMomentsRow(l *moment.Location, userID string, public bool, hidden bool, createDate *time.Time, expiresAt *time.Time, releaseDate *time.Time, capacity *int) *moment.MomentsRow {
+	return mc.c.NewMomentsRow(l, userID, public, hidden, createDate, expiresAt, releaseDate, capacity)
 }
 
 func (mc *MockClient) NewLocation(lat float32, long float32) *moment.Location {
@@ -442,42 +527,119 @@ func (mc *MockClient) NewMediaRow(momentID int64, userID string, mtype uint8, di
 	return mc.c.NewMediaRow(momentID, userID, mtype, dir)
 }
 
-func (mc *MockClient) NewFindsRow(momentID int64, userID string, found bool, findDate *time.Time) *moment.FindsRow {
-	return mc.c.NewFindsRow(momentID, userID, found, findDate)
+func (mc *MockClient) NewFindsRow(momentID int64, userID string, found bool, findDate *time.Time, attempt *moment.FindAttempt) *moment.FindsRow {
+	return mc.c.NewFindsRow(momentID, userID, found, findDate, attempt)
+}
+
+func (mc *MockClient) NewFindAttempt(l *moment.Location, accuracy float64) *moment.FindAttempt {
+	return mc.c.NewFindAttempt(l, accuracy)
 }
 
 func (mc *MockClient) NewSharesRow(sharesID int64, momentID int64, userID string) *moment.SharesRow {
 	return mc.c.NewSharesRow(sharesID, momentID, userID)
 }
 
+func (mc *MockClient) NewPageRequest(limit int, cursor string, s moment.Sort) *moment.PageRequest {
+	return mc.c.NewPageRequest(limit, cursor, s)
+}
+
+func (mc *MockClient) NewIdempotencyKeysRow(userID string, key string, hash []byte, createDate *time.Time) *moment.IdempotencyKeysRow {
+	return mc.c.NewIdempotencyKeysRow(userID, key, hash, createDate)
+}
+
+func (mc *MockClient) NewMomentEdit(momentID int64, hidden bool, messages []string) *moment.MomentEdit {
+	return mc.c.NewMomentEdit(momentID, hidden, messages)
+}
+
 func (mc *MockClient) NewRecipientsRow(sharesID int64, all bool, recipientID string) *moment.RecipientsRow {
 	return mc.c.NewRecipientsRow(sharesID, all, recipientID)
 }
 
-func (mc *MockClient) LocationShared(db moment.DbRunner, l *moment.Location, me string) ([]*moment.Moment, error) {
-	return nil, nil
+// MockStore accepts every modification and answers every selector with a page of moments whose
+// Next cursor is next. LocationPublic records the context it was called with in ctx.
+type MockStore struct {
+	moments []*moment.Moment
+	next    string
+	ctx     context.Context
+}
+
+func (ms *MockStore) FindPublic(ctx context.Context, f *moment.FindsRow) (int64, error) {
+	return 1, nil
+}
+
+func (ms *MockStore) FindPrivate(ctx context.Context, f *moment.FindsRow) error {
+	return nil
+}
+
+func (ms *MockStore) Share(ctx context.Context, s *moment.SharesRow, rs []*moment.RecipientsRow) error {
+	return nil
+}
+
+func (ms *MockStore) CreatePublic(ctx context.Context, m *moment.MomentsRow, mds []*moment.MediaRow) error {
+	return nil
+}
+
+func (ms *MockStore) CreatePrivate(ctx context.Context, m *moment.MomentsRow, mds []*moment.MediaRow, fs []*moment.FindsRow) error {
+	return nil
+}
+
+func (ms *MockStore) Edit(ctx context.Context, e *moment.MomentEdit) error {
+	return nil
+}
+
+func (ms *MockStore) Delete(ctx context.Context, id int64, at time.Time) error {
+	return nil
+}
+
+func (ms *MockStore) Restore(ctx context.Context, id int64, at time.Time) error {
+	return nil
+}
+
+func (ms *MockStore) LocationShared(ctx context.Context, l *moment.Location, radius float64, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
+}
+
+func (ms *MockStore) LocationPublic(ctx context.Context, l *moment.Location, radius float64, pr *moment.PageRequest) (*moment.Page, error) {
+	ms.ctx = ctx
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
+}
+
+func (ms *MockStore) LocationHidden(ctx context.Context, l *moment.Location, radius float64, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
+}
+
+func (ms *MockStore) LocationLost(ctx context.Context, l *moment.Location, radius float64, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
+}
+
+func (ms *MockStore) UserShared(ctx context.Context, you string, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
+}
+
+func (ms *MockStore) UserLeft(ctx context.Context, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
 }
 
-func (mc *MockClient) LocationPublic(db moment.DbRunner, l *moment.Location) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) UserFound(ctx context.Context, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
 }
 
-func (mc *MockClient) LocationHidden(db moment.DbRunner, l *moment.Location) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) UserPending(ctx context.Context, me string, pr *moment.PageRequest) (*moment.Page, error) {
+	return &moment.Page{Moments: ms.moments, Next: ms.next}, nil
 }
 
-func (mc *MockClient) LocationLost(db moment.DbRunner, l *moment.Location, me string) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) IsAuthor(ctx context.Context, user string, id int64) (bool, error) {
+	return false, nil
 }
 
-func (mc *MockClient) UserShared(db moment.DbRunner, you string, me string) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) HasFound(ctx context.Context, user string, id int64) (bool, error) {
+	return false, nil
 }
 
-func (mc *MockClient) UserLeft(db moment.DbRunner, me string) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) IsRecipient(ctx context.Context, user string, id int64) (bool, error) {
+	return false, nil
 }
 
-func (mc *MockClient) UserFound(db moment.DbRunner, me string) ([]*moment.Moment, error) {
-	return nil, nil
+func (ms *MockStore) IsPublic(ctx context.Context, id int64) (bool, error) {
+	return true, nil
 }
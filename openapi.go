@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIEndpoint serves the OpenAPI 3 document describing every operation of the resource tree,
+// LegacyMomentEndpoint and PoolStatsEndpoint.
+const OpenAPIEndpoint = "/openapi.json"
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// serveOpenAPI writes openAPIDocument. It is served without authentication.
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// openAPI is the subset of an OpenAPI 3.0 document that specValidator understands.
+type openAPI struct {
+	Servers    []server             `json:"servers"`
+	Paths      map[string]*pathItem `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+		Responses  map[string]*response  `json:"responses"`
+	} `json:"components"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+// serverPrefixes returns the path prefixes of ss, or the empty prefix if there are none.
+func serverPrefixes(ss []server) []string {
+	var prefixes []string
+	for _, s := range ss {
+		prefixes = append(prefixes, strings.TrimSuffix(s.URL, "/"))
+	}
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	return prefixes
+}
+
+// pathItem is a path of the document. Servers, if any, replace the servers of the document
+// for its operations, such as for a path that only some versions serve.
+type pathItem struct {
+	Servers    []server     `json:"servers"`
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Patch      *operation   `json:"patch"`
+}
+
+// operations returns the operations of p keyed by HTTP method.
+func (p *pathItem) operations() map[string]*operation {
+	ops := make(map[string]*operation)
+	for m, op := range map[string]*operation{
+		http.MethodGet:    p.Get,
+		http.MethodPut:    p.Put,
+		http.MethodPost:   p.Post,
+		http.MethodDelete: p.Delete,
+		http.MethodPatch:  p.Patch,
+	} {
+		if op != nil {
+			ops[m] = op
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *schema     `json:"schema"`
+	Example  interface{} `json:"example"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema  *schema     `json:"schema"`
+	Example interface{} `json:"example"`
+}
+
+// schema is the subset of the OpenAPI 3.0 schema object that specValidator enforces.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Nullable             bool               `json:"nullable"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+}
+
+const (
+	refSchemas    = "#/components/schemas/"
+	refParameters = "#/components/parameters/"
+	refResponses  = "#/components/responses/"
+)
+
+// specValidator checks requests, and optionally responses, against an OpenAPI document.
+type specValidator struct {
+	doc      *openAPI
+	prefixes []string
+
+	// responseError is called with every response that does not match the document.
+	// Responses are not checked when it is nil.
+	responseError func(r *http.Request, err error)
+}
+
+func newSpecValidator(doc []byte) (*specValidator, error) {
+	sv := &specValidator{doc: new(openAPI)}
+	if err := json.Unmarshal(doc, sv.doc); err != nil {
+		return nil, err
+	}
+	sv.prefixes = serverPrefixes(sv.doc.Servers)
+	return sv, nil
+}
+
+// middleware rejects requests that do not match the document with 400 and, if responseError
+// is set, reports every response that does not match the document.
+func (sv *specValidator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := sv.validateRequest(r); err != nil {
+			genErrorHandler(w, r, err)
+			return
+		}
+		if sv.responseError == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if err := sv.validateResponse(r, rec.status, rec.header, rec.body.Bytes()); err != nil {
+			sv.responseError(r, err)
+		}
+
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+// bufferedResponse holds a response until it has been validated.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// itemPrefixes returns the path prefixes that item is served under.
+func (sv *specValidator) itemPrefixes(item *pathItem) []string {
+	if len(item.Servers) == 0 {
+		return sv.prefixes
+	}
+	return serverPrefixes(item.Servers)
+}
+
+// match returns the path template, path item and path parameters that serve path. A path
+// item only matches under the prefixes of its own servers.
+func (sv *specValidator) match(path string) (string, *pathItem, map[string]string) {
+	for _, prefix := range sv.prefixes {
+		if prefix != "" && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		seg := pathSegments(strings.TrimPrefix(path, prefix), "")
+
+		templates := make([]string, 0, len(sv.doc.Paths))
+		for t := range sv.doc.Paths {
+			templates = append(templates, t)
+		}
+		sort.Strings(templates)
+
+	Templates:
+		for _, t := range templates {
+			tseg := pathSegments(t, "")
+			if len(tseg) != len(seg) {
+				continue
+			}
+			values := make(map[string]string)
+			for i, s := range tseg {
+				switch {
+				case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+					values[s[1:len(s)-1]] = seg[i]
+				case s != seg[i]:
+					continue Templates
+				}
+			}
+			item := sv.doc.Paths[t]
+			for _, p := range sv.itemPrefixes(item) {
+				if p == prefix {
+					return t, item, values
+				}
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// parameters returns the parameters of op, including those inherited from item.
+func (sv *specValidator) parameters(item *pathItem, op *operation) []*parameter {
+	byKey := make(map[string]*parameter)
+	var keys []string
+	for _, p := range append(append([]*parameter{}, item.Parameters...), op.Parameters...) {
+		p = sv.parameter(p)
+		k := p.In + ":" + p.Name
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = p
+	}
+
+	ps := make([]*parameter, len(keys))
+	for i, k := range keys {
+		ps[i] = byKey[k]
+	}
+	return ps
+}
+
+func (sv *specValidator) parameter(p *parameter) *parameter {
+	for p.Ref != "" {
+		p = sv.doc.Components.Parameters[strings.TrimPrefix(p.Ref, refParameters)]
+	}
+	return p
+}
+
+func (sv *specValidator) response(rs *response) *response {
+	for rs.Ref != "" {
+		rs = sv.doc.Components.Responses[strings.TrimPrefix(rs.Ref, refResponses)]
+	}
+	return rs
+}
+
+func (sv *specValidator) schema(s *schema) *schema {
+	for s.Ref != "" {
+		s = sv.doc.Components.Schemas[strings.TrimPrefix(s.Ref, refSchemas)]
+	}
+	return s
+}
+
+// validateRequest returns paramErrors naming every parameter and body field of r that does
+// not match its operation, or the error of reading its body. Requests for paths or methods the
+// document does not describe are left for the resource tree to reject.
+func (sv *specValidator) validateRequest(r *http.Request) error {
+	_, item, values := sv.match(r.URL.Path)
+	if item == nil {
+		return nil
+	}
+	op := item.operations()[r.Method]
+	if op == nil {
+		return nil
+	}
+
+	var errs paramErrors
+	q := r.URL.Query()
+	for _, p := range sv.parameters(item, op) {
+		var raw string
+		var ok bool
+		switch p.In {
+		case "path":
+			raw, ok = values[p.Name]
+		case "query":
+			raw, ok = q.Get(p.Name), q.Has(p.Name)
+		case "header":
+			raw = r.Header.Get(p.Name)
+			ok = raw != ""
+		default:
+			continue
+		}
+		if !ok {
+			if p.Required {
+				errs = append(errs, paramError{p.Name, "is required"})
+			}
+			continue
+		}
+		errs = append(errs, sv.validate(p.Schema, coerce(sv.schema(p.Schema), raw), p.Name)...)
+	}
+
+	if op.RequestBody != nil {
+		bodyErrs, err := sv.validateBody(r, op.RequestBody)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, bodyErrs...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateBody returns the paramErrors of the body of r, or the error of reading it, such as
+// the http.MaxBytesError of a body larger than maxBodyBytes.
+func (sv *specValidator) validateBody(r *http.Request, rb *requestBody) (paramErrors, error) {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+
+	if len(bytes.TrimSpace(buf)) == 0 {
+		if rb.Required {
+			return paramErrors{paramError{"body", "is required"}}, nil
+		}
+		return nil, nil
+	}
+	mt := rb.Content["application/json"]
+	if mt == nil || mt.Schema == nil {
+		return nil, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return paramErrors{paramError{"body", "is not valid JSON"}}, nil
+	}
+	return sv.validate(mt.Schema, v, "body"), nil
+}
+
+// validateResponse returns an error if status, the Content-Type in h or body are not what the
+// document declares for the operation that served r.
+func (sv *specValidator) validateResponse(r *http.Request, status int, h http.Header, body []byte) error {
+	t, item, _ := sv.match(r.URL.Path)
+	var op *operation
+	if item != nil {
+		op = item.operations()[r.Method]
+	}
+	if op == nil {
+		if status < http.StatusMultipleChoices {
+			return fmt.Errorf("%s %s answered %d but is not described by the document", r.Method, r.URL.Path, status)
+		}
+		return nil
+	}
+
+	rs, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if rs, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("%s %s: status %d is not documented", r.Method, t, status)
+		}
+	}
+	rs = sv.response(rs)
+
+	if len(rs.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s: status %d must not have a body", r.Method, t, status)
+		}
+		return nil
+	}
+
+	ct, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%s %s: status %d has no valid Content-Type", r.Method, t, status)
+	}
+	mt, ok := rs.Content[ct]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d does not document Content-Type %s", r.Method, t, status, ct)
+	}
+	if mt.Schema == nil {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("%s %s: status %d body is not valid JSON", r.Method, t, status)
+	}
+	if errs := sv.validate(mt.Schema, v, "body"); len(errs) > 0 {
+		return fmt.Errorf("%s %s: status %d: %v", r.Method, t, status, errs)
+	}
+	return nil
+}
+
+// validate returns a paramError for every part of v, found at at, that s does not allow.
+func (sv *specValidator) validate(s *schema, v interface{}, at string) (errs paramErrors) {
+	s = sv.schema(s)
+	if v == nil {
+		if !s.Nullable && s.Type != "" {
+			errs = append(errs, paramError{at, "must not be null"})
+		}
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		ok := false
+		for _, e := range s.Enum {
+			ok = ok || reflect.DeepEqual(e, v)
+		}
+		if !ok {
+			errs = append(errs, paramError{at, fmt.Sprintf("must be one of %v", s.Enum)})
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return append(errs, paramError{at, "must be an object"})
+		}
+		for _, k := range s.Required {
+			if _, ok := o[k]; !ok {
+				errs = append(errs, paramError{at + "." + k, "is required"})
+			}
+		}
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p, ok := s.Properties[k]
+			switch {
+			case ok:
+				errs = append(errs, sv.validate(p, o[k], at+"."+k)...)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				errs = append(errs, paramError{at + "." + k, "is not allowed"})
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return append(errs, paramError{at, "must be an array"})
+		}
+		if s.Items != nil {
+			for i, e := range a {
+				errs = append(errs, sv.validate(s.Items, e, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return append(errs, paramError{at, "must be a string"})
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			errs = append(errs, paramError{at, fmt.Sprintf("must be at least %d characters", *s.MinLength)})
+		}
+		if s.MaxLength != nil && len(str) > *s.MaxLength {
+			errs = append(errs, paramError{at, fmt.Sprintf("must be at most %d characters", *s.MaxLength)})
+		}
+		if s.Pattern != "" {
+			if ok, err := regexp.MatchString(s.Pattern, str); err != nil || !ok {
+				errs = append(errs, paramError{at, "must match " + s.Pattern})
+			}
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				errs = append(errs, paramError{at, "must be an RFC 3339 date-time"})
+			}
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return append(errs, paramError{at, "must be a " + s.Type})
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			errs = append(errs, paramError{at, "must be an integer"})
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			errs = append(errs, paramError{at, fmt.Sprintf("must be at least %v", *s.Minimum)})
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			errs = append(errs, paramError{at, fmt.Sprintf("must be at most %v", *s.Maximum)})
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, paramError{at, "must be a boolean"})
+		}
+	}
+	return
+}
+
+// coerce converts the raw value of a path or query parameter into the JSON type s expects.
+// Values that cannot be converted are returned unchanged and rejected by validate.
+func coerce(s *schema, raw string) interface{} {
+	switch s.Type {
+	case "number", "integer":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
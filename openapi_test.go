@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const tMomentJSON = `{
+	"id": 1,
+	"userID": "user00",
+	"location": {"latitude": 1.5, "longitude": -2.5},
+	"public": true,
+	"hidden": false,
+	"createDate": "2017-06-01T12:00:00Z",
+	"media": [{"momentID": 1, "message": "Hello.", "type": 0, "dir": ""}],
+	"finds": [{"momentID": 1, "userID": "user01", "found": true, "findDate": "2017-06-02T12:00:00Z"}],
+	"shares": [{"id": 1, "momentID": 1, "userID": "user00"}]
+}`
+
+// MockSpecApp returns a MockApp whose selectors return a moment, that validates requests
+// against openAPIDocument and fails t on every response the document does not describe.
+func MockSpecApp(t *testing.T) *app {
+	m := new(moment.Moment)
+	assert.Nil(t, json.Unmarshal([]byte(tMomentJSON), m))
+
+	a := MockApp()
+	a.s.(*MockStore).moments = []*moment.Moment{m}
+
+	var err error
+	a.spec, err = newSpecValidator(openAPIDocument)
+	assert.Nil(t, err)
+	a.spec.responseError = func(r *http.Request, err error) {
+		t.Errorf("response drifted from %s: %v", OpenAPIEndpoint, err)
+	}
+	return a
+}
+
+func Test_serveOpenAPI(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, OpenAPIEndpoint, nil)
+	rec := httptest.NewRecorder()
+
+	MockApp().routes().ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, openAPIDocument, rec.Body.Bytes())
+}
+
+// Test_openAPIReferences fails when the document refers to a component it does not define.
+func Test_openAPIReferences(t *testing.T) {
+	sv, err := newSpecValidator(openAPIDocument)
+	assert.Nil(t, err)
+	c := sv.doc.Components
+
+	var walk func(s *schema, at string)
+	walk = func(s *schema, at string) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			assert.NotNil(t, c.Schemas[strings.TrimPrefix(s.Ref, refSchemas)], at+" "+s.Ref)
+			return
+		}
+		for k, p := range s.Properties {
+			walk(p, at+"."+k)
+		}
+		walk(s.Items, at+"[]")
+	}
+	for k, s := range c.Schemas {
+		walk(s, k)
+	}
+
+	for path, item := range sv.doc.Paths {
+		ops := item.operations()
+		assert.NotEmpty(t, ops, path)
+		for m, op := range ops {
+			assert.NotEmpty(t, op.OperationID, m+" "+path)
+			for _, p := range append(append([]*parameter{}, item.Parameters...), op.Parameters...) {
+				if p.Ref != "" {
+					assert.NotNil(t, c.Parameters[strings.TrimPrefix(p.Ref, refParameters)], m+" "+path+" "+p.Ref)
+				}
+			}
+			for status, rs := range op.Responses {
+				if rs.Ref != "" {
+					assert.NotNil(t, c.Responses[strings.TrimPrefix(rs.Ref, refResponses)], m+" "+path+" "+status)
+				}
+			}
+			if op.RequestBody != nil {
+				for _, mt := range op.RequestBody.Content {
+					walk(mt.Schema, m+" "+path+" body")
+				}
+			}
+		}
+	}
+}
+
+// Test_openAPIDrift sends the documented example request of every operation to every
+// version of the service that serves it and fails when a handler answers other than the
+// document says.
+func Test_openAPIDrift(t *testing.T) {
+	sv, err := newSpecValidator(openAPIDocument)
+	assert.Nil(t, err)
+	db, err := moment.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "moment.db"), moment.DefaultPoolConfig)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	paths := make([]string, 0, len(sv.doc.Paths))
+	for p := range sv.doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := sv.doc.Paths[path]
+		for method, op := range item.operations() {
+			user := tUser
+			target := path
+			q := make([]string, 0)
+			h := make(http.Header)
+			for _, p := range sv.parameters(item, op) {
+				if p.Example == nil {
+					continue
+				}
+				v := fmt.Sprint(p.Example)
+				switch p.In {
+				case "path":
+					target = strings.Replace(target, "{"+p.Name+"}", v, 1)
+					if p.Name == "id" && p.Schema != nil && sv.schema(p.Schema).Type == "string" {
+						user = v
+					}
+				case "query":
+					q = append(q, p.Name+"="+v)
+				case "header":
+					h.Set(p.Name, v)
+				}
+			}
+			if len(q) > 0 {
+				target += "?" + strings.Join(q, "&")
+			}
+
+			var body []byte
+			if op.RequestBody != nil {
+				body, err = json.Marshal(op.RequestBody.Content["application/json"].Example)
+				assert.Nil(t, err)
+			}
+
+			success := 0
+			for status := range op.Responses {
+				if s := status[0]; s == '2' {
+					fmt.Sscan(status, &success)
+				}
+			}
+			assert.NotZero(t, success, method+" "+path+" documents no 2xx response")
+
+			for _, prefix := range sv.itemPrefixes(item) {
+				req := httptest.NewRequest(method, prefix+target, bytes.NewReader(body))
+				for k, v := range h {
+					req.Header[k] = v
+				}
+				req.Header.Set("Authorization", bearer(t, user))
+				rec := httptest.NewRecorder()
+
+				a := MockSpecApp(t)
+				a.db = db
+				a.routes().ServeHTTP(rec, req)
+				assert.Exactly(t, success, rec.Code, method+" "+prefix+target+": "+rec.Body.String())
+				if op.Deprecated {
+					assert.NotEmpty(t, rec.Header().Get("Deprecation"), method+" "+prefix+target)
+				}
+			}
+		}
+	}
+}
+
+func Test_validateRequest(t *testing.T) {
+	type test struct {
+		method   string
+		path     string
+		body     string
+		expected []string
+	}
+	tests := []test{
+		test{http.MethodPost, "/v2/moments", `{"Latitude":1,"Longitude":2}`, nil},
+		test{http.MethodPost, "/v2/moments", `{"Longitude":"east"}`, []string{"body.Latitude", "body.Longitude"}},
+		test{http.MethodPost, "/moments", ``, []string{"body"}},
+		test{http.MethodPost, "/moments", `{"Latitude":1,"Longitude":2,"CreateDate":"yesterday"}`, []string{"body.CreateDate"}},
+		test{http.MethodPost, "/v1/moments/1/finds", ``, nil},
+		test{http.MethodPost, "/v1/moments/-1/finds", ``, []string{"id"}},
+		test{http.MethodPost, "/moments/1/shares", `{"Recipients":[{"All":"yes"}]}`, []string{"body.Recipients[0].All"}},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=public", ``, nil},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments", ``, []string{"kind"}},
+		test{http.MethodGet, "/locations/moments?lat=north&kind=everything", ``, []string{"lat", "kind"}},
+		test{http.MethodGet, "/locations/moments?lat=100&long=-200&kind=public&radius=500", ``, []string{"lat", "long"}},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=public&radius=-1", ``, []string{"radius"}},
+		test{http.MethodPatch, "/v1/moment?action=findpublic", `{"MomentID":1}`, nil},
+		test{http.MethodPatch, "/moment?action=steal", `{}`, []string{"action", "body.MomentID"}},
+		test{http.MethodPatch, "/v2/moment?action=steal", `{}`, nil},
+		test{http.MethodGet, "/undocumented", ``, nil},
+		test{http.MethodDelete, "/moments", ``, nil},
+	}
+
+	sv, err := newSpecValidator(openAPIDocument)
+	assert.Nil(t, err)
+	for _, v := range tests {
+		req := httptest.NewRequest(v.method, v.path, strings.NewReader(v.body))
+
+		err := sv.validateRequest(req)
+		if v.expected == nil {
+			assert.Nil(t, err, v.method+" "+v.path)
+			continue
+		}
+		var names []string
+		if errs, ok := err.(paramErrors); assert.True(t, ok, v.method+" "+v.path) {
+			for _, e := range errs {
+				names = append(names, e.Name)
+			}
+		}
+		assert.ElementsMatch(t, v.expected, names, v.method+" "+v.path)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/moments/1/finds", nil)
+	req.Header.Set(IdempotencyHeader, strings.Repeat("k", 256))
+	assert.Equal(t, paramErrors{paramError{IdempotencyHeader, "must be at most 255 characters"}}, sv.validateRequest(req))
+}
+
+func Test_validateResponse(t *testing.T) {
+	type test struct {
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+		ok          bool
+	}
+	tests := []test{
+		test{http.MethodGet, "/v2/users/u/moments/left", http.StatusOK, "application/vnd.moment.v2+json", `{"moments":[` + tMomentJSON + `]}`, true},
+		test{http.MethodGet, "/v1/users/u/moments/left", http.StatusOK, "application/json", `[` + tMomentJSON + `]`, true},
+		test{http.MethodGet, "/v1/users/u/moments/left", http.StatusOK, "application/json", `null`, true},
+		test{http.MethodGet, "/v2/users/u/moments/left", http.StatusOK, "application/vnd.moment.v2+json", `null`, false},
+		test{http.MethodGet, "/v2/users/u/moments/left", http.StatusOK, "application/vnd.moment.v2+json", `{"moments":[{"id":"1"}]}`, false},
+		test{http.MethodGet, "/v2/users/u/moments/left", http.StatusOK, "text/plain", `{"moments":[]}`, false},
+		test{http.MethodGet, "/v2/users/u/moments/left", http.StatusTeapot, problemContentType, `{}`, false},
+		test{http.MethodPost, "/moments", http.StatusCreated, "", ``, true},
+		test{http.MethodPost, "/moments", http.StatusCreated, "", `{"id":1}`, false},
+		test{http.MethodPost, "/moments", http.StatusBadRequest, problemContentType, `{"type":"/problems/bad-request","title":"Bad Request","status":400,"correlationID":"1"}`, true},
+		test{http.MethodGet, "/undocumented", http.StatusOK, "", ``, false},
+		test{http.MethodGet, "/undocumented", http.StatusNotFound, "", ``, true},
+	}
+
+	sv, err := newSpecValidator(openAPIDocument)
+	assert.Nil(t, err)
+	for _, v := range tests {
+		req := httptest.NewRequest(v.method, v.path, nil)
+		h := make(http.Header)
+		h.Set("Content-Type", v.contentType)
+
+		err := sv.validateResponse(req, v.status, h, []byte(v.body))
+		assert.Equal(t, v.ok, err == nil, fmt.Sprintf("%s %s %d: %v", v.method, v.path, v.status, err))
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	paramLat    = "lat"
+	paramLong   = "long"
+	paramRadius = "radius"
+	paramMe     = "me"
+
+	paramLimit  = "limit"
+	paramCursor = "cursor"
+	paramSort   = "sort"
+
+	// deprecatedBodyWarning is sent with the Deprecation header to callers that still put
+	// read parameters in a JSON request body.
+	deprecatedBodyWarning = `299 - "JSON request bodies on GET are deprecated, use query parameters"`
+)
+
+// legacyBodyNames maps the query parameters that replaced fields of the JSON body onto the
+// name of the field, where it differs.
+var legacyBodyNames = map[string]string{
+	paramLat:  "Latitude",
+	paramLong: "Longitude",
+}
+
+// paramError describes why a single request parameter was rejected.
+type paramError struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// paramErrors is returned when one or more request parameters are missing or malformed.
+type paramErrors []paramError
+
+// Error returns every rejected parameter and its reason.
+func (e paramErrors) Error() string {
+	s := make([]string, len(e))
+	for i, p := range e {
+		s[i] = p.Name + " " + p.Reason
+	}
+	return "Invalid parameters: " + strings.Join(s, ", ") + "."
+}
+
+// params reads typed parameters and collects an error for every parameter that cannot be read.
+// bodyErr is set if the legacy body the parameters fall back to could not be read.
+type params struct {
+	get     func(string) string
+	errs    paramErrors
+	bodyErr error
+}
+
+// queryParams reads the query string of r.
+func queryParams(r *http.Request) *params {
+	return &params{get: r.URL.Query().Get}
+}
+
+// readParams reads the query string of r. Legacy versions keep the deprecated body-based read
+// API: a parameter missing from the query falls back to the field of the JSON body that carried
+// it, and the response carries Deprecation and Warning headers.
+func readParams(w http.ResponseWriter, r *http.Request) *params {
+	query := r.URL.Query()
+	legacy := requestVersion(r).legacy
+	p := new(params)
+	p.get = func(name string) string {
+		if v := query.Get(name); v != "" || !legacy {
+			return v
+		}
+		v, ok, err := legacyBodyParam(r, name)
+		if err != nil {
+			p.bodyErr = err
+		}
+		if ok {
+			deprecate(w)
+			w.Header().Set("Warning", deprecatedBodyWarning)
+		}
+		return v
+	}
+	return p
+}
+
+// deprecate marks the response w deprecated, unless its version already announced since when.
+func deprecate(w http.ResponseWriter) {
+	if w.Header().Get("Deprecation") == "" {
+		w.Header().Set("Deprecation", "true")
+	}
+}
+
+// userParam returns the user ID in parameter name of r, see readParams.
+func userParam(w http.ResponseWriter, r *http.Request, name string) (string, error) {
+	q := readParams(w, r)
+	u := q.user(name)
+	return u, q.err()
+}
+
+// caller returns the authenticated user of r. A client that still names itself in the
+// deprecated me parameter must name the authenticated user, and a legacy body that cannot be
+// read is an error rather than a missing me.
+func caller(w http.ResponseWriter, r *http.Request) (string, error) {
+	me, err := authenticatedUser(r)
+	if err != nil {
+		return "", err
+	}
+	u, err := userParam(w, r, paramMe)
+	var missing paramErrors
+	switch {
+	case errors.As(err, &missing):
+	case err != nil:
+		return "", err
+	case u != me:
+		return "", paramErrors{paramError{paramMe, "must name the authenticated user"}}
+	}
+	return me, nil
+}
+
+// legacyBodyParam reads the body field of parameter name, matched case-insensitively, from the
+// JSON body of r. Numbers are returned in their shortest decimal form. A body that is not JSON
+// holds no fields, but one larger than maxBodyBytes is returned as an error.
+func legacyBodyParam(r *http.Request, name string) (string, bool, error) {
+	b := make(map[string]interface{})
+	if r.Body == nil {
+		return "", false, nil
+	}
+	if err := peekBody(r, &b); err != nil {
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			return "", false, err
+		}
+		return "", false, nil
+	}
+	field := name
+	if f, ok := legacyBodyNames[name]; ok {
+		field = f
+	}
+	for k, v := range b {
+		if !strings.EqualFold(k, field) {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			return v, v != "", nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// float32 parses parameter name. Missing or malformed values are recorded as errors.
+func (p *params) float32(name string) float32 {
+	s := p.get(name)
+	if s == "" {
+		p.errs = append(p.errs, paramError{name, "is required"})
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		p.errs = append(p.errs, paramError{name, "must be a number"})
+		return 0
+	}
+	return float32(f)
+}
+
+// optionalInt parses parameter name, returning 0 if it is missing. Malformed values are recorded as errors.
+func (p *params) optionalInt(name string) int {
+	s := p.get(name)
+	if s == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		p.errs = append(p.errs, paramError{name, "must be an integer"})
+		return 0
+	}
+	return i
+}
+
+// optionalFloat64 parses parameter name, returning 0 if it is missing. Malformed values are recorded as errors.
+func (p *params) optionalFloat64(name string) float64 {
+	s := p.get(name)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		p.errs = append(p.errs, paramError{name, "must be a number"})
+		return 0
+	}
+	return f
+}
+
+// user returns parameter name, recording an error if it is missing.
+func (p *params) user(name string) string {
+	s := p.get(name)
+	if s == "" {
+		p.errs = append(p.errs, paramError{name, "is required"})
+	}
+	return s
+}
+
+// err returns the error of reading the legacy body or the collected paramErrors, or nil if
+// every parameter was read.
+func (p *params) err() error {
+	if p.bodyErr != nil {
+		return p.bodyErr
+	}
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return p.errs
+}
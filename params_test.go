@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_userParam(t *testing.T) {
+	type test struct {
+		target       string
+		body         string
+		expected     string
+		expectedErr  error
+		expectedDepr string
+	}
+	tests := []test{
+		test{"/?me=" + tUser, "", tUser, nil, ""},
+		test{"/?me=" + tUser, `{"Me":"` + tUser1 + `"}`, tUser, nil, ""},
+		test{"/", `{"Me":"` + tUser1 + `"}`, tUser1, nil, "true"},
+		test{"/", `{"me":"` + tUser1 + `"}`, tUser1, nil, "true"},
+		test{"/", "", "", paramErrors{paramError{paramMe, "is required"}}, ""},
+		test{"/", "not json", "", paramErrors{paramError{paramMe, "is required"}}, ""},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, v.target, strings.NewReader(v.body))
+		rec := httptest.NewRecorder()
+
+		me, err := userParam(rec, req, paramMe)
+		assert.Equal(t, v.expected, me)
+		assert.Equal(t, v.expectedErr, err)
+		assert.Equal(t, v.expectedDepr, rec.Header().Get("Deprecation"))
+	}
+}
+
+func Test_readParams(t *testing.T) {
+	type test struct {
+		target       string
+		body         string
+		lat          float32
+		long         float32
+		expectedErr  error
+		expectedDepr string
+	}
+	tests := []test{
+		test{"/?lat=1.5&long=-2.5", "", 1.5, -2.5, nil, ""},
+		test{"/?lat=1.5&long=-2.5", `{"Latitude":3,"Longitude":4}`, 1.5, -2.5, nil, ""},
+		test{"/?lat=1.5", `{"Latitude":3,"Longitude":4}`, 1.5, 4, nil, "true"},
+		test{"/", `{"latitude":3.25,"longitude":-4}`, 3.25, -4, nil, "true"},
+		test{"/", `{"Latitude":"north","Longitude":4}`, 0, 4, paramErrors{paramError{paramLat, "must be a number"}}, "true"},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, v.target, strings.NewReader(v.body))
+		rec := httptest.NewRecorder()
+
+		q := readParams(rec, req)
+		assert.Equal(t, v.lat, q.float32(paramLat), v.target+" "+v.body)
+		assert.Equal(t, v.long, q.float32(paramLong), v.target+" "+v.body)
+		assert.Equal(t, v.expectedErr, q.err(), v.target+" "+v.body)
+		assert.Equal(t, v.expectedDepr, rec.Header().Get("Deprecation"), v.target+" "+v.body)
+	}
+}
+
+func Test_caller(t *testing.T) {
+	type test struct {
+		target   string
+		body     string
+		user     string
+		expected error
+	}
+	tests := []test{
+		test{"/", "", tUser, nil},
+		test{"/?me=" + tUser, "", tUser, nil},
+		test{"/", `{"Me":"` + tUser + `"}`, tUser, nil},
+		test{"/?me=" + tUser2, "", tUser, paramErrors{paramError{paramMe, "must name the authenticated user"}}},
+		test{"/", `{"Me":"` + tUser2 + `"}`, tUser, paramErrors{paramError{paramMe, "must name the authenticated user"}}},
+		test{"/", "", "", ErrorUnauthorized},
+		test{"/", strings.Repeat(" ", maxBodyBytes) + `{"Me":"` + tUser + `"}`, tUser, &http.MaxBytesError{Limit: maxBodyBytes}},
+	}
+
+	for _, v := range tests {
+		req := asUser(httptest.NewRequest(http.MethodGet, v.target, strings.NewReader(v.body)), v.user)
+		rec := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(rec, req.Body, maxBodyBytes)
+
+		_, err := caller(rec, req)
+		assert.Equal(t, v.expected, err, v.target+" "+v.body)
+	}
+}
+
+func Test_paramsFloat32(t *testing.T) {
+	v := map[string]string{paramLat: "1.25", paramLong: "east"}
+	q := &params{get: func(k string) string { return v[k] }}
+
+	assert.Equal(t, float32(1.25), q.float32(paramLat))
+	assert.Equal(t, float32(0), q.float32(paramLong))
+	assert.Equal(t, paramErrors{paramError{paramLong, "must be a number"}}, q.err())
+}
+
+func Test_paramsOptionalInt(t *testing.T) {
+	v := map[string]string{paramLimit: "ten"}
+	q := &params{get: func(k string) string { return v[k] }}
+
+	assert.Equal(t, 0, q.optionalInt(paramCursor))
+	assert.Nil(t, q.err())
+	assert.Equal(t, 0, q.optionalInt(paramLimit))
+	assert.Equal(t, paramErrors{paramError{paramLimit, "must be an integer"}}, q.err())
+}
+
+func Test_paramsOptionalFloat64(t *testing.T) {
+	v := map[string]string{paramRadius: "250.5", paramLat: "north"}
+	q := &params{get: func(k string) string { return v[k] }}
+
+	assert.Equal(t, 0.0, q.optionalFloat64(paramLong))
+	assert.Equal(t, 250.5, q.optionalFloat64(paramRadius))
+	assert.Nil(t, q.err())
+	assert.Equal(t, 0.0, q.optionalFloat64(paramLat))
+	assert.Equal(t, paramErrors{paramError{paramLat, "must be a number"}}, q.err())
+}
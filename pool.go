@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/penutty/Moment-Service/moment"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// PoolStatsEndpoint serves the statistics of the connection pool of Moment-Db for monitoring.
+const PoolStatsEndpoint = "/debug/pool"
+
+var ErrorPoolInvalid = errors.New("Pool limits must be non-negative integers, lifetimes non-negative durations and MomentDBPingAttempts at least 1.")
+
+// poolFromEnv reads the moment.PoolConfig of Moment-Db from the environment:
+//
+//	MomentDBMaxOpenConns     connections open at once, 0 for unlimited
+//	MomentDBMaxIdleConns     idle connections kept open
+//	MomentDBConnMaxLifetime  time a connection is reused, 0 for unlimited
+//	MomentDBConnMaxIdleTime  time a connection may stay idle, 0 for unlimited
+//	MomentDBPingAttempts     pings at startup before giving up
+//	MomentDBPingBackoff      wait after the first failed ping, doubled after each next one
+//
+// Each defaults to its value in moment.DefaultPoolConfig.
+func poolFromEnv() (c moment.PoolConfig, err error) {
+	c = moment.DefaultPoolConfig
+
+	ints := map[string]*int{
+		"MomentDBMaxOpenConns": &c.MaxOpenConns,
+		"MomentDBMaxIdleConns": &c.MaxIdleConns,
+		"MomentDBPingAttempts": &c.PingAttempts,
+	}
+	for k, n := range ints {
+		if s := os.Getenv(k); s != "" {
+			if *n, err = strconv.Atoi(s); err != nil || *n < 0 {
+				return moment.PoolConfig{}, ErrorPoolInvalid
+			}
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"MomentDBConnMaxLifetime": &c.ConnMaxLifetime,
+		"MomentDBConnMaxIdleTime": &c.ConnMaxIdleTime,
+		"MomentDBPingBackoff":     &c.PingBackoff,
+	}
+	for k, d := range durations {
+		if s := os.Getenv(k); s != "" {
+			if *d, err = time.ParseDuration(s); err != nil || *d < 0 {
+				return moment.PoolConfig{}, ErrorPoolInvalid
+			}
+		}
+	}
+
+	if c.PingAttempts < 1 {
+		return moment.PoolConfig{}, ErrorPoolInvalid
+	}
+	return
+}
+
+// servePoolStats writes the sql.DBStats of a.db. WaitDuration is in nanoseconds.
+// routes mounts it only when app has a db.
+func (a *app) servePoolStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.db.Stats())
+}
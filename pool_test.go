@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_poolFromEnv(t *testing.T) {
+	type test struct {
+		env      map[string]string
+		expected moment.PoolConfig
+		err      error
+	}
+	custom := moment.DefaultPoolConfig
+	custom.MaxOpenConns = 10
+	custom.ConnMaxLifetime = time.Hour
+	custom.PingAttempts = 1
+	tests := []test{
+		test{nil, moment.DefaultPoolConfig, nil},
+		test{map[string]string{"MomentDBMaxOpenConns": "10", "MomentDBConnMaxLifetime": "1h", "MomentDBPingAttempts": "1"}, custom, nil},
+		test{map[string]string{"MomentDBMaxIdleConns": "-1"}, moment.PoolConfig{}, ErrorPoolInvalid},
+		test{map[string]string{"MomentDBMaxOpenConns": "many"}, moment.PoolConfig{}, ErrorPoolInvalid},
+		test{map[string]string{"MomentDBConnMaxIdleTime": "-1s"}, moment.PoolConfig{}, ErrorPoolInvalid},
+		test{map[string]string{"MomentDBPingAttempts": "0"}, moment.PoolConfig{}, ErrorPoolInvalid},
+	}
+
+	for _, v := range tests {
+		for _, k := range []string{"MomentDBMaxOpenConns", "MomentDBMaxIdleConns", "MomentDBConnMaxLifetime", "MomentDBConnMaxIdleTime", "MomentDBPingAttempts", "MomentDBPingBackoff"} {
+			t.Setenv(k, v.env[k])
+		}
+		c, err := poolFromEnv()
+		assert.Exactly(t, v.err, err)
+		assert.Equal(t, v.expected, c)
+	}
+}
+
+func Test_servePoolStats(t *testing.T) {
+	a := MockApp()
+
+	rec := httptest.NewRecorder()
+	a.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PoolStatsEndpoint, nil))
+	assert.NotEqual(t, http.StatusOK, rec.Code, "an app without a db has no pool")
+
+	db, err := moment.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "moment.db"), moment.DefaultPoolConfig)
+	assert.Nil(t, err)
+	defer db.Close()
+	a.db = db
+
+	rec = httptest.NewRecorder()
+	a.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PoolStatsEndpoint, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats sql.DBStats
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, 1, stats.MaxOpenConnections)
+	assert.Equal(t, 1, stats.OpenConnections)
+
+	rec = httptest.NewRecorder()
+	a.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PoolStatsEndpoint, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/penutty/Moment-Service/moment"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// CorrelationHeader carries the ID that ties a request to its log lines and problem bodies.
+	CorrelationHeader = "X-Correlation-ID"
+
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "/problems/"
+)
+
+type contextKey int
+
+const (
+	correlationKey contextKey = iota
+	userKey
+	versionKey
+)
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	Instance      string       `json:"instance,omitempty"`
+	Field         string       `json:"field,omitempty"`
+	InvalidParams []paramError `json:"invalidParams,omitempty"`
+	CorrelationID string       `json:"correlationID"`
+}
+
+// withCorrelationID reuses the caller's CorrelationHeader, or generates one, and makes it
+// available to handlers through correlationID and to the caller through the response header.
+func withCorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationHeader)
+		if id == "" {
+			id = newCorrelationID()
+		}
+		w.Header().Set(CorrelationHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), correlationKey, id)))
+	})
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Println(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// correlationID returns the correlation ID of r, generating one if r did not pass through withCorrelationID.
+func correlationID(r *http.Request) string {
+	if id, ok := r.Context().Value(correlationKey).(string); ok {
+		return id
+	}
+	return newCorrelationID()
+}
+
+// statusOf maps err onto an HTTP status code and the name of the input that caused it.
+func statusOf(err error) (status int, field string) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var paramErr paramErrors
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrorUnauthorized),
+		errors.Is(err, ErrorTokenInvalid),
+		errors.Is(err, ErrorTokenExpired),
+		errors.Is(err, ErrorTokenClaims):
+		return http.StatusUnauthorized, ""
+	case errors.As(err, &sizeErr):
+		return http.StatusRequestEntityTooLarge, ""
+	case errors.As(err, &paramErr):
+		return http.StatusBadRequest, paramErr[0].Name
+	case errors.As(err, &typeErr):
+		return http.StatusBadRequest, typeErr.Field
+	case errors.As(err, &syntaxErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, ErrorBadRequest),
+		errors.Is(err, ErrorPathParameter),
+		errors.Is(err, ErrorKindInvalid):
+		return http.StatusBadRequest, ""
+	}
+
+	field = moment.FieldOf(err)
+	switch moment.KindOf(err) {
+	case moment.KindValidation:
+		status = http.StatusUnprocessableEntity
+	case moment.KindNotFound:
+		status = http.StatusNotFound
+	case moment.KindConflict:
+		status = http.StatusConflict
+	case moment.KindForbidden:
+		status = http.StatusForbidden
+	case moment.KindUnavailable:
+		status = http.StatusServiceUnavailable
+	default:
+		status = http.StatusInternalServerError
+	}
+	return
+}
+
+// genErrorHandler logs err and writes it to w as an application/problem+json response.
+func genErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	status, field := statusOf(err)
+	writeProblem(w, r, status, field, err)
+}
+
+// writeProblem writes an application/problem+json response with the given status.
+// The message of err is only exposed to the caller for 4xx responses.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, field string, err error) {
+	id := correlationID(r)
+	log.Printf("%s %s %s: %v", id, r.Method, instance(r), err)
+
+	p := problem{
+		Type:          problemTypePrefix + problemType(status),
+		Title:         http.StatusText(status),
+		Status:        status,
+		Instance:      instance(r),
+		Field:         field,
+		CorrelationID: id,
+	}
+	if status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+	var paramErr paramErrors
+	if errors.As(err, &paramErr) {
+		p.InvalidParams = paramErr
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set(CorrelationHeader, id)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Println(err)
+	}
+}
+
+// instance returns the path r was sent to, before any version prefix was stripped.
+func instance(r *http.Request) string {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u.Path
+	}
+	return r.URL.Path
+}
+
+// problemType returns the last segment of the problem type URI reference for status.
+func problemType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad-request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusUnprocessableEntity:
+		return moment.KindValidation.String()
+	case http.StatusNotFound:
+		return moment.KindNotFound.String()
+	case http.StatusConflict:
+		return moment.KindConflict.String()
+	case http.StatusForbidden:
+		return moment.KindForbidden.String()
+	case http.StatusServiceUnavailable:
+		return moment.KindUnavailable.String()
+	case http.StatusMethodNotAllowed:
+		return "method-not-allowed"
+	case http.StatusNotAcceptable:
+		return "not-acceptable"
+	}
+	return moment.KindInternal.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_statusOf(t *testing.T) {
+	type test struct {
+		err            error
+		expectedStatus int
+		expectedField  string
+	}
+	tests := []test{
+		test{moment.ErrorLatitude, http.StatusUnprocessableEntity, "latitude"},
+		test{fmt.Errorf("wrapped: %w", moment.ErrorUserIDShort), http.StatusUnprocessableEntity, "userID"},
+		test{moment.ErrorFindsRowDNE, http.StatusNotFound, "momentID"},
+		test{&moment.DomainError{Kind: moment.KindConflict, Err: errors.New("duplicate")}, http.StatusConflict, ""},
+		test{&moment.DomainError{Kind: moment.KindForbidden, Err: errors.New("forbidden")}, http.StatusForbidden, ""},
+		test{&moment.DomainError{Kind: moment.KindUnavailable, Err: errors.New("down")}, http.StatusServiceUnavailable, ""},
+		test{io.EOF, http.StatusBadRequest, ""},
+		test{&http.MaxBytesError{Limit: maxBodyBytes}, http.StatusRequestEntityTooLarge, ""},
+		test{ErrorUnauthorized, http.StatusUnauthorized, ""},
+		test{ErrorPathParameter, http.StatusBadRequest, ""},
+		test{json.Unmarshal([]byte(`{"Me":1}`), new(struct{ Me string })), http.StatusBadRequest, "Me"},
+		test{errors.New("unknown"), http.StatusInternalServerError, ""},
+	}
+
+	for _, v := range tests {
+		status, field := statusOf(v.err)
+		assert.Equal(t, v.expectedStatus, status, v.err.Error())
+		assert.Equal(t, v.expectedField, field, v.err.Error())
+	}
+}
+
+func Test_genErrorHandler(t *testing.T) {
+	t.Run("Validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/locations/100,0/moments", nil)
+		req.Header.Set(CorrelationHeader, "abc123")
+		rec := httptest.NewRecorder()
+
+		withCorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			genErrorHandler(w, r, moment.ErrorLatitude)
+		})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "abc123", rec.Header().Get(CorrelationHeader))
+
+		p := new(problem)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(p))
+		assert.Equal(t, problem{
+			Type:          problemTypePrefix + "validation",
+			Title:         http.StatusText(http.StatusUnprocessableEntity),
+			Status:        http.StatusUnprocessableEntity,
+			Detail:        moment.ErrorLatitude.Error(),
+			Instance:      "/locations/100,0/moments",
+			Field:         "latitude",
+			CorrelationID: "abc123",
+		}, *p)
+	})
+
+	t.Run("Internal", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/moments", nil)
+		rec := httptest.NewRecorder()
+
+		withCorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			genErrorHandler(w, r, errors.New("connection string leaked"))
+		})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		p := new(problem)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(p))
+		assert.Empty(t, p.Detail)
+		assert.NotEmpty(t, p.CorrelationID)
+		assert.Equal(t, rec.Header().Get(CorrelationHeader), p.CorrelationID)
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"github.com/penutty/Moment-Service/moment"
+	"os"
+	"strconv"
+)
+
+var ErrorFindRadiusInvalid = errors.New("MomentFindRadius must be a number of meters such as \"100\".")
+
+// findRadiusFromEnv reads how far in meters from a moment a find is accepted from
+// MomentFindRadius, which defaults to moment.DefaultFindRadius.
+func findRadiusFromEnv() (float64, error) {
+	s := os.Getenv("MomentFindRadius")
+	if s == "" {
+		return moment.DefaultFindRadius, nil
+	}
+	r, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, ErrorFindRadiusInvalid
+	}
+	return r, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_findRadiusFromEnv(t *testing.T) {
+	type test struct {
+		radius   string
+		expected float64
+		err      error
+	}
+	tests := []test{
+		test{"", moment.DefaultFindRadius, nil},
+		test{"250", 250, nil},
+		test{"12.5", 12.5, nil},
+		test{"near", 0, ErrorFindRadiusInvalid},
+	}
+
+	for _, v := range tests {
+		t.Setenv("MomentFindRadius", v.radius)
+		r, err := findRadiusFromEnv()
+		assert.Exactly(t, v.err, err, v.radius)
+		assert.Equal(t, v.expected, r, v.radius)
+	}
+}
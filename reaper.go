@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/penutty/Moment-Service/moment"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// defaultReapInterval is how often the reaper runs when MomentReapInterval is unset.
+	defaultReapInterval = time.Hour
+)
+
+var ErrorReapIntervalInvalid = errors.New("MomentReapInterval must be a positive duration such as \"15m\", or off.")
+
+// reapIntervalFromEnv reads how often the reaper runs from MomentReapInterval, a
+// time.ParseDuration string that defaults to defaultReapInterval. off disables the reaper.
+func reapIntervalFromEnv() (time.Duration, error) {
+	s := os.Getenv("MomentReapInterval")
+	switch s {
+	case "":
+		return defaultReapInterval, nil
+	case "off":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, ErrorReapIntervalInvalid
+	}
+	return d, nil
+}
+
+// reap runs r at once and then every interval until ctx is done. Each run deletes the moments
+// that have expired, purges those deleted more than moment.RestoreWindow ago and sweeps the
+// idempotency keys that have expired, bounded by timeout.
+func reap(ctx context.Context, r moment.Reaper, interval time.Duration, timeout time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		reapOnce(ctx, r, timeout)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// reapOnce runs r once and logs what it did. A zero timeout leaves the run bounded only by ctx.
+func reapOnce(ctx context.Context, r moment.Reaper, timeout time.Duration) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	now := time.Now().UTC()
+	expired, err := r.Reap(ctx, now)
+	if err != nil {
+		log.Printf("reap: %v", err)
+		return
+	}
+	purged, err := r.Purge(ctx, now)
+	if err != nil {
+		log.Printf("reap: %v", err)
+		return
+	}
+	swept, err := r.SweepKeys(ctx, now)
+	if err != nil {
+		log.Printf("reap: %v", err)
+		return
+	}
+	log.Printf("reap: deleted %d expired moments, purged %d moments, swept %d idempotency keys", expired, purged, swept)
+}
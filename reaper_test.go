@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"github.com/penutty/Moment-Service/moment"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_reapIntervalFromEnv(t *testing.T) {
+	type test struct {
+		interval string
+		expected time.Duration
+		err      error
+	}
+	tests := []test{
+		test{"", defaultReapInterval, nil},
+		test{"15m", 15 * time.Minute, nil},
+		test{"off", 0, nil},
+		test{"0s", 0, ErrorReapIntervalInvalid},
+		test{"hourly", 0, ErrorReapIntervalInvalid},
+	}
+
+	for _, v := range tests {
+		t.Setenv("MomentReapInterval", v.interval)
+		d, err := reapIntervalFromEnv()
+		assert.Exactly(t, v.err, err, v.interval)
+		assert.Equal(t, v.expected, d, v.interval)
+	}
+}
+
+// onceReaper cancels the reaper once it has run.
+type onceReaper struct {
+	moment.Reaper
+	cancel context.CancelFunc
+}
+
+func (r onceReaper) SweepKeys(ctx context.Context, at time.Time) (int64, error) {
+	defer r.cancel()
+	return r.Reaper.SweepKeys(ctx, at)
+}
+
+func Test_reap(t *testing.T) {
+	mc := new(moment.MomentClient)
+	s := moment.NewMemoryStore()
+	created := time.Now().UTC().Add(-time.Hour)
+	expires := created.Add(time.Minute)
+	m := mc.NewMomentsRow(mc.NewLocation(1, 1), tUser, true, false, &created, &expires, nil, nil)
+	assert.Nil(t, s.CreatePublic(context.Background(), m, []*moment.MediaRow{mc.NewMediaRow(0, "Hello.", moment.DNE, "")}))
+	reserved := created.Add(-moment.KeyTTL)
+	_, err := s.ReserveKey(context.Background(), mc.NewIdempotencyKeysRow(tUser, "k", []byte{1}, &reserved))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reap(ctx, onceReaper{s, cancel}, time.Hour, time.Second)
+
+	n, err := s.Reap(context.Background(), time.Now())
+	assert.Nil(t, err)
+	assert.Zero(t, n, "the expired moment was deleted by the run of reap")
+	n, err = s.Purge(context.Background(), expires.Add(moment.RestoreWindow+time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.SweepKeys(context.Background(), time.Now())
+	assert.Nil(t, err)
+	assert.Zero(t, n, "the expired key was swept by the run of reap")
+}
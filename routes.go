@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MomentsEndpoint, UsersEndpoint and LocationsEndpoint are the roots of the resource tree served by app.
+	MomentsEndpoint   = "/moments"
+	UsersEndpoint     = "/users"
+	LocationsEndpoint = "/locations"
+
+	finds   = "finds"
+	shares  = "shares"
+	restore = "restore"
+
+	userFound   = "found"
+	userLeft    = "left"
+	userShared  = "shared"
+	userPending = "pending"
+
+	kindPublic = "public"
+	kindHidden = "hidden"
+	kindLost   = "lost"
+	kindShared = "shared"
+
+	// maxBodyBytes is the largest request body app reads. A larger one is answered with 413.
+	maxBodyBytes = 1 << 20
+)
+
+var (
+	ErrorPathParameter = errors.New("Path parameter is invalid.")
+	ErrorKindInvalid   = errors.New("Query parameter kind must be one of public, hidden, lost or shared.")
+)
+
+// routes returns the http.Handler that mounts one handler set per apiVersion under its
+// prefix, e.g. /v1/moments and /v2/moments. Unversioned paths are served by the version
+// negotiated through the Accept header. Request bodies are bounded by maxBodyBytes. When app
+// has a spec, requests are validated against it before they reach a handler set. Mutating
+// requests with an Idempotency-Key are then answered at most once, see idempotent. Every
+// handler set maps the resource tree onto app's handlers, as described by the document served
+// at OpenAPIEndpoint:
+//
+//	/moments                                       POST
+//	/moments/{id}                                  PUT, DELETE
+//	/moments/{id}/restore                          POST
+//	/moments/{id}/finds                            POST
+//	/moments/{id}/shares                           POST
+//	/users/{id}/moments/found|left|shared|pending  GET
+//	/locations/{lat},{long}/moments?kind=...       GET
+//	/locations/moments?lat=&long=&kind=...         GET
+//
+// Legacy versions also serve LegacyMomentEndpoint beside the tree, see legacyMomentHandler.
+func (a *app) routes() http.Handler {
+	tree := a.resources()
+
+	mux := http.NewServeMux()
+	for _, v := range apiVersions {
+		mux.Handle(v.prefix+"/", http.StripPrefix(v.prefix, v.serve(tree)))
+	}
+	mux.Handle("/", negotiateVersion(tree))
+
+	var h http.Handler = a.idempotent(mux)
+	if a.spec != nil {
+		h = a.spec.middleware(h)
+	}
+	h = limitBody(h)
+
+	root := http.NewServeMux()
+	root.HandleFunc(OpenAPIEndpoint, serveOpenAPI)
+	if a.db != nil {
+		root.HandleFunc(PoolStatsEndpoint, a.servePoolStats)
+	}
+	root.Handle("/", a.auth.middleware(h))
+	return withCorrelationID(root)
+}
+
+// resources returns the resource tree shared by every handler set.
+// Handlers that differ between versions consult requestVersion.
+func (a *app) resources() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(MomentsEndpoint, a.momentsHandler)
+	mux.HandleFunc(MomentsEndpoint+"/", a.momentsHandler)
+	mux.HandleFunc(UsersEndpoint+"/", a.usersHandler)
+	mux.HandleFunc(LocationsEndpoint+"/", a.locationsHandler)
+	mux.HandleFunc(LegacyMomentEndpoint, a.legacyMomentHandler)
+
+	return mux
+}
+
+// momentsHandler serves /moments and every resource below it.
+func (a *app) momentsHandler(w http.ResponseWriter, r *http.Request) {
+	seg := pathSegments(r.URL.Path, MomentsEndpoint)
+	if len(seg) == 0 {
+		a.momentsCollectionHandler(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(seg[0], 10, 64)
+	if err != nil || id < 0 {
+		genErrorHandler(w, r, ErrorPathParameter)
+		return
+	}
+
+	switch {
+	case len(seg) == 1:
+		a.momentHandler(w, r, id)
+	case len(seg) == 2 && seg[1] == restore:
+		a.momentRestoreHandler(w, r, id)
+	case len(seg) == 2 && seg[1] == finds:
+		a.momentFindsHandler(w, r, id)
+	case len(seg) == 2 && seg[1] == shares:
+		a.momentSharesHandler(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// momentsCollectionHandler creates a public or private moment depending on the Public field of the body.
+func (a *app) momentsCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	type body struct {
+		Public bool
+	}
+	b := new(body)
+	if err := peekBody(r, b); err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+
+	var err error
+	if b.Public {
+		err = a.postPublicMoment(r)
+	} else {
+		err = a.postPrivateMoment(r)
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// momentHandler edits moment id on PUT and deletes it on DELETE. Only its author may do either.
+func (a *app) momentHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	var err error
+	switch r.Method {
+	case http.MethodPut:
+		err = a.editMoment(r, id)
+	case http.MethodDelete:
+		err = a.deleteMoment(r, id)
+	default:
+		methodNotAllowed(w, r, http.MethodPut, http.MethodDelete)
+		return
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// momentRestoreHandler restores moment id if it was deleted within moment.RestoreWindow.
+func (a *app) momentRestoreHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	if err := a.restoreMoment(r, id); err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// momentFindsHandler records a find of moment id. A body with Private=true finds a moment
+// the caller was a recipient of, otherwise the moment is found as a public moment.
+func (a *app) momentFindsHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	type body struct {
+		Private bool
+	}
+	b := new(body)
+	if err := peekBody(r, b); err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+
+	var err error
+	if b.Private {
+		err = a.findPrivateMoment(r, id)
+	} else {
+		err = a.findPublicMoment(r, id)
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// momentSharesHandler shares moment id with a set of recipients.
+func (a *app) momentSharesHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	if err := a.shareMoment(r, id); err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// usersHandler serves /users/{id}/moments/found|left|shared|pending.
+func (a *app) usersHandler(w http.ResponseWriter, r *http.Request) {
+	seg := pathSegments(r.URL.Path, UsersEndpoint)
+	if len(seg) != 3 || seg[1] != "moments" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	var err error
+	user := seg[0]
+	switch seg[2] {
+	case userFound:
+		err = a.getFoundMoment(w, r, user)
+	case userLeft:
+		err = a.getLeftMoment(w, r, user)
+	case userShared:
+		err = a.getSharedMomentbyUser(w, r, user)
+	case userPending:
+		err = a.getPendingMoment(w, r, user)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+}
+
+// locationsHandler serves /locations/{lat},{long}/moments?kind=public|hidden|lost|shared and
+// /locations/moments?lat=...&long=...&kind=public|hidden|lost|shared. Both take an optional
+// radius in meters. The parameters fall back to a deprecated JSON body, see readParams.
+func (a *app) locationsHandler(w http.ResponseWriter, r *http.Request) {
+	seg := pathSegments(r.URL.Path, LocationsEndpoint)
+
+	q := readParams(w, r)
+	var lat, long float32
+	var err error
+	switch {
+	case len(seg) == 2 && seg[1] == "moments":
+		lat, long, err = parseCoordinates(seg[0])
+	case len(seg) == 1 && seg[0] == "moments":
+		lat, long = q.float32(paramLat), q.float32(paramLong)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	radius := q.optionalFloat64(paramRadius)
+	if err == nil {
+		err = q.err()
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+
+	switch r.URL.Query().Get("kind") {
+	case kindPublic:
+		err = a.getPublicMoment(w, r, lat, long, radius)
+	case kindHidden:
+		err = a.getHiddenMoment(w, r, lat, long, radius)
+	case kindLost:
+		err = a.getLostMoment(w, r, lat, long, radius)
+	case kindShared:
+		err = a.getSharedMomentbyLocation(w, r, lat, long, radius)
+	default:
+		genErrorHandler(w, r, ErrorKindInvalid)
+		return
+	}
+	if err != nil {
+		genErrorHandler(w, r, err)
+		return
+	}
+}
+
+// pathSegments returns the non-empty segments of path that follow prefix.
+func pathSegments(path string, prefix string) (seg []string) {
+	for _, s := range strings.Split(strings.TrimPrefix(path, prefix), "/") {
+		if s != "" {
+			seg = append(seg, s)
+		}
+	}
+	return
+}
+
+// parseCoordinates parses a "{lat},{long}" path segment.
+func parseCoordinates(s string) (lat float32, long float32, err error) {
+	p := strings.Split(s, ",")
+	if len(p) != 2 {
+		err = paramErrors{paramError{paramLat + "," + paramLong, "must be of the form {lat},{long}"}}
+		return
+	}
+
+	v := map[string]string{paramLat: p[0], paramLong: p[1]}
+	q := &params{get: func(k string) string { return v[k] }}
+	lat, long = q.float32(paramLat), q.float32(paramLong)
+	return lat, long, q.err()
+}
+
+// limitBody bounds the body of every request to maxBodyBytes, for the bodies that the spec
+// middleware, idempotent and peekBody buffer as well as for the JSON decoders of the handlers.
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// peekBody decodes the JSON body of r into v and rewinds r.Body so that it can be decoded again.
+// An empty body leaves v unchanged.
+func peekBody(r *http.Request, v interface{}) error {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// methodNotAllowed responds with 405 and an Allow header listing the methods the resource supports.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allow ...string) {
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	writeProblem(w, r, http.StatusMethodNotAllowed, "", ErrorMethodNotImplemented)
+}
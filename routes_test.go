@@ -0,0 +1,127 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_routes(t *testing.T) {
+	type test struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedAllow  string
+	}
+	big := strings.Repeat(" ", maxBodyBytes) + "{}"
+	tests := []test{
+		test{http.MethodGet, "/moments", "", http.StatusMethodNotAllowed, http.MethodPost},
+		test{http.MethodPost, "/moments", "not json", http.StatusBadRequest, ""},
+		test{http.MethodPost, "/moments", `{"Latitude":1,"Longitude":1,"Public":true,"CreateDate":"2017-06-01T12:00:00Z","ExpiresAt":"2017-05-01T12:00:00Z","Media":[{"Message":"Hello."}]}`, http.StatusUnprocessableEntity, ""},
+		test{http.MethodDelete, "/moments/1", "", http.StatusNoContent, ""},
+		test{http.MethodPut, "/moments/1", `{"Hidden":false,"Media":[{"Message":"Edited."}]}`, http.StatusNoContent, ""},
+		test{http.MethodPut, "/moments/1", `{"Media":[]}`, http.StatusUnprocessableEntity, ""},
+		test{http.MethodPatch, "/moments/1", "", http.StatusMethodNotAllowed, "PUT, DELETE"},
+		test{http.MethodPost, "/moments/1/restore", "", http.StatusNoContent, ""},
+		test{http.MethodGet, "/moments/1/restore", "", http.StatusMethodNotAllowed, http.MethodPost},
+		test{http.MethodPost, "/moments/abc/finds", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/moments/1/finds", "", http.StatusMethodNotAllowed, http.MethodPost},
+		test{http.MethodPost, "/moments/1/finds", "", http.StatusCreated, ""},
+		test{http.MethodPost, "/moments/1/finds", `{"Private":true}`, http.StatusCreated, ""},
+		test{http.MethodPut, "/moments/1/shares", "", http.StatusMethodNotAllowed, http.MethodPost},
+		test{http.MethodGet, "/moments/1/unknown", "", http.StatusNotFound, ""},
+		test{http.MethodGet, "/users/" + tUser + "/moments/found", "", http.StatusOK, ""},
+		test{http.MethodGet, "/users/" + tUser + "/moments/pending", "", http.StatusOK, ""},
+		test{http.MethodGet, "/users/" + tUser + "/moments/left", "", http.StatusOK, ""},
+		test{http.MethodGet, "/users/" + tUser2 + "/moments/left", "", http.StatusForbidden, ""},
+		test{http.MethodPost, "/users/" + tUser + "/moments/left", "", http.StatusMethodNotAllowed, http.MethodGet},
+		test{http.MethodGet, "/users/" + tUser + "/moments/unknown", "", http.StatusNotFound, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=public", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=hidden", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=other", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/1.5/moments?kind=public", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/200,0/moments?kind=public", "", http.StatusUnprocessableEntity, ""},
+		test{http.MethodPost, "/locations/1.5,-2.5/moments?kind=public", "", http.StatusMethodNotAllowed, http.MethodGet},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=lost", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=shared", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/moments?lat=1.5&long=-2.5&kind=public", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/moments?lat=north&kind=public", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/moments?kind=public", `{"Latitude":1.5,"Longitude":-2.5}`, http.StatusOK, ""},
+		test{http.MethodGet, "/users/" + tUser + "/moments/found?me=" + tUser2, "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=public&radius=250.5", "", http.StatusOK, ""},
+		test{http.MethodGet, "/locations/1.5,-2.5/moments?kind=public&radius=far", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/moments?lat=1.5&long=-2.5&kind=public&radius=far", "", http.StatusBadRequest, ""},
+		test{http.MethodGet, "/locations/100,0/moments?kind=public", "", http.StatusUnprocessableEntity, ""},
+		test{http.MethodGet, "/locations/0,100/moments?kind=public", "", http.StatusOK, ""},
+		test{http.MethodGet, "/users/" + tUser1 + "/moments/shared", "", http.StatusOK, ""},
+		test{http.MethodPost, "/moments", big, http.StatusRequestEntityTooLarge, ""},
+		test{http.MethodPost, "/moments/1/finds", big, http.StatusRequestEntityTooLarge, ""},
+		test{http.MethodPatch, "/moment?action=findpublic", big, http.StatusRequestEntityTooLarge, ""},
+		test{http.MethodGet, "/locations/moments?kind=public", big, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, v := range tests {
+		for _, a := range []*app{MockApp(), MockSpecApp(t)} {
+			req := httptest.NewRequest(v.method, v.path, strings.NewReader(v.body))
+			req.Header.Set("Authorization", bearer(t, tUser))
+			rec := httptest.NewRecorder()
+
+			a.routes().ServeHTTP(rec, req)
+			assert.Exactly(t, v.expectedStatus, rec.Code, v.method+" "+v.path)
+			if v.expectedStatus == http.StatusMethodNotAllowed {
+				assert.Equal(t, v.expectedAllow, rec.Header().Get("Allow"), v.method+" "+v.path)
+			}
+		}
+	}
+}
+
+func Test_routesUnauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/"+tUser+"/moments/found", nil)
+	rec := httptest.NewRecorder()
+
+	MockApp().routes().ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(CorrelationHeader))
+}
+
+func Test_pathSegments(t *testing.T) {
+	assert.Equal(t, []string{"1", "finds"}, pathSegments("/moments/1/finds/", MomentsEndpoint))
+	assert.Nil(t, pathSegments("/moments", MomentsEndpoint))
+}
+
+func Test_parseCoordinates(t *testing.T) {
+	type test struct {
+		s             string
+		lat           float32
+		long          float32
+		invalidParams []string
+	}
+	tests := []test{
+		test{"1.5,-2.5", 1.5, -2.5, nil},
+		test{"1.5", 0, 0, []string{paramLat + "," + paramLong}},
+		test{"a,1", 0, 0, []string{paramLat}},
+		test{"1,b", 0, 0, []string{paramLong}},
+		test{",", 0, 0, []string{paramLat, paramLong}},
+	}
+
+	for _, v := range tests {
+		lat, long, err := parseCoordinates(v.s)
+		if v.invalidParams == nil {
+			assert.Nil(t, err)
+			assert.Equal(t, v.lat, lat)
+			assert.Equal(t, v.long, long)
+			continue
+		}
+
+		pe, ok := err.(paramErrors)
+		assert.True(t, ok, v.s)
+		var names []string
+		for _, p := range pe {
+			names = append(names, p.Name)
+		}
+		assert.Equal(t, v.invalidParams, names, v.s)
+	}
+}
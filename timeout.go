@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// defaultTimeout bounds every operation whose timeout is not configured.
+	defaultTimeout = 10 * time.Second
+)
+
+var ErrorTimeoutInvalid = errors.New("Timeout must be a positive duration such as \"5s\" or \"1500ms\".")
+
+// timeouts bounds the time each operation of app may spend in Moment-Db.
+// A zero timeout leaves an operation bounded only by the lifetime of its request.
+type timeouts struct {
+	create time.Duration
+	find   time.Duration
+	share  time.Duration
+	list   time.Duration
+	edit   time.Duration
+	delete time.Duration
+
+	idempotency time.Duration
+	reap        time.Duration
+}
+
+// timeoutsFromEnv reads the per-operation timeouts from the environment:
+//
+//	MomentCreateTimeout       POST /moments
+//	MomentFindTimeout         POST /moments/{id}/finds
+//	MomentShareTimeout        POST /moments/{id}/shares
+//	MomentListTimeout         every GET of a moment collection
+//	MomentEditTimeout         PUT /moments/{id}
+//	MomentDeleteTimeout       DELETE /moments/{id} and POST /moments/{id}/restore
+//	MomentIdempotencyTimeout  reserving and storing each Idempotency-Key
+//	MomentReapTimeout         each run of the reaper
+//
+// Each is a time.ParseDuration string and defaults to defaultTimeout.
+func timeoutsFromEnv() (to timeouts, err error) {
+	env := map[string]*time.Duration{
+		"MomentCreateTimeout": &to.create,
+		"MomentFindTimeout":   &to.find,
+		"MomentShareTimeout":  &to.share,
+		"MomentListTimeout":   &to.list,
+		"MomentEditTimeout":   &to.edit,
+		"MomentDeleteTimeout": &to.delete,
+
+		"MomentIdempotencyTimeout": &to.idempotency,
+		"MomentReapTimeout":        &to.reap,
+	}
+	for k, d := range env {
+		*d = defaultTimeout
+		s := os.Getenv(k)
+		if s == "" {
+			continue
+		}
+		if *d, err = time.ParseDuration(s); err != nil || *d <= 0 {
+			return timeouts{}, ErrorTimeoutInvalid
+		}
+	}
+	return
+}
+
+// withTimeout returns the context of r bounded by d. The context of r is cancelled when the
+// client disconnects, so the statements run under the returned context are aborted with it.
+func withTimeout(r *http.Request, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), d)
+}
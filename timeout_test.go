@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_timeoutsFromEnv(t *testing.T) {
+	type test struct {
+		list     string
+		expected time.Duration
+		err      error
+	}
+	tests := []test{
+		test{"", defaultTimeout, nil},
+		test{"1500ms", 1500 * time.Millisecond, nil},
+		test{"0s", 0, ErrorTimeoutInvalid},
+		test{"-1s", 0, ErrorTimeoutInvalid},
+		test{"soon", 0, ErrorTimeoutInvalid},
+	}
+
+	for _, v := range tests {
+		t.Setenv("MomentListTimeout", v.list)
+		to, err := timeoutsFromEnv()
+		assert.Exactly(t, v.err, err, v.list)
+		assert.Equal(t, v.expected, to.list, v.list)
+		if err == nil {
+			assert.Equal(t, defaultTimeout, to.create, v.list)
+		}
+	}
+}
+
+func Test_withTimeout(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil)
+
+	ctx, cancel := withTimeout(req, 0)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+	assert.Exactly(t, context.Canceled, ctx.Err())
+
+	ctx, cancel = withTimeout(req, time.Minute)
+	defer cancel()
+	d, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), d, time.Second)
+}
+
+// Test_timeoutContext checks that the context handed to the client is derived from the
+// request's context, which net/http cancels when the client disconnects, and carries the
+// configured timeout.
+func Test_timeoutContext(t *testing.T) {
+	parent := context.WithValue(context.Background(), correlationKey, "1")
+	req := httptest.NewRequest(http.MethodGet, LocationsEndpoint, nil).WithContext(parent)
+	rec := httptest.NewRecorder()
+
+	a := MockApp()
+	a.timeouts.list = time.Minute
+	assert.Nil(t, a.getPublicMoment(rec, req, tLat, tLong, 0))
+
+	ctx := a.s.(*MockStore).ctx
+	assert.Equal(t, "1", ctx.Value(correlationKey))
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Exactly(t, context.Canceled, ctx.Err())
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/penutty/Moment-Service/moment"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// VersionMediaType is the vendor media type a client names in Accept to select a version
+	// on unversioned paths, e.g. "application/vnd.moment.v2+json".
+	VersionMediaType = "application/vnd.moment.v%d+json"
+
+	vendorPrefix = "application/vnd.moment."
+)
+
+var (
+	ErrorVersionNotAcceptable = errors.New("Accept names no API version served by this endpoint.")
+	ErrorScheduleInvalid      = errors.New("Deprecation and sunset must be dates such as \"2026-11-01\", the sunset after the deprecation.")
+)
+
+// apiVersion is one side-by-side handler set mounted under prefix. A version with a deprecation
+// date announces it on every response, see scheduleFromEnv. A legacy version also serves
+// LegacyMomentEndpoint and reads parameters missing from the query out of a JSON body.
+type apiVersion struct {
+	n           int
+	prefix      string
+	contentType string
+	legacy      bool
+	deprecated  time.Time
+	sunset      time.Time
+	successor   string
+}
+
+var (
+	v1 = &apiVersion{
+		n:           1,
+		prefix:      "/v1",
+		contentType: "application/json",
+		legacy:      true,
+		successor:   "/v2",
+	}
+	v2 = &apiVersion{
+		n:           2,
+		prefix:      "/v2",
+		contentType: fmt.Sprintf(VersionMediaType, 2),
+	}
+
+	// apiVersions are the versions mounted by routes. Unversioned requests that do not
+	// negotiate a version through Accept are served by defaultVersion so that existing
+	// clients keep today's behaviour.
+	apiVersions    = []*apiVersion{v1, v2}
+	defaultVersion = v1
+)
+
+// scheduleFromEnv reads the dates from which v is deprecated and sunset out of
+// MomentV{n}Deprecation and MomentV{n}Sunset, e.g. MomentV1Sunset=2027-05-01. A version
+// without a deprecation date is not deprecated and cannot be sunset.
+func (v *apiVersion) scheduleFromEnv() (err error) {
+	env := map[string]*time.Time{
+		fmt.Sprintf("MomentV%dDeprecation", v.n): &v.deprecated,
+		fmt.Sprintf("MomentV%dSunset", v.n):      &v.sunset,
+	}
+	for k, t := range env {
+		*t = time.Time{}
+		s := os.Getenv(k)
+		if s == "" {
+			continue
+		}
+		if *t, err = time.Parse(time.DateOnly, s); err != nil {
+			return ErrorScheduleInvalid
+		}
+	}
+	if !v.sunset.IsZero() && (v.deprecated.IsZero() || !v.sunset.After(v.deprecated)) {
+		return ErrorScheduleInvalid
+	}
+	return nil
+}
+
+// serve stamps v on r and, for deprecated versions, adds the Deprecation (RFC 9745),
+// Sunset (RFC 8594) and successor Link headers before handing r to next.
+func (v *apiVersion) serve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !v.deprecated.IsZero() {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", v.deprecated.Unix()))
+			if !v.sunset.IsZero() {
+				w.Header().Set("Sunset", v.sunset.Format(http.TimeFormat))
+			}
+			if v.successor != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, v.successor))
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey, v)))
+	})
+}
+
+// negotiateVersion serves unversioned paths with the version named in the Accept header,
+// or defaultVersion if Accept names none.
+func negotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		v, err := acceptedVersion(r.Header.Get("Accept"))
+		if err != nil {
+			writeProblem(w, r, http.StatusNotAcceptable, "", err)
+			return
+		}
+		v.serve(next).ServeHTTP(w, r)
+	})
+}
+
+// acceptedVersion returns the first served version whose vendor media type is listed in accept.
+// An Accept header listing only vendor media types of versions that are not served is rejected.
+func acceptedVersion(accept string) (*apiVersion, error) {
+	if accept == "" {
+		return defaultVersion, nil
+	}
+
+	onlyVendor := true
+	for _, s := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		if !strings.HasPrefix(mt, vendorPrefix) {
+			onlyVendor = false
+			continue
+		}
+		for _, v := range apiVersions {
+			if mt == fmt.Sprintf(VersionMediaType, v.n) {
+				return v, nil
+			}
+		}
+	}
+	if onlyVendor {
+		return nil, ErrorVersionNotAcceptable
+	}
+	return defaultVersion, nil
+}
+
+// requestVersion returns the version r is served by.
+func requestVersion(r *http.Request) *apiVersion {
+	if v, ok := r.Context().Value(versionKey).(*apiVersion); ok {
+		return v
+	}
+	return defaultVersion
+}
+
+// momentList is the v2 shape of every moment collection. Unlike v1's bare array it
+// is never null and carries the cursor of the next page.
+type momentList struct {
+	Moments []*moment.Moment `json:"moments"`
+	Next    string           `json:"next,omitempty"`
+}
+
+// writeMoments encodes page to w in the shape of the version r is served by. Every version
+// links the next page, if there is one, with a Link header.
+func writeMoments(w http.ResponseWriter, r *http.Request, page *moment.Page) error {
+	if page == nil {
+		page = new(moment.Page)
+	}
+	if page.Next != "" {
+		u := *r.URL
+		u.Path = instance(r)
+		q := u.Query()
+		q.Set(paramCursor, page.Next)
+		u.RawQuery = q.Encode()
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+	}
+
+	v := requestVersion(r)
+	w.Header().Set("Content-Type", v.contentType)
+	if v == v1 {
+		return json.NewEncoder(w).Encode(page.Moments)
+	}
+
+	moments := page.Moments
+	if moments == nil {
+		moments = []*moment.Moment{}
+	}
+	return json.NewEncoder(w).Encode(momentList{moments, page.Next})
+}
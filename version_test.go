@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withSchedule deprecates v on deprecated and sunsets it on sunset until t ends.
+func withSchedule(t *testing.T, v *apiVersion, deprecated string, sunset string) {
+	t.Setenv(fmt.Sprintf("MomentV%dDeprecation", v.n), deprecated)
+	t.Setenv(fmt.Sprintf("MomentV%dSunset", v.n), sunset)
+	assert.Nil(t, v.scheduleFromEnv())
+	t.Cleanup(func() { v.deprecated, v.sunset = time.Time{}, time.Time{} })
+}
+
+func Test_scheduleFromEnv(t *testing.T) {
+	type test struct {
+		deprecated string
+		sunset     string
+		err        error
+	}
+	tests := []test{
+		test{"", "", nil},
+		test{"2026-11-01", "", nil},
+		test{"2026-11-01", "2027-05-01", nil},
+		test{"", "2027-05-01", ErrorScheduleInvalid},
+		test{"2026-11-01", "2026-11-01", ErrorScheduleInvalid},
+		test{"November", "", ErrorScheduleInvalid},
+		test{"2026-11-01", "2027-05-01T00:00:00Z", ErrorScheduleInvalid},
+	}
+
+	v := &apiVersion{n: 1}
+	for _, tc := range tests {
+		t.Setenv("MomentV1Deprecation", tc.deprecated)
+		t.Setenv("MomentV1Sunset", tc.sunset)
+		assert.Exactly(t, tc.err, v.scheduleFromEnv(), tc.deprecated+" "+tc.sunset)
+	}
+}
+
+func Test_acceptedVersion(t *testing.T) {
+	type test struct {
+		accept   string
+		expected *apiVersion
+		err      error
+	}
+	tests := []test{
+		test{"", v1, nil},
+		test{"*/*", v1, nil},
+		test{"application/json", v1, nil},
+		test{"application/vnd.moment.v2+json", v2, nil},
+		test{"application/vnd.moment.v1+json", v1, nil},
+		test{"application/vnd.moment.v3+json, application/vnd.moment.v2+json;q=0.5", v2, nil},
+		test{"application/vnd.moment.v3+json, application/json", v1, nil},
+		test{"application/vnd.moment.v3+json", nil, ErrorVersionNotAcceptable},
+	}
+
+	for _, v := range tests {
+		version, err := acceptedVersion(v.accept)
+		assert.Exactly(t, v.err, err, v.accept)
+		assert.Equal(t, v.expected, version, v.accept)
+	}
+}
+
+func Test_routesVersioned(t *testing.T) {
+	withSchedule(t, v1, "2026-11-01", "2027-05-01")
+
+	type test struct {
+		path               string
+		accept             string
+		expectedStatus     int
+		expectedBody       string
+		expectedDeprecated bool
+	}
+	tests := []test{
+		test{"/v1/users/" + tUser + "/moments/left", "", http.StatusOK, "null\n", true},
+		test{"/v2/users/" + tUser + "/moments/left", "", http.StatusOK, `{"moments":[]}` + "\n", false},
+		test{"/users/" + tUser + "/moments/left", "", http.StatusOK, "null\n", true},
+		test{"/users/" + tUser + "/moments/left", "application/vnd.moment.v2+json", http.StatusOK, `{"moments":[]}` + "\n", false},
+		test{"/v1/users/" + tUser + "/moments/left", "application/vnd.moment.v2+json", http.StatusOK, "null\n", true},
+		test{"/users/" + tUser + "/moments/left", "application/vnd.moment.v9+json", http.StatusNotAcceptable, "", false},
+		test{"/v9/users/" + tUser + "/moments/left", "", http.StatusNotFound, "", true},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, v.path, nil)
+		req.Header.Set("Authorization", bearer(t, tUser))
+		if v.accept != "" {
+			req.Header.Set("Accept", v.accept)
+		}
+		rec := httptest.NewRecorder()
+
+		MockApp().routes().ServeHTTP(rec, req)
+		assert.Exactly(t, v.expectedStatus, rec.Code, v.path)
+		if v.expectedBody != "" {
+			assert.Equal(t, v.expectedBody, rec.Body.String(), v.path)
+		}
+		if v.expectedDeprecated {
+			assert.Equal(t, "@1793491200", rec.Header().Get("Deprecation"), v.path)
+			assert.Equal(t, "Sat, 01 May 2027 00:00:00 GMT", rec.Header().Get("Sunset"), v.path)
+			assert.Equal(t, `</v2>; rel="successor-version"`, rec.Header().Get("Link"), v.path)
+		} else {
+			assert.Empty(t, rec.Header().Get("Deprecation"), v.path)
+			assert.Empty(t, rec.Header().Get("Sunset"), v.path)
+		}
+	}
+}
+
+// Test_routesLegacyVersion expects only v1 to keep the legacy endpoint and the body fallback of
+// its read parameters.
+func Test_routesLegacyVersion(t *testing.T) {
+	type test struct {
+		path               string
+		accept             string
+		body               string
+		expectedStatus     int
+		expectedDeprecated string
+	}
+	location := `{"Latitude":1.5,"Longitude":-2.5}`
+	tests := []test{
+		test{"/v1/moment?type=public", "", location, http.StatusOK, "true"},
+		test{"/moment?type=public", "", location, http.StatusOK, "true"},
+		test{"/v2/moment?type=public", "", location, http.StatusNotFound, ""},
+		test{"/moment?type=public", "application/vnd.moment.v2+json", location, http.StatusNotFound, ""},
+		test{"/v1/locations/moments?kind=public", "", location, http.StatusOK, "true"},
+		test{"/v2/locations/moments?kind=public", "", location, http.StatusBadRequest, ""},
+		test{"/v2/locations/moments?kind=public&lat=1.5&long=-2.5", "", location, http.StatusOK, ""},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, v.path, strings.NewReader(v.body))
+		req.Header.Set("Authorization", bearer(t, tUser))
+		if v.accept != "" {
+			req.Header.Set("Accept", v.accept)
+		}
+		rec := httptest.NewRecorder()
+
+		MockApp().routes().ServeHTTP(rec, req)
+		assert.Exactly(t, v.expectedStatus, rec.Code, v.path)
+		assert.Equal(t, v.expectedDeprecated, rec.Header().Get("Deprecation"), v.path)
+	}
+}
+
+func Test_problemInstanceVersioned(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v2/moments", nil)
+	req.Header.Set("Authorization", bearer(t, tUser))
+	rec := httptest.NewRecorder()
+
+	MockApp().routes().ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"instance":"/v2/moments"`)
+}
+
+func Test_routesNextPage(t *testing.T) {
+	type test struct {
+		path         string
+		expectedBody string
+	}
+	tests := []test{
+		test{"/v1/users/" + tUser + "/moments/left?limit=1", "null\n"},
+		test{"/v2/users/" + tUser + "/moments/left?limit=1", `{"moments":[],"next":"abc"}` + "\n"},
+	}
+
+	for _, v := range tests {
+		req := httptest.NewRequest(http.MethodGet, v.path, nil)
+		req.Header.Set("Authorization", bearer(t, tUser))
+		rec := httptest.NewRecorder()
+
+		a := MockApp()
+		a.s.(*MockStore).next = "abc"
+		a.routes().ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusOK, rec.Code, v.path)
+		assert.Equal(t, v.expectedBody, rec.Body.String(), v.path)
+
+		path := strings.SplitN(v.path, "?", 2)[0]
+		assert.Contains(t, rec.Header()["Link"], `<`+path+`?cursor=abc&limit=1>; rel="next"`, v.path)
+	}
+}